@@ -3,6 +3,7 @@ package logf
 import (
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,20 +62,84 @@ func allocSplicerFunc(arg any, verb string) func() {
 		msg = fmt.Sprintf("{key:%s}", verb)
 	}
 
-	store := Store{
-		scope: []string{},
-		as: [][]Attr{
-			{slog.Any("key", arg)},
-		},
-	}
+	store := Store{}.WithAttrs([]Attr{slog.Any("key", arg)})
 
 	return func() {
 		s := newSplicer()
 		defer s.free()
 
 		s.scanMessage(msg)
-		s.joinStore(store, nil)
+		s.joinStore(store)
+		s.ipol(msg)
+		io.WriteString(io.Discard, s.line())
+	}
+}
+
+func TestAllocSplicerScoped(t *testing.T) {
+	store := Store{}.
+		WithGroup("a").
+		WithGroup("b").
+		WithAttrs([]Attr{slog.String("key", "value")})
+
+	msg := "{a.b.key}"
+
+	wantAllocs(t, "scoped splicing", 1, func() {
+		s := newSplicer()
+		defer s.free()
+
+		s.scanMessage(msg)
+		s.joinStore(store)
 		s.ipol(msg)
 		io.WriteString(io.Discard, s.line())
+	})
+}
+
+func TestSplicerLimits(t *testing.T) {
+	defer SetSplicerLimits(16<<10, 128, 128)
+
+	large := strings.Repeat("x", 32<<10)
+
+	splice := func() {
+		s := newSplicer()
+		defer s.free()
+
+		s.scanMessage(large)
+		s.ipol(large)
+	}
+
+	// default limits discard large splicers
+	before := SplicerStats()
+	splice()
+	after := SplicerStats()
+
+	if after.Discards-before.Discards != 1 {
+		t.Errorf("default limits: want 1 discard, got %d", after.Discards-before.Discards)
+	}
+
+	// raised limits return large splicers to the pool
+	SetSplicerLimits(1<<20, 0, 0)
+
+	before = SplicerStats()
+	splice()
+	after = SplicerStats()
+
+	if after.Discards != before.Discards {
+		t.Errorf("raised limits: want 0 discards, got %d", after.Discards-before.Discards)
+	}
+	if after.Puts-before.Puts != 1 {
+		t.Errorf("raised limits: want 1 put, got %d", after.Puts-before.Puts)
+	}
+
+	// a subsequent splicer reuses the large buffer
+	// (sync.Pool may drop items, so allow a few tries)
+	var reused bool
+	for i := 0; i < 10 && !reused; i++ {
+		splice()
+		s := newSplicer()
+		reused = cap(s.text) >= len(large)
+		s.free()
+	}
+	if !reused {
+		t.Errorf("raised limits: want reused text capacity >= %d", len(large))
 	}
 }
@@ -2,8 +2,14 @@ package logf
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
+
+	"log/slog"
 )
 
 func TestStore(t *testing.T) {
@@ -37,6 +43,17 @@ func TestStore(t *testing.T) {
 
 	log.Info("", "store", store)
 	want("store:{chordata:{duck:0 goose:1 platypus:2}}")
+
+	// a group opened without attrs is elided
+	store = store.WithGroup("aves")
+
+	log.Info("", "store", store)
+	want("store:{chordata:{duck:0 goose:1 platypus:2}}")
+
+	store = store.WithAttrs(Attrs("duck", 3))
+
+	log.Info("", "store", store)
+	want("store:{chordata:{duck:0 goose:1 platypus:2 aves:{duck:3}}}")
 }
 
 func TestJSONValue(t *testing.T) {
@@ -85,16 +102,289 @@ func TestJSONValue(t *testing.T) {
 		},
 	}
 
-	for _, obj := range objects {
-		v, err := JSONValue(obj.json)
-		if err != nil {
-			log.Error("JSON", err)
-			continue
+	decoders := map[string]func(string) (Value, error){
+		"JSONValue": JSONValue,
+		"JSONBytesValue": func(s string) (Value, error) {
+			return JSONBytesValue([]byte(s))
+		},
+		"DecodeJSON": func(s string) (Value, error) {
+			return DecodeJSON(strings.NewReader(s))
+		},
+		"RawMessage": func(s string) (Value, error) {
+			return KV("", json.RawMessage(s)).Value, nil
+		},
+	}
+
+	for name, decode := range decoders {
+		for _, obj := range objects {
+			v, err := decode(obj.json)
+			if err != nil {
+				log.Error("JSON", err)
+				continue
+			}
+			log.Info("", "object", v)
+			if obj.want != b.String() {
+				t.Errorf("%s, %s: want %s, got %s", name, obj.label, obj.want, b.String())
+			}
+			b.Reset()
+		}
+	}
+
+	// raw messages in Attrs, falling back to the raw string
+	as := Attrs("ok", json.RawMessage(`{"a":1}`), "bad", json.RawMessage(`{"a":`))
+	if got := fmt.Sprint(as); got != `[ok=[a=1] bad={"a":]` {
+		t.Errorf("raw messages: got %s", got)
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	store := Store{}.
+		WithAttrs(Attrs("id", 1)).
+		WithGroup("req").
+		WithAttrs(Attrs("path", "/", Group("user", "name", "gopher"), "tags", []string{"a", "b"})).
+		WithAttrs(Attrs("path", "/home"))
+
+	fs := []struct {
+		key  string
+		want any
+	}{
+		{"id", int64(1)},
+		{"req.path", "/home"},
+		{"req.user.name", "gopher"},
+		{"req.tags.1", "b"},
+		{"path", nil},
+		{"req.user.id", nil},
+		{"req.tags.2", nil},
+	}
+
+	for _, f := range fs {
+		v, ok := store.Get(f.key)
+		switch {
+		case f.want == nil && ok:
+			t.Errorf("%s: unexpected value %v", f.key, v)
+		case f.want != nil && !ok:
+			t.Errorf("%s: missing", f.key)
+		case f.want != nil && v.Any() != f.want:
+			t.Errorf("%s: want %v, got %v", f.key, f.want, v)
 		}
-		log.Info("", "object", v)
-		if obj.want != b.String() {
-			t.Errorf("want %s, got %s", obj.want, b.String())
+		if store.Has(f.key) != ok {
+			t.Errorf("%s: Has disagrees with Get", f.key)
 		}
-		b.Reset()
 	}
+
+	log := New().
+		ForceTTY(true).
+		Logger().
+		WithGroup("req").
+		With("request_id", "abc")
+
+	if v, ok := log.Attr("req.request_id"); !ok || v.String() != "abc" {
+		t.Errorf("Logger.Attr: got %v, %v", v, ok)
+	}
+	if _, ok := log.Attr("request_id"); ok {
+		t.Errorf("Logger.Attr: unexpected unscoped match")
+	}
+}
+
+func TestStoreWithout(t *testing.T) {
+	store := Store{}.
+		WithAttrs(Attrs("id", 1, "token", "secret")).
+		WithGroup("req").
+		WithAttrs(Attrs("path", "/", Group("auth", "user", "gopher", "token", "secret")))
+
+	store2 := store.Without("token", "req.auth.token", "req.missing")
+
+	for _, key := range []string{"token", "req.auth.token"} {
+		if store2.Has(key) {
+			t.Errorf("%s: not removed", key)
+		}
+		if !store.Has(key) {
+			t.Errorf("%s: removed from original store", key)
+		}
+	}
+	for _, key := range []string{"id", "req.path", "req.auth.user"} {
+		if !store2.Has(key) {
+			t.Errorf("%s: unexpectedly removed", key)
+		}
+	}
+
+	// a TTY rebuilds preformatted attrs
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		With("user", "gopher", "token", "secret").
+		WithGroup("req").
+		With("path", "/")
+
+	log.Without("token").Info("msg")
+	if got := b.String(); got != "msg\tuser:gopher req:{path:/}\n" {
+		t.Errorf("Logger.Without: got %q", got)
+	}
+
+	// and an aux handler keeps the labels
+	b.Reset()
+	log = New().
+		Writer(&b).
+		ShowLayout("tags", "message").
+		ShowColor(false).
+		ForceTTY(true).
+		ForceAux(true).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+				return Attr{}
+			}
+			return a
+		}).
+		Logger().
+		With("#", "svc", "token", "secret")
+
+	log.Without("token").Info("msg")
+	if want, got := `{"msg":"msg","#":"svc"}`+"\nsvc msg\n", b.String(); got != want {
+		t.Errorf("Logger.Without, aux:\n\twant %q\n\tgot  %q", want, got)
+	}
+
+	b.Reset()
+	log.WithError(errors.New("first")).WithError(errors.New("failed")).Info("msg")
+	if want := `"#":"svc","token":"secret","err":"failed"}`; !strings.Contains(b.String(), want) {
+		t.Errorf("Logger.WithError, aux: want %s in %q", want, b.String())
+	}
+}
+
+func TestStoreReplace(t *testing.T) {
+	var calls int
+	replace := func(scope []string, a Attr) Attr {
+		// (built-in fields are replaced per record, with no scope)
+		if len(scope) > 0 {
+			calls++
+		}
+		if a.Key == "token" {
+			return slog.String(a.Key, "REDACTED")
+		}
+		return a
+	}
+
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		ReplaceFunc(replace).
+		Logger().
+		WithGroup("req").
+		With("token", "secret", Group("user", "name", "gopher"))
+
+	with := calls
+	log.Log(INFO, "{req.token} {req.user.name}")
+	log.Log(INFO, "{req.token}")
+
+	if want, got := "REDACTED gopher\nREDACTED\n", b.String(); got != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+	if calls != with {
+		t.Errorf("stored attrs replaced per record: %d calls after With, %d after logging", with, calls)
+	}
+}
+
+func TestStoreReplaceGroupOpen(t *testing.T) {
+	// the open group holds no attrs yet
+	store := Store{}.WithAttrs([]Attr{slog.Int("a", 1)}).WithGroup("g")
+	if v, _ := store.Get("a"); v.Int64() != 1 {
+		t.Fatalf("before: got %v", v)
+	}
+
+	store.ReplaceAttr(func(scope []string, a Attr) Attr {
+		return slog.Int(a.Key, 2)
+	})
+	if v, _ := store.Get("a"); v.Int64() != 2 {
+		t.Errorf("Get: not replaced, got %v", v)
+	}
+
+	s := newSplicer()
+	defer s.free()
+	s.splice("{a}", store, nil, nil)
+	if got := s.line(); got != "2" {
+		t.Errorf("interpolation: not replaced, got %s", got)
+	}
+}
+
+func TestStoreJSON(t *testing.T) {
+	var store Store
+	if got, _ := json.Marshal(store); string(got) != "{}" {
+		t.Errorf("empty: got %s", got)
+	}
+
+	store = store.
+		WithAttrs(Attrs("id", 1)).
+		WithGroup("req").
+		WithAttrs(Attrs("path", "/", "elapsed", time.Second)).
+		WithAttrs([]Attr{slog.Any("store", Store{}.WithAttrs(Attrs("inner", true)))})
+
+	got, err := json.Marshal(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"req":{"path":"/","elapsed":1000000000,"store":{"inner":true}}}`
+	if string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+
+	snap := store.Snapshot()
+	var keys []string
+	for _, a := range snap {
+		keys = append(keys, a.Key)
+	}
+	if strings.Join(keys, " ") != "id req.path req.elapsed req.store" {
+		t.Errorf("snapshot keys: got %v", keys)
+	}
+
+	// snapshots are copies
+	snap[0].Key = "changed"
+	if !store.Has("id") {
+		t.Errorf("snapshot mutated store")
+	}
+}
+
+func TestMapAttrs(t *testing.T) {
+	m := map[string]any{
+		"port": 5432,
+		"host": "localhost",
+		"db": map[string]any{
+			"name":  "app",
+			"hosts": []any{"a", "b"},
+		},
+	}
+
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	as := Attrs(m)
+	want("[db=[hosts=[0=a 1=b] name=app] host=localhost port=5432]", fmt.Sprint(as))
+
+	as = Attrs("cfg", m, "x", 1)
+	want("[cfg=[db=[hosts=[0=a 1=b] name=app] host=localhost port=5432] x=1]", fmt.Sprint(as))
+
+	want("app b 5432", Fmt("{db.name} {db.hosts.1} {port}", m))
+	want("localhost", Fmt("{cfg.host}", "cfg", m))
+
+	want("g=[db=[hosts=[0=a 1=b] name=app] host=localhost port=5432]", Group("g", m).String())
+
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.With(map[string]any{"b": 2, "a": 1}).Info("with", "cfg", map[string]any{"z": true})
+	want("with\ta:1 b:2 cfg:{z:true}\n", b.String())
 }
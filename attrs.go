@@ -1,26 +1,59 @@
 package logf
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
 	"strings"
 
 	"log/slog"
 	"slices"
 	"strconv"
+	"sync/atomic"
 )
 
 type replaceFunc func([]string, Attr) Attr
 
 // KV constructs an Attr from a key string and a value.
-// See [slog.Any].
+// See [slog.Any]. A [json.RawMessage] value is decoded, as with [JSONBytesValue].
 func KV(key string, value any) Attr {
+	if raw, ok := value.(json.RawMessage); ok {
+		return slog.Attr{Key: key, Value: rawJSONValue(raw)}
+	}
 	return slog.Any(key, value)
 }
 
-// See [slog.Group].
+// Secret holds a value that is redacted when logged.
+// A Secret is a [slog.LogValuer], resolving to "[REDACTED]".
+// A [TTY] configured with [Config.RevealSecrets] displays the held value instead.
+type Secret struct {
+	v any
+}
+
+// Redact returns a [Secret] holding the given value.
+func Redact(v any) Secret {
+	return Secret{v}
+}
+
+// LogValue returns "[REDACTED]".
+func (Secret) LogValue() Value {
+	return slog.StringValue("[REDACTED]")
+}
+
+// resolves a value, revealing the value held by a [Secret] if reveal is set
+func resolveSecret(v Value, reveal bool) Value {
+	if reveal && v.Kind() == slog.KindLogValuer {
+		if sec, ok := v.LogValuer().(Secret); ok {
+			return slog.AnyValue(sec.v).Resolve()
+		}
+	}
+	return v.Resolve()
+}
+
+// See [slog.Group]. As with [Attrs], map[string]any arguments are expanded.
 func Group(name string, as ...any) Attr {
-	return slog.Group(name, as...)
+	return slog.Group(name, expandMaps(as)...)
 }
 
 // See [slog.GroupValue]
@@ -54,8 +87,7 @@ func expandHandler(list *[]Attr, prefix string, h slog.Handler) {
 	if lv, ok := h.(slog.LogValuer); ok {
 		group := lv.LogValue()
 		if group.Kind() == slog.KindGroup {
-			as := scopeAttrs(prefix, group.Group(), nil)
-			*list = append(*list, as...)
+			*list = appendScoped(*list, prefix, group.Group())
 		}
 	}
 }
@@ -65,6 +97,11 @@ func expandHandler(list *[]Attr, prefix string, h slog.Handler) {
 //   - An Attr is appended to the return.
 //   - A slice of Attrs is flattened into the return.
 //   - A [slog.LogValuer] which resolves to a [slog.Group] is flattened into the return.
+//   - Following a key, a [json.RawMessage] is decoded, as with [JSONBytesValue].
+//   - A [Struct] is flattened into the return, as with [StructAttrs].
+//   - A map[string]any is flattened into the return, one Attr per key, in sorted order.
+//     Following a key, a map[string]any is a group.
+//     Nested maps are groups, and []any values are groups keyed by index, as with [JSONValue].
 //
 // Malformed lists result in Attrs indicating missing arguments, keys, or values.
 func Attrs(args ...any) (as []Attr) {
@@ -77,6 +114,27 @@ func Attrs(args ...any) (as []Attr) {
 				return
 			}
 
+			// raw JSON is decoded
+			if raw, ok := args[1].(json.RawMessage); ok {
+				expandAttr(&as, slog.Attr{Key: arg, Value: rawJSONValue(raw)})
+				args = args[2:]
+				continue
+			}
+
+			// a map is a group
+			if m, ok := args[1].(map[string]any); ok {
+				expandAttr(&as, slog.Attr{Key: arg, Value: mapValue(m)})
+				args = args[2:]
+				continue
+			}
+
+			// a Secret resolves late, so it may be revealed
+			if sec, ok := args[1].(Secret); ok {
+				expandAttr(&as, slog.Any(arg, sec))
+				args = args[2:]
+				continue
+			}
+
 			// intercept / expand a LogValuer
 			if lv, ok := args[1].(slog.LogValuer); ok {
 				expandValuer(&as, arg, lv)
@@ -105,10 +163,18 @@ func Attrs(args ...any) (as []Attr) {
 			expandHandler(&as, "", arg.Handler())
 			args = args[1:]
 
+		case Struct:
+			as = append(as, StructAttrs(arg.V)...)
+			args = args[1:]
+
 		case slog.LogValuer:
 			expandValuer(&as, "", arg)
 			args = args[1:]
 
+		case map[string]any:
+			as = append(as, mapAttrs(arg)...)
+			args = args[1:]
+
 		default:
 			as = append(as, slog.Any(missingKey, arg))
 			args = args[1:]
@@ -117,42 +183,295 @@ func Attrs(args ...any) (as []Attr) {
 	return
 }
 
-func scopeAttrs(scope string, as []Attr, replace replaceFunc) []Attr {
-	if scope == "" {
-		return as
+// mapAttrs returns an Attr per key of m, sorted by key
+func mapAttrs(m map[string]any) []Attr {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	slices.Sort(keys)
 
-	scoped := make([]Attr, 0)
-	for _, a := range as {
-		if replace != nil {
-			a = replace(nil, a)
+	as := make([]Attr, 0, len(m))
+	for _, k := range keys {
+		as = append(as, slog.Attr{Key: k, Value: mapElemValue(m[k])})
+	}
+	return as
+}
+
+func mapValue(m map[string]any) Value {
+	return slog.GroupValue(mapAttrs(m)...)
+}
+
+func mapElemValue(x any) Value {
+	switch x := x.(type) {
+	case map[string]any:
+		return mapValue(x)
+	case []any:
+		as := make([]Attr, 0, len(x))
+		for i, elem := range x {
+			as = append(as, slog.Attr{Key: strconv.Itoa(i), Value: mapElemValue(elem)})
 		}
+		return slog.GroupValue(as...)
+	}
+	return slog.AnyValue(x)
+}
 
-		if a.Key == "" {
+// expandMaps rewrites a list of arguments, as given to [slog.Logger.With], so that
+// map[string]any arguments are expanded as by [Attrs]. The list is copied only if it holds a map.
+func expandMaps(args []any) []any {
+	var expanded []any
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case string:
+			if i+1 < len(args) {
+				if m, ok := args[i+1].(map[string]any); ok {
+					if expanded == nil {
+						expanded = slices.Clone(args[:i])
+					}
+					expanded = append(expanded, slog.Attr{Key: arg, Value: mapValue(m)})
+					i++
+					continue
+				}
+			}
+			if expanded != nil {
+				expanded = append(expanded, arg)
+				if i+1 < len(args) {
+					expanded = append(expanded, args[i+1])
+				}
+			}
+			i++
 			continue
+
+		case map[string]any:
+			if expanded == nil {
+				expanded = slices.Clone(args[:i])
+			}
+			for _, a := range mapAttrs(arg) {
+				expanded = append(expanded, a)
+			}
+			continue
+		}
+
+		if expanded != nil {
+			expanded = append(expanded, args[i])
+		}
+	}
+
+	if expanded == nil {
+		return args
+	}
+	return expanded
+}
+
+// replaceAttr applies replace to a, and then to the members of a group-valued result, recursively.
+// The stack gives the scope of a.
+func replaceAttr(stack []string, a Attr, replace replaceFunc) Attr {
+	if replace == nil {
+		return a
+	}
+
+	a = replace(stack, a)
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+
+	group := a.Value.Group()
+	if len(group) == 0 {
+		return a
+	}
+
+	stack = append(stack[:len(stack):len(stack)], a.Key)
+	replaced := make([]Attr, len(group))
+	for i, ga := range group {
+		replaced[i] = replaceAttr(stack, ga, replace)
+	}
+	a.Value = slog.GroupValue(replaced...)
+	return a
+}
+
+// nestFrames nests the attrs of each frame in the group of its scope, returning the top-level frame.
+// frames[i] holds the attrs given at scope[:i]; empty groups are elided. If dedup is set, each frame is deduplicated with [dedupAttrs].
+func nestFrames(scope []string, frames [][]Attr, dedup bool) []Attr {
+	for i := len(scope); i > 0; i-- {
+		if dedup {
+			frames[i] = dedupAttrs(frames[i])
+		}
+		if len(frames[i]) > 0 {
+			frames[i-1] = append(frames[i-1], Attr{Key: scope[i-1], Value: slog.GroupValue(frames[i]...)})
+		}
+	}
+	if dedup {
+		return dedupAttrs(frames[0])
+	}
+	return frames[0]
+}
+
+// dedupAttrs returns as, less any attr followed by another with the same key.
+// The members of group-valued attrs are deduplicated in turn. Attrs with an empty key are kept.
+func dedupAttrs(as []Attr) []Attr {
+	var seen map[string]bool
+	kept := make([]Attr, 0, len(as))
+	for i := len(as) - 1; i >= 0; i-- {
+		a := as[i]
+		if a.Key != "" {
+			if seen[a.Key] {
+				continue
+			}
+			if seen == nil {
+				seen = make(map[string]bool)
+			}
+			seen[a.Key] = true
 		}
+		if a.Value.Kind() == slog.KindGroup {
+			a.Value = slog.GroupValue(dedupAttrs(a.Value.Group())...)
+		}
+		kept = append(kept, a)
+	}
+	slices.Reverse(kept)
+	return kept
+}
 
-		scoped = append(scoped, Attr{
-			Key:   scope + a.Key,
-			Value: a.Value,
-		})
+// appends as to list, with keys prefixed by scope
+func appendScoped(list []Attr, scope string, as []Attr) []Attr {
+	if scope == "" {
+		return append(list, as...)
+	}
+
+	list = slices.Grow(list, len(as))
+	for _, a := range as {
+		if a.Key == "" {
+			continue
+		}
+		a.Key = scope + a.Key
+		list = append(list, a)
 	}
-	return scoped
+	return list
 }
 
-func detectLabel(as []Attr, label Attr) ([]Attr, Attr) {
+// detects label attrs with the given key, returning the remaining attrs and the resulting labels.
+// Labels given together replace inherited ones, while a [Logger.Tags] set is appended to them.
+// Given an empty key, no labels are detected.
+func detectLabel(as []Attr, labels []Attr, key string) ([]Attr, []Attr) {
+	if key == "" {
+		return as, labels
+	}
+
 	var ii int
+	var replaced bool
 
 	for i := range as {
-		if as[i].Key == "#" {
-			label = as[i]
-		} else {
-			as[ii] = as[i]
+		a := as[i]
+		if a.Key != key {
+			as[ii] = a
 			ii++
+			continue
+		}
+
+		if set, ok := a.Value.Any().(tagSet); ok {
+			labels = appendTags(labels, key, set...)
+			continue
+		}
+
+		if !replaced {
+			labels, replaced = nil, true
 		}
+		labels = appendTags(labels, key, a.Value.String())
 	}
 
-	return as[:ii], label
+	return as[:ii], labels
+}
+
+// appends tags not already present to labels, without modifying labels
+func appendTags(labels []Attr, key string, tags ...string) []Attr {
+	labels = slices.Clip(labels)
+	for _, tag := range tags {
+		if !slices.ContainsFunc(labels, func(a Attr) bool { return a.Value.String() == tag }) {
+			labels = append(labels, slog.String(key, tag))
+		}
+	}
+	return labels
+}
+
+// tagSet holds tags added by [Logger.Tags]
+type tagSet []string
+
+// joins each label attr in as to the tag preceding it, with sep.
+// Given an empty sep, as is returned as given; a new tag replaces an inherited one.
+// Tags added by [Logger.Tags] aren't joined.
+func joinTags(as []Attr, labels []Attr, sep, key string) []Attr {
+	if sep == "" || key == "" {
+		return as
+	}
+
+	var label Attr
+	if len(labels) > 0 {
+		label = labels[len(labels)-1]
+	}
+
+	var joined []Attr
+	for i, a := range as {
+		if a.Key != key {
+			continue
+		}
+		if _, ok := a.Value.Any().(tagSet); ok {
+			continue
+		}
+
+		if label.Key == key {
+			if joined == nil {
+				joined = slices.Clone(as)
+			}
+			a = slog.String(key, label.Value.String()+sep+a.Value.String())
+			joined[i] = a
+		}
+		label = a
+	}
+
+	if joined == nil {
+		return as
+	}
+	return joined
+}
+
+// returns the tag of a record: a tag given with the record, joined to the last label as by [joinTags],
+// or else the last label. A [Logger.Tags] set given with the record doesn't count.
+func recordTag(r slog.Record, labels []Attr, sep, key string) (tag string) {
+	if len(labels) > 0 {
+		tag = labels[len(labels)-1].Value.String()
+	}
+	if key == "" {
+		return
+	}
+
+	last := tag
+	r.Attrs(func(a Attr) bool {
+		if a.Key != key {
+			return true
+		}
+		if _, ok := a.Value.Any().(tagSet); ok {
+			return true
+		}
+		tag = a.Value.String()
+		if sep != "" && last != "" {
+			tag = last + sep + tag
+		}
+		return true
+	})
+	return
+}
+
+func detectErr(as []Attr, err error) error {
+	for _, a := range as {
+		if a.Key != "err" {
+			continue
+		}
+		if curr, isErr := a.Value.Any().(error); isErr {
+			err = curr
+		} else {
+			err = nil
+		}
+	}
+	return err
 }
 
 // Store implements the `WithAttrs` and `WithGroup` methods of the [slog.Handler] interface.
@@ -160,70 +479,192 @@ func detectLabel(as []Attr, label Attr) ([]Attr, Attr) {
 type Store struct {
 	scope []string
 	as    [][]Attr
+
+	// dotted key prefixes, keys[i] joins scope[:i+1]
+	keys []string
+
+	// stored attrs by dotted key, for interpolation; replace is applied as attrs are stored
+	dict    *storeDict
+	replace replaceFunc
 }
 
-var attrStoreEmptyTail = Attr{}
+// storeDict maps the dotted keys of stored attrs, and of the members of stored groups, to values.
+// Entries are not modified once built; a Store adding attrs builds a copy.
+// As groups opened without attrs don't change it, the dict also caches the Store's LogValue.
+type storeDict struct {
+	entries []storeEntry // in the order added
+	sorted  []int32      // indices of entries sorted by key, less entries superseded by a later one with the same key
+	value   atomic.Pointer[Value]
+}
 
-// LogValue returns a [Value]
-func (store Store) LogValue() Value {
-	depth := len(store.scope)
-	tail := attrStoreEmptyTail
+type storeEntry struct {
+	key string
+	v   Value
+}
 
-	for depth >= 0 {
-		tail = store.frame(depth, tail)
-		depth--
+// returns a copy of the dict, with as added in the scope given by stack and prefix
+func (d *storeDict) with(stack []string, prefix string, as []Attr, replace replaceFunc) *storeDict {
+	var entries []storeEntry
+	if d != nil {
+		entries = d.entries
 	}
 
-	return tail.Value
+	d2 := &storeDict{entries: make([]storeEntry, len(entries), len(entries)+len(as))}
+	copy(d2.entries, entries)
+	d2.add(stack, prefix, as, replace)
+	return d2
 }
 
-func (store Store) attrsDepth(depth int) []Attr {
-	if len(store.as) == len(store.scope) {
-		return []Attr{}
+func (d *storeDict) add(stack []string, prefix string, as []Attr, replace replaceFunc) {
+	for _, a := range as {
+		a = replaceAttr(stack, a, replace)
+		d.addAttr(prefix, a)
 	}
-	return store.as[depth]
+	d.sort()
 }
 
-func (store Store) attrsDepthAny(depth int) []any {
-	as := store.attrsDepth(depth)
-	var list []any
-	for _, a := range as {
-		list = append(list, a)
+func (d *storeDict) addAttr(prefix string, a Attr) {
+	key := prefix + a.Key
+	d.entries = append(d.entries, storeEntry{key, a.Value})
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			d.addAttr(key+".", ga)
+		}
 	}
-	return list
 }
 
-func (store Store) keyDepth(depth int) string {
-	if depth == 0 {
-		return ""
+// (indices are sorted, rather than entries, as moving entries is costly)
+func (d *storeDict) sort() {
+	sorted := make([]int32, len(d.entries))
+	for i := range sorted {
+		sorted[i] = int32(i)
 	}
-	return store.scope[depth-1]
+
+	slices.SortFunc(sorted, func(i, j int32) int {
+		if c := strings.Compare(d.entries[i].key, d.entries[j].key); c != 0 {
+			return c
+		}
+		return int(i - j)
+	})
+
+	// of entries with the same key, the last added wins
+	n := 0
+	for k, i := range sorted {
+		if k+1 < len(sorted) && d.entries[sorted[k+1]].key == d.entries[i].key {
+			continue
+		}
+		sorted[n] = i
+		n++
+	}
+	d.sorted = sorted[:n]
 }
 
-// Builds a Group-kind Attr capturing the given frame, and ending with the
-// provided tail Attr. This routine is used when building a Store's LogValue,
-// iteratively using the result at depth as the tail for another frame call,
-// for a successively shallower/lower depth.
-func (store Store) frame(depth int, tail Attr) Attr {
-	emptyTail := tail.Key == attrStoreEmptyTail.Key && tail.Value.Equal(attrStoreEmptyTail.Value)
-	emptyFrame := len(store.attrsDepth(depth)) == 0
+func (d *storeDict) len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.sorted)
+}
 
-	if emptyTail && emptyFrame {
-		return attrStoreEmptyTail
+func (d *storeDict) get(key string) (Value, bool) {
+	k, found := slices.BinarySearchFunc(d.sorted, key, func(i int32, key string) int {
+		return strings.Compare(d.entries[i].key, key)
+	})
+	if !found {
+		return Value{}, false
 	}
+	return d.entries[d.sorted[k]].v, true
+}
 
-	key := store.keyDepth(depth)
+// lookup finds the value of key, or else indexes into the value held by the longest dotted prefix of key
+func (d *storeDict) lookup(key string) (Value, bool) {
+	if d == nil {
+		return Value{}, false
+	}
+	if v, ok := d.get(key); ok {
+		return v, true
+	}
 
-	if emptyTail {
-		list := append([]any{}, store.attrsDepthAny(depth)...)
-		return slog.Group(key, list...)
+	for n := strings.LastIndexByte(key, '.'); n > 0; n = strings.LastIndexByte(key[:n], '.') {
+		if v, ok := d.get(key[:n]); ok && v.Kind() == slog.KindAny {
+			return indexValue(v.Any(), key[n+1:])
+		}
 	}
+	return Value{}, false
+}
 
-	if emptyFrame {
-		return slog.Group(key, tail)
+// builds the dict of the store from scratch
+func (store Store) buildDict() *storeDict {
+	d := new(storeDict)
+	for depth := 0; depth < len(store.as) && depth <= len(store.scope); depth++ {
+		d.add(store.scope[:depth], store.scopeKey(depth), store.as[depth], store.replace)
+	}
+	return d
+}
+
+// LogValue returns a [Value]
+func (store Store) LogValue() Value {
+	if store.dict == nil {
+		return store.logValue()
+	}
+	if v := store.dict.value.Load(); v != nil {
+		return *v
+	}
+
+	v := store.logValue()
+	store.dict.value.Store(&v)
+	return v
+}
+
+// Builds nested groups frame by frame, from the deepest frame holding attrs up to the top level.
+// Each frame's group ends with the group of the frame below it.
+func (store Store) logValue() Value {
+	var tail *Attr
+	for depth := len(store.scope); depth >= 0; depth-- {
+		as := store.attrsDepth(depth)
+		if tail == nil && len(as) == 0 {
+			continue
+		}
+
+		group := make([]Attr, len(as), len(as)+1)
+		copy(group, as)
+		if tail != nil {
+			group = append(group, *tail)
+		}
+		tail = &Attr{Key: store.keyDepth(depth), Value: slog.GroupValue(group...)}
 	}
 
-	return slog.Group(key, concatOne(store.attrsDepthAny(depth), any(tail))...)
+	if tail == nil {
+		return Value{}
+	}
+	return tail.Value
+}
+
+func (store Store) attrsDepth(depth int) []Attr {
+	if depth >= len(store.as) {
+		return nil
+	}
+	return store.as[depth]
+}
+
+// scopeKey returns the dotted key prefix of scope at depth, e.g. "a.b.",
+// or "" at depth 0.
+func (store Store) scopeKey(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	if depth <= len(store.keys) {
+		return store.keys[depth-1]
+	}
+	return strings.Join(store.scope[:depth], ".") + "."
+}
+
+func (store Store) keyDepth(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	return store.scope[depth-1]
 }
 
 // Attrs traverses attributes in the [Store], applying the given function to each visited attribute.
@@ -241,58 +682,285 @@ func (store Store) Attrs(f func([]string, Attr)) {
 	}
 }
 
+// Snapshot returns a copy of the attributes in the [Store], with keys qualified by the
+// groups open when they were stored, e.g. "group.key".
+func (store Store) Snapshot() []Attr {
+	var as []Attr
+	for depth := 0; depth < len(store.as) && depth <= len(store.scope); depth++ {
+		prefix := store.scopeKey(depth)
+		for _, a := range store.as[depth] {
+			a.Key = prefix + a.Key
+			as = append(as, a)
+		}
+	}
+	return as
+}
+
+// MarshalJSON encodes the [Store] as a JSON object, with the same nesting as [Store.LogValue].
+// Values are encoded as by the "json" interpolation verb.
+func (store Store) MarshalJSON() ([]byte, error) {
+	v := store.LogValue()
+	if v.Kind() != slog.KindGroup {
+		return []byte("{}"), nil
+	}
+	return appendJSONValue(nil, v, false, timeMillis), nil
+}
+
+// Get returns the value of the attribute with the given dotted key, as interpolation would find it:
+// keys are qualified by open groups, and may reach into groups or index into slices and maps.
+// When more than one attribute matches, the last one wins.
+func (store Store) Get(key string) (Value, bool) {
+	s := newSplicer()
+	defer s.free()
+
+	s.dictAdd(key)
+	s.joinStore(store)
+
+	v := s.vals[s.dict[key]]
+	if v.Equal(missingMatch) {
+		return Value{}, false
+	}
+	return v, true
+}
+
+// Has reports whether [Store.Get] finds the given key.
+func (store Store) Has(key string) bool {
+	_, ok := store.Get(key)
+	return ok
+}
+
 // ReplaceAttr resembles functionality seen in [slog.HandlerOptions]. Unlike [Store.Attrs], it can
 // be used to mutate attributes held in the store.
 func (store Store) ReplaceAttr(f func([]string, Attr) Attr) {
-	for depth := 0; depth <= len(store.scope); depth++ {
-		if len(store.as) == depth {
-			return
-		}
+	for depth := 0; depth <= len(store.scope) && depth < len(store.as); depth++ {
 		for i, a := range store.as[depth] {
 			store.as[depth][i] = f(store.scope[:depth], a)
 		}
 	}
+
+	if store.dict != nil {
+		d := store.buildDict()
+		store.dict.entries, store.dict.sorted = d.entries, d.sorted
+		store.dict.value.Store(nil)
+	}
 }
 
 // WithGroup opens a new group in the [Store].
+// As with [slog.Handler], an empty name opens no group.
 func (store Store) WithGroup(name string) Store {
+	if name == "" {
+		return store
+	}
+
 	as := slices.Clone(store.as)
 	for len(as) <= len(store.scope) {
 		as = append(as, []Attr{})
 	}
 
 	return Store{
-		scope: concatOne(store.scope, name),
-		as:    as,
+		scope:   concatOne(store.scope, name),
+		as:      as,
+		keys:    concatOne(store.keys, store.scopeKey(len(store.scope))+name+"."),
+		dict:    store.dict,
+		replace: store.replace,
 	}
 }
 
 // WithAttrs commits attributes to the [Store].
 func (store Store) WithAttrs(as []Attr) Store {
-	as2 := slices.Clone(store.as)
-
-	if len(as2) == len(store.scope) {
-		as2 = concatOne(as2, slices.Clone(as))
+	var as2 [][]Attr
+	if len(store.as) == len(store.scope) {
+		as2 = concatOne(store.as, slices.Clone(as))
 	} else {
+		as2 = slices.Clone(store.as)
 		as2[len(store.scope)] = concat(store.as[len(store.scope)], as)
 	}
 
+	return Store{
+		scope:   store.scope,
+		as:      as2,
+		keys:    store.keys,
+		dict:    store.dict.with(store.scope, store.scopeKey(len(store.scope)), as, store.replace),
+		replace: store.replace,
+	}
+}
+
+// returns a copy of the [Store], not sharing stored attributes
+func (store Store) clone() Store {
+	as := make([][]Attr, len(store.as))
+	for i := range store.as {
+		as[i] = slices.Clone(store.as[i])
+	}
+
+	var dict *storeDict
+	if store.dict != nil {
+		dict = &storeDict{entries: store.dict.entries, sorted: store.dict.sorted}
+	}
+
+	return Store{
+		scope:   slices.Clone(store.scope),
+		as:      as,
+		keys:    slices.Clone(store.keys),
+		dict:    dict,
+		replace: store.replace,
+	}
+}
+
+// Without returns a copy of the [Store], less any attributes matching the given dotted keys.
+// As with [Store.Get], keys are qualified by open groups, and may reach into groups.
+func (store Store) Without(keys ...string) Store {
+	store, _ = store.without(keys...)
+	return store
+}
+
+// without is [Store.Without], also reporting whether any attribute was removed.
+func (store Store) without(keys ...string) (Store, bool) {
+	var removed bool
+	as2 := slices.Clone(store.as)
+
+	for depth := range as2 {
+		var ok bool
+		as2[depth], ok = withoutAttrs(store.scopeKey(depth), as2[depth], keys)
+		removed = removed || ok
+	}
+	if !removed {
+		return store, false
+	}
+
+	store = Store{
+		scope:   store.scope,
+		as:      as2,
+		keys:    store.keys,
+		replace: store.replace,
+	}
+	store.dict = store.buildDict()
+	return store, true
+}
+
+// returns a copy of as, less attrs with keys (qualified by prefix) found in keys.
+// Groups reached by a key are rebuilt without the matching members.
+func withoutAttrs(prefix string, as []Attr, keys []string) ([]Attr, bool) {
+	var removed bool
+	as2 := make([]Attr, 0, len(as))
+
+	for _, a := range as {
+		key := prefix + a.Key
+		if slices.Contains(keys, key) {
+			removed = true
+			continue
+		}
+
+		if a.Value.Kind() == slog.KindGroup && withinGroup(key, keys) {
+			if group, ok := withoutAttrs(key+".", a.Value.Group(), keys); ok {
+				a.Value = slog.GroupValue(group...)
+				removed = true
+			}
+		}
+		as2 = append(as2, a)
+	}
+
+	return as2, removed
+}
+
+// reports whether any of the keys reaches into the group at key
+func withinGroup(key string, keys []string) bool {
+	for _, k := range keys {
+		if len(k) > len(key) && k[len(key)] == '.' && k[:len(key)] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sansSeed returns a copy of the [Store], less top-level attrs equal to those of seed.
+func (store Store) sansSeed(seed Store) Store {
+	if len(seed.as) == 0 || len(store.as) == 0 {
+		return store
+	}
+
+	as2 := slices.Clone(store.as)
+	as2[0] = slices.DeleteFunc(slices.Clone(as2[0]), func(a Attr) bool {
+		return slices.ContainsFunc(seed.as[0], a.Equal)
+	})
+
+	// (for replay; the dict is not rebuilt)
 	return Store{
 		scope: store.scope,
 		as:    as2,
+		keys:  store.keys,
+	}
+}
+
+// replay rebuilds the structure of the [Store] on top of the given handler,
+// alternating WithAttrs and WithGroup calls frame by frame.
+func (store Store) replay(h slog.Handler) slog.Handler {
+	for depth := 0; depth <= len(store.scope); depth++ {
+		if depth < len(store.as) && len(store.as[depth]) > 0 {
+			h = h.WithAttrs(slices.Clone(store.as[depth]))
+		}
+		if depth < len(store.scope) {
+			h = h.WithGroup(store.scope[depth])
+		}
 	}
+	return h
+}
+
+// BytesValue returns a [Value] of a byte count, displayed by a [TTY] in IEC units, e.g. "1.2 MiB".
+// Encoded as JSON, including by an auxilliary handler, the value is the raw count.
+// A [Config.ReplaceFunc] sees a [slog.KindAny] value holding a [fmt.Stringer], and may recover the count as with [Value.Any].
+func BytesValue(n int64) Value {
+	return slog.AnyValue(byteCount(n))
+}
+
+// SIValue returns a [Value] of a count, displayed by a [TTY] with SI prefixes, e.g. "3.4M".
+// As with [BytesValue], encoded as JSON the value is the raw count.
+func SIValue(n int64) Value {
+	return slog.AnyValue(siCount(n))
+}
+
+// byteCount is an int64 humanized as a count of bytes
+type byteCount int64
+
+func (n byteCount) String() string {
+	return string(appendBytes(nil, float64(n)))
+}
+
+// siCount is an int64 humanized with SI prefixes
+type siCount int64
+
+func (n siCount) String() string {
+	return string(appendSI(nil, float64(n)))
 }
 
 // JSONValue converst a JSON object to a [Value]. Array values are expanded
 // to attributes with a key string derived from array index (i.e., the 0th element is keyed "0").
 func JSONValue(object string) (Value, error) {
-	dec := json.NewDecoder(strings.NewReader(object))
+	return DecodeJSON(strings.NewReader(object))
+}
+
+// JSONBytesValue is like [JSONValue], given bytes.
+func JSONBytesValue(object []byte) (Value, error) {
+	return DecodeJSON(bytes.NewReader(object))
+}
+
+// DecodeJSON is like [JSONValue], decoding the first JSON value read from r.
+func DecodeJSON(r io.Reader) (Value, error) {
+	dec := json.NewDecoder(r)
 	dec.UseNumber()
 
 	v, err := parseValue(dec)
 	return v, err
 }
 
+// decodes raw JSON, falling back to the raw string
+func rawJSONValue(raw json.RawMessage) Value {
+	v, err := JSONBytesValue(raw)
+	if err != nil {
+		return slog.StringValue(string(raw))
+	}
+	return v
+}
+
 func parseKey(dec *json.Decoder) (string, error) {
 	keyToken, err := dec.Token()
 	if err != nil {
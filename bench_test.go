@@ -265,6 +265,8 @@ func BenchmarkAttrs(b *testing.B) {
 		{"Text discard", slog.NewTextHandler(io.Discard, &slog.HandlerOptions{AddSource: false})},
 		{"JSON discard", slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{AddSource: false})},
 		{"logf discard", New().Writer(io.Discard).JSON().Handler().(handler)},
+		{"logf native discard", New().Writer(io.Discard).NativeJSON(true).JSON().Handler().(handler)},
+		{"logf TTY discard", New().Writer(io.Discard).ForceTTY(true).TTY()},
 	} {
 		logger := slog.New(handler.h)
 		b.Run(handler.name, func(b *testing.B) {
@@ -391,6 +393,16 @@ func BenchmarkSplicer(b *testing.B) {
 		}
 	})
 
+	b.Run("splicer scan 5 keys", func(b *testing.B) {
+		s := newSplicer()
+		defer s.free()
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.scanMessage("{string} {status} {duration} {time} {error}")
+		}
+	})
+
 	// b.Run("splicer join 5 attrs 5 args", func(b *testing.B) {
 	// 	s := newSplicer()
 	// 	defer s.free()
@@ -407,12 +419,7 @@ func BenchmarkSplicer(b *testing.B) {
 		defer s.free()
 
 		s.scanMessage("{} {} {} {} {}")
-		s.joinStore(Store{
-			scope: []string{},
-			as: [][]Attr{
-				TestAttrs,
-			},
-		}, nil)
+		s.joinStore(Store{}.WithAttrs(TestAttrs))
 
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
@@ -425,12 +432,7 @@ func BenchmarkSplicer(b *testing.B) {
 		defer s.free()
 
 		s.scanMessage("{string} {status} {duration} {time} {error}")
-		s.joinStore(Store{
-			scope: []string{},
-			as: [][]Attr{
-				TestAttrs,
-			},
-		}, nil)
+		s.joinStore(Store{}.WithAttrs(TestAttrs))
 
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
@@ -1,9 +1,13 @@
 package logf
 
 import (
+	"cmp"
+	"context"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
 	"sync"
 	"time"
 )
@@ -31,26 +35,67 @@ var stdMutex sync.Mutex
 // Methods applying to any handler or logger produced by the Config, and defaults:
 //   - [Config.Writer]: os.Stdout
 //   - [Config.Ref]: logf.StdRef
+//   - [Config.Scopes]: logf.StdScopes
 //   - [Config.AddSource]: false
+//   - [Config.SourceSkip]: 0
+//   - [Config.AddStack]: none
+//   - [Config.ConsumeInterpolated]: false
+//   - [Config.ContextAttrs]: nil
+//   - [Config.Observer]: nil
+//   - [Config.OnRecord]: none
+//   - [Config.RepanicAfterRecover]: false
 //   - [Config.ReplaceFunc]: nil
+//   - [Config.HandlerOptions]: none
+//   - [Config.TagJoin]: ""
+//   - [Config.TagKey]: "#"
+//   - [Config.TimePrecision]: time.Millisecond
+//
+// Methods applying to JSON and text output, and defaults:
+//   - [Config.DedupKeys]: false
+//   - [Config.Keys]: "time", "level", "msg", "source"
+//
+// Methods applying only to a [Logger] returned by [Config.JSON], and defaults:
+//   - [Config.NativeJSON]: false
 //
 // Methods applying only to a [TTY], or a logger based on one, and default arguments:
 //   - [Config.Aux]: none
+//   - [Config.AuxRef]: the reference level
+//   - [Config.AuxTagKey]: the tag key
+//   - [Config.FilterAux]: false
+//   - [Config.FloatFormat]: 'g', -1
 //   - [Config.ForceAux]: false
 //   - [Config.ForceTTY]: false
+//   - [Config.PrinterLayout]: "tags", "message"
+//   - [Config.RevealSecrets]: false
+//   - [Config.SanitizeControl]: true
 //
 // Methods configuring the color and encoding of [TTY] fields:
 //   - [Config.ShowAttrKey]
 //   - [Config.ShowAttrValue]
+//   - [Config.ShowBytesMax]: 32
 //   - [Config.ShowColor]: true
+//   - [Config.ShowErrorChain]: false
+//   - [Config.ShowErrorChainDepth]: 8
+//   - [Config.ShowFloatTrimZero]: false
 //   - [Config.ShowGroup]: "dim"
+//   - [Config.ShowGroupStyle]: "braces"
+//   - [Config.ShowHost]: "dim", the hostname
+//   - [Config.ShowInterpolated]: none
+//   - [Config.ShowKeyColor]: none
 //   - [Config.ShowLayout]: "level", "time", "tags", "message", "\t", "attrs"
+//   - [Config.ShowLayoutAt]: none
 //   - [Config.ShowLevel]: LevelBar
 //   - [Config.ShowLevelColors]: "bright cyan", "bright green", "bright yellow", "bright red"
+//   - [Config.ShowTraceColor]: "dim blue"
+//   - [Config.ShowMapSort]: true
 //   - [Config.ShowMessage]: ""
+//   - [Config.ShowQuoting]: "auto"
 //   - [Config.ShowSource]: "dim", SourceAbs
+//   - [Config.ShowSourceLink]: none
+//   - [Config.ShowStructFields]: true
 //   - [Config.ShowTag]: "#", "bright magenta"
 //   - [Config.ShowTagEncode]: nil
+//   - [Config.ShowTagKeys]: false
 //   - [Config.ShowTime]: "dim", TimeShort
 //
 // 3. A Config method returning a [Logger] or a [TTY] closes the chained invocation:
@@ -63,17 +108,39 @@ type Config struct {
 	w *ttySyncWriter
 
 	// slog.Handler config
-	ref     *slog.LevelVar
+	ref     levelRef
+	auxRef  *slog.LevelVar
+	scopes  *LevelTree
 	replace func([]string, Attr) Attr
 
 	// tty gadgets
 	aux        slog.Handler
 	fmtr       *ttyFormatter
+	printer    []ttyField
 	addSource  bool
 	addColors  bool
+	addStack   bool
+	stackLevel slog.Level
+	skip       int
+	ctxAttrs   func(context.Context) []Attr
+	observe    func(slog.Level, string, bool)
+	onRecord   recordHooks
+	repanic    bool
+	consume    bool
+	timePrec   int
+	timePrecOk bool
+	tagJoin    string
+	tagKey     string
+	auxTagKey  *string
+	filterAux  bool
 	enableTTY  bool
 	forceTTY   bool
 	forceAux   bool
+	reveal     bool
+	sanitize   bool
+	nativeJSON bool
+	dedupKeys  bool
+	keys       *builtinKeys
 	setDefault bool
 }
 
@@ -84,10 +151,15 @@ func New() *Config {
 	cfg := &Config{
 		w:         w,
 		ref:       &StdRef,
+		scopes:    &StdScopes,
 		replace:   nil,
 		addColors: true,
+		sanitize:  true,
+		tagKey:    "#",
+		timePrec:  timeMillis,
 
 		fmtr:      newTTYFormatter(),
+		printer:   []ttyField{ttyTagsField, ttyMessageField},
 		enableTTY: enableTTY,
 	}
 
@@ -111,6 +183,49 @@ func (cfg *Config) Ref(level *slog.LevelVar) *Config {
 	return cfg
 }
 
+// HandlerOptions configures the Config as the given [slog.HandlerOptions] would configure a slog handler:
+//   - Level configures the reference level, as with [Config.Ref]. A Leveler that isn't a [slog.LevelVar] is consulted as its level changes.
+//     If Level is nil, the reference level is unchanged.
+//   - AddSource configures [Config.AddSource].
+//   - ReplaceAttr configures [Config.ReplaceFunc].
+//
+// As with other Config methods, a later conflicting call wins.
+func (cfg *Config) HandlerOptions(opts *slog.HandlerOptions) *Config {
+	if opts == nil {
+		return cfg
+	}
+	if opts.Level != nil {
+		cfg.ref = adaptLeveler(opts.Level)
+	}
+	cfg.addSource = opts.AddSource
+	cfg.replace = opts.ReplaceAttr
+	return cfg
+}
+
+// AuxRef configures the reference [slog.LevelVar] of the auxilliary handler a [TTY] builds, when none is given to [Config.Aux].
+// By default, the auxilliary handler uses the same reference level as the TTY (see [Config.Ref]).
+// A TTY is enabled for a level if either the TTY or its auxilliary handler is, and each handles only records at levels it's enabled for.
+// For example, a TTY may show INFO and above, while its auxilliary handler logs DEBUG and above to a file.
+func (cfg *Config) AuxRef(level *slog.LevelVar) *Config {
+	cfg.auxRef = level
+	return cfg
+}
+
+// returns the reference level of the auxilliary handler a TTY builds
+func (cfg *Config) auxLevel() slog.Leveler {
+	if cfg.auxRef != nil {
+		return cfg.auxRef
+	}
+	return cfg.ref
+}
+
+// Scopes configures the use of the given [LevelTree], setting minimum levels per group scope.
+// A nil tree disables scoped levels.
+func (cfg *Config) Scopes(tree *LevelTree) *Config {
+	cfg.scopes = tree
+	return cfg
+}
+
 // Writer configures the eventual destination of log lines.
 // Configuring a new writer creates a new mutex guarding it.
 func (cfg *Config) Writer(w io.Writer) *Config {
@@ -128,6 +243,8 @@ func (cfg *Config) ShowColor(toggle bool) *Config {
 
 // ShowTime sets a color and an encoder for the [slog.Record.Time] field.
 // If the enc argument is nil, the configuration uses the [TimeShort] function.
+// Other built-in encoders are [TimeShortMillis] and [TimeShortMicro], showing fractional seconds,
+// [TimeRFC3339Nano], and [TimeDelta], showing the time since the previous record handled by the TTY.
 func (cfg *Config) ShowTime(color string, enc Encoder[time.Time]) *Config {
 	if enc == nil {
 		enc = EncodeFunc(encTimeShort)
@@ -156,6 +273,13 @@ func (cfg *Config) ShowLevelColors(debug string, info string, warn string, error
 	return cfg
 }
 
+// ShowTraceColor configures a color for levels below DEBUG, e.g. [TRACE].
+// These levels are otherwise colored as DEBUG is (see [Config.ShowLevelColors]).
+func (cfg *Config) ShowTraceColor(color string) *Config {
+	cfg.fmtr.tracePen = newPen(color)
+	return cfg
+}
+
 // ShowMessage sets a color for the [slog.Record.Message] field.
 func (cfg *Config) ShowMessage(color string) *Config {
 	cfg.fmtr.message = ttyEncoder[string]{newPen(color), nil}
@@ -163,8 +287,8 @@ func (cfg *Config) ShowMessage(color string) *Config {
 }
 
 // ShowAttrKey sets a color and an encoder for [slog.Attr.Key] encoding.
-// If the enc argument is nil, the configuration uses an [Encoder] that simply writes the [slog.Attr.Key].
-// TODO: this default does no escaping. Perhaps JSON quoting and escaping would be useful.
+// If the enc argument is nil, the configuration uses an [Encoder] that writes the [slog.Attr.Key],
+// quoted as configured by [Config.ShowQuoting].
 func (cfg *Config) ShowAttrKey(color string, enc Encoder[string]) *Config {
 	if enc == nil {
 		enc = EncodeFunc(encKey)
@@ -174,8 +298,8 @@ func (cfg *Config) ShowAttrKey(color string, enc Encoder[string]) *Config {
 }
 
 // ShowAttrValue sets a color and an encoder for [slog.Attr.Value] encoding.
-// If the enc argument is nil, the configuration uses an default [Encoder].
-// TODO: this default does no escaping. Perhaps JSON quoting and escaping would be useful.
+// If the enc argument is nil, the configuration uses an default [Encoder],
+// quoting as configured by [Config.ShowQuoting].
 func (cfg *Config) ShowAttrValue(color string, enc Encoder[Value]) *Config {
 	if enc == nil {
 		enc = EncodeFunc(encValue)
@@ -184,8 +308,175 @@ func (cfg *Config) ShowAttrValue(color string, enc Encoder[Value]) *Config {
 	return cfg
 }
 
+// ShowAttrs sets a color and an encoder for the attrs field, taking over from the key and value encoders.
+// The encoder is given the attrs of a log line: those given to WithAttrs, followed by those given with the record,
+// after replacement (see [Config.ReplaceFunc]), and nested in groups opened with WithGroup.
+// Built-in encoders are [AttrsJSON] and [AttrsLogfmt].
+// If the enc argument is nil, the configuration uses the key and value encoders.
+func (cfg *Config) ShowAttrs(color string, enc Encoder[[]Attr]) *Config {
+	cfg.fmtr.attrs = ttyEncoder[[]Attr]{newPen(color), enc}
+	return cfg
+}
+
+// ShowAttrsDiff toggles a diff mode for the attrs field.
+// When the attrs given to WithAttrs are rendered just as on the previous line, they're replaced by a dim placeholder
+// counting them, e.g. "(+15 ctx)". Attrs given with a record are always shown, as are all attrs of records at ERROR and above.
+// The diff mode doesn't apply to an encoder set with [Config.ShowAttrs].
+func (cfg *Config) ShowAttrsDiff(toggle bool) *Config {
+	cfg.fmtr.attrsDiff = toggle
+	return cfg
+}
+
+// ShowQuoting sets when the default key and value encoders quote text, as with [strconv.Quote].
+// The mode is one of:
+//   - "auto": quote text containing whitespace, ':', '{', '}', or control characters
+//   - "always": quote all text
+//   - "never": never quote
+//
+// Other modes are treated as "auto".
+func (cfg *Config) ShowQuoting(mode string) *Config {
+	switch mode {
+	case "always":
+		cfg.fmtr.quote = quoteAlways
+	case "never":
+		cfg.fmtr.quote = quoteNever
+	default:
+		cfg.fmtr.quote = quoteAuto
+	}
+	return cfg
+}
+
+// ShowInterpolated sets a color highlighting values interpolated into [TTY] messages, distinguishing them from literal text.
+// Highlighting applies only to [TTY] output, and not to text interpolated by [Fmt], [WrapErr], or similar,
+// nor to the message an auxilliary handler receives. By default, interpolated values are not highlighted.
+func (cfg *Config) ShowInterpolated(color string) *Config {
+	cfg.fmtr.ipolPen = newPen(color)
+	return cfg
+}
+
+// ShowKeyColor sets colors for the key and value of attrs with the given key, overriding the colors set by
+// [Config.ShowAttrKey] and [Config.ShowAttrValue]. An empty color defers to those colors.
+// A dotted key (e.g. "req.status") matches an attr within the named groups, including groups opened with WithGroup,
+// and takes precedence over a key without groups (e.g. "status"), which matches an attr in any group.
+func (cfg *Config) ShowKeyColor(key string, keyColor, valueColor string) *Config {
+	if cfg.fmtr.keyColors == nil {
+		cfg.fmtr.keyColors = make(map[string]keyPens)
+	}
+	cfg.fmtr.keyColors[key] = keyPens{newPen(keyColor), newPen(valueColor)}
+	return cfg
+}
+
+// AbbreviateKey configures a [TTY] to show attrs with the given key, or dotted key (including open groups), by a short key.
+// Abbreviations are only shown in the attrs field: interpolation, auxilliary handlers, and attrs encoders given to
+// [Config.ShowAttrs] see the full key. Per-key colors (see [Config.ShowKeyColor]) are set by the full key.
+func (cfg *Config) AbbreviateKey(full, short string) *Config {
+	if cfg.fmtr.keyAbbrev == nil {
+		cfg.fmtr.keyAbbrev = make(map[string]string)
+	}
+	cfg.fmtr.keyAbbrev[full] = short
+	return cfg
+}
+
+// AbbreviateScope configures a [TTY] to show groups with the given name by a short name, as with [Config.AbbreviateKey].
+func (cfg *Config) AbbreviateScope(full, short string) *Config {
+	if cfg.fmtr.scopeAbbrev == nil {
+		cfg.fmtr.scopeAbbrev = make(map[string]string)
+	}
+	cfg.fmtr.scopeAbbrev[full] = short
+	return cfg
+}
+
+// ShowErrorChain configures a [TTY] to render the chain of errors wrapped by a record's error (as with [errors.Unwrap]),
+// each on its own dim, indented "caused by: " line following the log line.
+// Errors wrapped together, as by [errors.Join], are rendered as siblings, each followed by its own chain, further indented.
+// An auxilliary handler receives the chain as an "err_chain" group.
+// See [Config.ShowErrorChainDepth] to limit the depth of the chain.
+func (cfg *Config) ShowErrorChain(toggle bool) *Config {
+	cfg.fmtr.errChain = toggle
+	return cfg
+}
+
+// ShowErrorChainDepth sets the number of links followed when rendering an error chain (see [Config.ShowErrorChain]).
+// Non-positive depths are ignored.
+func (cfg *Config) ShowErrorChainDepth(depth int) *Config {
+	if depth > 0 {
+		cfg.fmtr.errChainDepth = depth
+	}
+	return cfg
+}
+
+// ShowGroupStyle sets how a [TTY] renders groups of attrs. The style is one of:
+//   - "braces": nested groups are enclosed, as in `outer:{inner:{x:1}}`
+//   - "dots": nested groups are flattened into dotted keys, as in `outer.inner.x:1`
+//   - "tree": attrs are rendered as an indented tree, on lines following the log line
+//
+// Other styles are treated as "braces".
+// In the "dots" style, group values interpolated into messages are flattened likewise, as in `[inner.x=1]`.
+// The "tree" style applies only when writing to a terminal; otherwise, the "braces" style is used.
+func (cfg *Config) ShowGroupStyle(style string) *Config {
+	cfg.fmtr.groupDots = style == "dots"
+	cfg.fmtr.groupTree = style == "tree"
+	return cfg
+}
+
+// ShowBytesMax sets how many bytes of a byte slice a [TTY] renders. Byte slices are rendered in lowercase hex,
+// and a truncated slice is followed by its full length, as in `0a1b…(64B)`. If max is not positive, byte slices aren't truncated.
+// Interpolation verbs render byte slices and strings in full: "hex" as lowercase hex, "b64" as standard base64, and "len" as a length.
+func (cfg *Config) ShowBytesMax(max int) *Config {
+	cfg.fmtr.hexMax = max
+	return cfg
+}
+
+// FloatFormat sets the format and precision a [TTY] renders floats with, as with [strconv.AppendFloat].
+// The format is one of 'b', 'e', 'E', 'f', 'g', 'G', 'x', or 'X'; other formats are treated as 'g'.
+// Floats interpolated with a verb, as in `{x:%.2f}`, are rendered as the verb specifies.
+func (cfg *Config) FloatFormat(format byte, prec int) *Config {
+	switch format {
+	case 'b', 'e', 'E', 'f', 'g', 'G', 'x', 'X':
+	default:
+		format = 'g'
+	}
+	cfg.fmtr.floatFmt, cfg.fmtr.floatPrec = format, prec
+	return cfg
+}
+
+// ShowFloatTrimZero sets whether a [TTY] renders floats that are whole numbers without a decimal point,
+// regardless of the format set by [Config.FloatFormat].
+func (cfg *Config) ShowFloatTrimZero(toggle bool) *Config {
+	cfg.fmtr.floatTrim = toggle
+	return cfg
+}
+
+// ShowMapSort sets whether a [TTY] sorts the keys of maps. A [TTY] renders maps as groups, as in `{a:1 b:2}`,
+// and nested maps and slices of them likewise, up to 64 entries of each.
+// Without sorting, entries are rendered in Go's randomized map order, which may be preferable for very large maps.
+func (cfg *Config) ShowMapSort(toggle bool) *Config {
+	cfg.fmtr.mapSort = toggle
+	return cfg
+}
+
+// ShowStructFields sets whether a [TTY] renders struct values with field names, as in `{first:Fox last:Mulder}`,
+// rather than as with %v, as in `{Fox Mulder}`. Fields are keyed as with [StructAttrs].
+// Values implementing [fmt.Stringer] or error are rendered as with %v regardless.
+func (cfg *Config) ShowStructFields(toggle bool) *Config {
+	cfg.fmtr.structFields = toggle
+	return cfg
+}
+
+// ShowHost sets a color for the "host" and "pid" layout fields, and a name for the "host" field.
+// If the override argument is empty, the hostname reported by the system is used.
+// When these fields are in the layout, the preset JSON auxilliary handler includes "host" and "pid" attrs as well.
+func (cfg *Config) ShowHost(color string, override string) *Config {
+	cfg.fmtr.hostPen = newPen(color)
+	if override != "" {
+		cfg.fmtr.host = override
+	}
+	return cfg
+}
+
 // ShowGroup sets a color and a pair of encoders for opening and closing groups.
 // If the open or close arguments are nil, [Encoder]s that write "{" or "}" tokens are used.
+// The encoders apply only in the "braces" group style (see [Config.ShowGroupStyle]).
 func (cfg *Config) ShowGroup(color string, open Encoder[int], close Encoder[int]) *Config {
 	cfg.fmtr.groupPen = newPen(color)
 	if open == nil {
@@ -199,7 +490,8 @@ func (cfg *Config) ShowGroup(color string, open Encoder[int], close Encoder[int]
 	return cfg
 }
 
-// ShowSource sets a color and an encoder for [SourceLine] encoding.
+// ShowSource sets a color and an encoder for [slog.Source] encoding.
+// Some encoders are provided: [SourceAbs], [SourceRel], [SourceShort], [SourceFunc], and [SourcePkg].
 // If the enc argument is nil, the configuration uses the [SourceAbs] function.
 // Configurations must set [Config.AddSource] to output source annotations.
 func (cfg *Config) ShowSource(color string, enc Encoder[*slog.Source]) *Config {
@@ -210,6 +502,14 @@ func (cfg *Config) ShowSource(color string, enc Encoder[*slog.Source]) *Config {
 	return cfg
 }
 
+// ShowSourceLink wraps the configured source encoder with [SourceLink], using the given template,
+// so that source annotations link to the source file, e.g. with "vscode" or "file".
+// A subsequent call to [Config.ShowSource] replaces the link.
+func (cfg *Config) ShowSourceLink(template string) *Config {
+	cfg.fmtr.source.Encoder = SourceLink(template, cfg.fmtr.source.Encoder)
+	return cfg
+}
+
 // ShowTag configures tagging values with the given key.
 // If tagged, an [Attr]'s value appears,in the given color, in the "tags" field of the log line.
 func (cfg *Config) ShowTag(key string, color string) *Config {
@@ -226,12 +526,140 @@ func (cfg *Config) ShowTagEncode(key string, color string, enc Encoder[Attr]) *C
 	return cfg
 }
 
+// ShowTagKeys configures the tags field to show the key of each tag-keyed attr, as "key=value", with the key dimmed.
+// The keys of attrs in groups are qualified by the group keys, e.g. "req.span_id=...".
+// A logger's tag (see [Config.TagKey]) is shown without its key. By default, only values are shown.
+func (cfg *Config) ShowTagKeys(toggle bool) *Config {
+	cfg.fmtr.tagKeys = toggle
+	return cfg
+}
+
+// ShowTagOrder pins the order in which tag-keyed attrs appear in the "tags" field, by key.
+// A logger's tag (see [Config.TagKey]) always appears first, and tags with unlisted keys follow listed ones,
+// in the order they were given. Without a tag order, tags given to WithAttrs precede those given with a record.
+func (cfg *Config) ShowTagOrder(keys ...string) *Config {
+	cfg.fmtr.tagOrder = make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, found := cfg.fmtr.tagOrder[key]; !found {
+			cfg.fmtr.tagOrder[key] = i
+		}
+	}
+	return cfg
+}
+
 // AddSource configures the inclusion of source file and line information in log lines.
 func (cfg *Config) AddSource(toggle bool) *Config {
 	cfg.addSource = toggle
 	return cfg
 }
 
+// AddStack configures the capture of a stack trace for records at or above the given level.
+// The stack is attached to records as a "stack" [Attr], a group of frames with file, line, and func attributes.
+// A [TTY] displays the frames, indented, below the log line.
+// Frames inside logf are elided.
+func (cfg *Config) AddStack(min slog.Level) *Config {
+	cfg.addStack = true
+	cfg.stackLevel = min
+	return cfg
+}
+
+// TagJoin configures how a logger's tag (see [Config.TagKey]) is set when one is already present.
+// By default, a new tag replaces the inherited one.
+// Given a non-empty separator, a new tag is appended to the inherited one, e.g. "parent/child".
+// A [TTY] filter (see [TTY.Filter]) matches a joined tag in full, or any of its components.
+func (cfg *Config) TagJoin(sep string) *Config {
+	cfg.tagJoin = sep
+	return cfg
+}
+
+// TagKey configures the key of attrs treated as a logger's tag, e.g. by [Logger.Tags] and [Config.TagJoin].
+// A [TTY] displays a tag in the tags field, in the color given by [Config.ShowTag] for the key, or else for "#".
+// Setting a key that user data won't employ, e.g. "logf.tag", avoids misinterpreting ordinary attrs.
+// An empty key disables tags: attrs keyed "#" are ordinary attrs.
+func (cfg *Config) TagKey(key string) *Config {
+	cfg.tagKey = key
+	return cfg
+}
+
+// AuxTagKey configures how a [TTY] passes its tags (see [Config.TagKey]) to an auxilliary handler:
+// keyed by the given key, e.g. "tag", or not at all if the key is empty.
+// By default, tags are passed with the key they were given. The [TTY] display is unaffected.
+func (cfg *Config) AuxTagKey(key string) *Config {
+	cfg.auxTagKey = &key
+	return cfg
+}
+
+// Observer configures a function called once per record a handler receives, with the record's level and tag,
+// and whether the record was dropped by filtering (see [TTY.Filter] and [Config.OnRecord]).
+// It's called after the record is written, outside of any lock on the writer.
+// [Counters] provides an implementation.
+func (cfg *Config) Observer(observe func(level slog.Level, tag string, dropped bool)) *Config {
+	cfg.observe = observe
+	return cfg
+}
+
+// OnRecord adds a hook called with each record a handler receives, before the record is encoded.
+// A hook may modify the record, e.g. with [slog.Record.AddAttrs], and returning false drops the record.
+// Hooks run in the order they were added. With a [TTY], modifications reach an auxilliary handler as well.
+func (cfg *Config) OnRecord(hook func(ctx context.Context, r *slog.Record) bool) *Config {
+	cfg.onRecord = append(slices.Clip(cfg.onRecord), hook)
+	return cfg
+}
+
+// TimePrecision configures the precision of times rendered in RFC3339 format without an interpolation verb:
+// time values rendered by a [TTY] or in interpolated messages, and times encoded by the native JSON handler (see [Config.NativeJSON]).
+// The precision is rounded to a power of ten seconds, from time.Second (no fractional digits) to time.Nanosecond.
+// Unless it is configured, the native JSON handler encodes time values as a [slog.JSONHandler] does, in [time.RFC3339Nano] format.
+func (cfg *Config) TimePrecision(d time.Duration) *Config {
+	prec := 0
+	for unit := time.Second; unit > d && prec < 9; unit /= 10 {
+		prec++
+	}
+	cfg.timePrec = prec
+	cfg.timePrecOk = true
+	return cfg
+}
+
+// RepanicAfterRecover configures whether [Logger.Recover] resumes a panic after logging it.
+func (cfg *Config) RepanicAfterRecover(toggle bool) *Config {
+	cfg.repanic = toggle
+	return cfg
+}
+
+// ConsumeInterpolated configures whether arguments interpolated into a logged message are consumed,
+// rather than also exported as attrs of the record.
+// An argument is consumed if a keyed interpolation site names its key (or a key within it, if it's a group),
+// or if it's interpolated by an unkeyed or positional site, or by `{*}`.
+// Only arguments given with a message are consumed; stored attrs are exported as usual.
+// The formatting methods of a [Logger], e.g. [Logger.Infof] and [Logger.Log], respect the configuration.
+func (cfg *Config) ConsumeInterpolated(toggle bool) *Config {
+	cfg.consume = toggle
+	return cfg
+}
+
+// FilterAux configures whether records dropped by [TTY.Filter] are also withheld from an auxilliary handler.
+// By default, an auxilliary handler receives every record.
+func (cfg *Config) FilterAux(toggle bool) *Config {
+	cfg.filterAux = toggle
+	return cfg
+}
+
+// ContextAttrs configures a function extracting attributes from the context passed to a handler.
+// Extracted attributes are added to a record as if given at the call site.
+//
+// Contexts reach handlers by way of context-accepting methods, e.g. [Logger.InfoContext].
+func (cfg *Config) ContextAttrs(fn func(context.Context) []Attr) *Config {
+	cfg.ctxAttrs = fn
+	return cfg
+}
+
+// SourceSkip configures any [Logger] produced by the configuration to skip n additional call frames
+// when capturing source information. See [Logger.Depth].
+func (cfg *Config) SourceSkip(n int) *Config {
+	cfg.skip = n
+	return cfg
+}
+
 // ShowLayout configures the fields encoded in a [TTY] log line.
 //
 // ShowLayout recognizes the following strings (and ignores others):
@@ -243,6 +671,8 @@ func (cfg *Config) AddSource(toggle bool) *Config {
 //   - "attrs" (alt "attr")
 //   - "tags" (alt "tag")
 //   - "source" (alt "src")
+//   - "host" (see [Config.ShowHost])
+//   - "pid"
 //
 // Spacing:
 //   - "\n" (results in a newline, followed by a tab)
@@ -251,40 +681,95 @@ func (cfg *Config) AddSource(toggle bool) *Config {
 //
 // If [Config.AddSource] is configured, source information is the last field encoded in a log line.
 func (cfg *Config) ShowLayout(fields ...string) *Config {
-	cfg.fmtr.layout = cfg.fmtr.layout[:0]
+	cfg.fmtr.layout = parseLayout(cfg.fmtr.layout[:0], fields)
+	return cfg
+}
+
+// ShowLayoutAt sets a layout for log lines at or above the given level, with fields as in [Config.ShowLayout].
+// A line is encoded with the layout registered at the greatest level not exceeding its own.
+// Lines below every registered level use the layout given by [Config.ShowLayout].
+// Registering a layout at an already registered level replaces it.
+func (cfg *Config) ShowLayoutAt(level slog.Level, fields ...string) *Config {
+	layout := parseLayout(nil, fields)
+
+	i, found := slices.BinarySearchFunc(cfg.fmtr.levelLayouts, level, func(ll levelLayout, level slog.Level) int {
+		return cmp.Compare(ll.level, level)
+	})
+	if found {
+		cfg.fmtr.levelLayouts[i].layout = layout
+	} else {
+		cfg.fmtr.levelLayouts = slices.Insert(cfg.fmtr.levelLayouts, i, levelLayout{level, layout})
+	}
+	return cfg
+}
 
-	var f ttyField
+// ShowFieldAt sets a minimum level for a field of the layout, as named in [Config.ShowLayout].
+// In log lines below the level, the field is skipped, along with the spacing preceding it.
+// Fields without a minimum level are always shown. For example, to show source and attrs only at WARN and above:
+//
+//	cfg.ShowFieldAt("source", WARN).ShowFieldAt("attrs", WARN)
+//
+// Spacing fields are shown as needed, and can't be given a minimum level.
+func (cfg *Config) ShowFieldAt(field string, min slog.Level) *Config {
+	f, ok := parseField(field)
+	if !ok || f >= ttyNewlineField {
+		return cfg
+	}
+
+	if cfg.fmtr.fieldMin == nil {
+		cfg.fmtr.fieldMin = make(map[ttyField]slog.Level)
+	}
+	cfg.fmtr.fieldMin[f] = min
+	return cfg
+}
+
+func parseLayout(layout []ttyField, fields []string) []ttyField {
 	for _, s := range fields {
-		switch s {
-		case " ":
-			f = ttySpaceField
-		case "\n":
-			f = ttyNewlineField
-		case "\t":
-			f = ttyTabField
-		case "time":
-			f = ttyTimeField
-		case "level":
-			f = ttyLevelField
-		case "msg", "message":
-			f = ttyMessageField
-		case "attr", "attrs":
-			f = ttyAttrsField
-		case "tag", "tags":
-			f = ttyTagsField
-		case "src", "source":
-			f = ttySourceField
-		default:
-			continue
+		if f, ok := parseField(s); ok {
+			layout = append(layout, f)
 		}
+	}
+	return layout
+}
 
-		cfg.fmtr.layout = append(cfg.fmtr.layout, f)
+func parseField(s string) (f ttyField, ok bool) {
+	switch s {
+	case " ":
+		f = ttySpaceField
+	case "\n":
+		f = ttyNewlineField
+	case "\t":
+		f = ttyTabField
+	case "time":
+		f = ttyTimeField
+	case "level":
+		f = ttyLevelField
+	case "msg", "message":
+		f = ttyMessageField
+	case "attr", "attrs":
+		f = ttyAttrsField
+	case "tag", "tags":
+		f = ttyTagsField
+	case "src", "source":
+		f = ttySourceField
+	case "host":
+		f = ttyHostField
+	case "pid":
+		f = ttyPidField
+	default:
+		return f, false
 	}
-	return cfg
+	return f, true
 }
 
-// ReplaceAttr configures the use of the given function to replace Attrs when logging.
+// ReplaceFunc configures the use of the given function to replace Attrs when logging.
 // See [slog.HandlerOptions].
+// The scope given to the function lists the groups enclosing an Attr, including those opened with [Logger.WithGroup].
+//
+// As with slog handlers, a [TTY] also gives the function the built-in time, level, message, and source fields,
+// keyed [slog.TimeKey], [slog.LevelKey], [slog.MessageKey], and [slog.SourceKey].
+// Returning an Attr with an empty key omits the field. A returned value of the field's kind
+// is given to the field's [Encoder]; other values are written as they would be interpolated.
 func (cfg *Config) ReplaceFunc(replace func(scope []string, a Attr) Attr) *Config {
 	cfg.replace = replace
 	return cfg
@@ -298,6 +783,23 @@ func (cfg *Config) ForceTTY(toggle bool) *Config {
 	return cfg
 }
 
+// RevealSecrets configures any [TTY] produced by the configuration to display the values held by [Secret]s,
+// rather than "[REDACTED]". Intended for local development, it applies only to [TTY] output:
+// auxilliary handlers, and messages interpolated before reaching the [TTY], remain redacted.
+func (cfg *Config) RevealSecrets(toggle bool) *Config {
+	cfg.reveal = toggle
+	return cfg
+}
+
+// SanitizeControl configures any [TTY] produced by the configuration to escape control characters
+// in message text and attribute values, as with [strconv.Quote] (e.g., "\x1b" is written as `\x1b`).
+// Escape sequences carried by logged values then can't recolor, move the cursor of, or retitle a terminal.
+// Tabs are kept. Auxilliary handlers escape their output independently.
+func (cfg *Config) SanitizeControl(toggle bool) *Config {
+	cfg.sanitize = toggle
+	return cfg
+}
+
 // Aux configures an auxilliary handler for a [TTY].
 // The auxilliary handler is employed:
 //   - If, the [TTY]'s writer is not a tty devices, and [Config.ForceTTY] is configured false
@@ -310,6 +812,92 @@ func (cfg *Config) Aux(aux slog.Handler) *Config {
 	return cfg
 }
 
+// Keys renames the built-in time, level, message, and source fields of output from [Config.JSON] and [Config.Text] loggers,
+// and from the preset auxilliary handler of a [TTY]. An empty key omits the field.
+// [TTY] output is unaffected.
+//
+// A function given to [Config.ReplaceFunc] still sees the fields keyed [slog.TimeKey], [slog.LevelKey], [slog.MessageKey], and [slog.SourceKey].
+func (cfg *Config) Keys(timeKey, levelKey, msgKey, sourceKey string) *Config {
+	cfg.keys = &builtinKeys{
+		time:   timeKey,
+		level:  levelKey,
+		msg:    msgKey,
+		source: sourceKey,
+	}
+	return cfg
+}
+
+// builtinKeys renames built-in fields, as configured by [Config.Keys]
+type builtinKeys struct {
+	time, level, msg, source string
+}
+
+// renames a built-in field. Other attrs are returned unchanged.
+func (keys *builtinKeys) rename(a Attr) Attr {
+	switch a.Key {
+	case slog.TimeKey:
+		if a.Value.Kind() == slog.KindTime {
+			a.Key = keys.time
+		}
+	case slog.LevelKey:
+		if _, ok := a.Value.Any().(slog.Level); ok {
+			a.Key = keys.level
+		}
+	case slog.MessageKey:
+		if a.Value.Kind() == slog.KindString {
+			a.Key = keys.msg
+		}
+	case slog.SourceKey:
+		if _, ok := a.Value.Any().(*slog.Source); ok {
+			a.Key = keys.source
+		}
+	}
+
+	if a.Key == "" {
+		return Attr{}
+	}
+	return a
+}
+
+// returns the replace function given to slog handlers, naming levels and renaming built-in fields after [Config.ReplaceFunc] is applied
+func (cfg *Config) handlerReplace() replaceFunc {
+	replace, keys := cfg.replace, cfg.keys
+	return func(scope []string, a Attr) Attr {
+		if replace != nil {
+			a = replace(scope, a)
+		}
+		if len(scope) == 0 {
+			if keys != nil {
+				a = keys.rename(a)
+			}
+			// levels are named as a TTY names them
+			if a.Value.Kind() == slog.KindAny {
+				if level, ok := a.Value.Any().(slog.Level); ok {
+					a.Value = slog.StringValue(levelName(level))
+				}
+			}
+		}
+		return a
+	}
+}
+
+// NativeJSON configures [Config.JSON] to use a JSON encoder native to logf, rather than a [slog.JSONHandler].
+// The output is in the same format, and attrs given to [Logger.With] are encoded just once.
+func (cfg *Config) NativeJSON(toggle bool) *Config {
+	cfg.nativeJSON = toggle
+	return cfg
+}
+
+// DedupKeys configures whether repeated keys are dropped from the attrs of encoded records,
+// in the attrs field of a [TTY] and in the output of the native JSON handler (see [Config.NativeJSON]).
+// Of attrs with the same key, given to [Logger.With] or at the call site, only the last is encoded;
+// the members of a group are deduplicated within the group.
+// Interpolation is unaffected.
+func (cfg *Config) DedupKeys(toggle bool) *Config {
+	cfg.dedupKeys = toggle
+	return cfg
+}
+
 // ForceAux configures any [TTY] produced by the configuraton to always employ an
 // auxilliary handler.
 func (cfg *Config) ForceAux(toggle bool) *Config {
@@ -322,18 +910,34 @@ func (cfg *Config) ForceAux(toggle bool) *Config {
 // TTY returns a new TTY.
 // If the configured Writer is the same as [StdTTY] (default: [os.Stdout]), the new TTY shares a mutex with [StdTTY].
 func (cfg *Config) TTY() *TTY {
+	return cfg.tty(nil)
+}
+
+// tty returns a new TTY, with the given layout replacing the configured one if non-nil
+func (cfg *Config) tty(layout []ttyField) *TTY {
 	// WRITER
 	// w, enableTTY := newTTYSyncWriter(cfg.w, cfg.mu)
 	// enableTTY = enableTTY || cfg.enableTTY
 
 	// FORMATTER
 	fmtr := cfg.fmtr.clone(cfg.addSource, cfg.addColors)
+	if layout != nil {
+		fmtr.layout = cloneLayout(layout, cfg.addSource)
+	}
 
-	// FILTER
-	filter := &ttyFilter{
-		tag: make(map[string]struct{}),
+	// the encoder configured for "#" encodes tags with another key
+	if cfg.tagKey != "#" {
+		tag := maps.Clone(fmtr.tag)
+		if _, found := tag[cfg.tagKey]; !found && cfg.tagKey != "" {
+			tag[cfg.tagKey] = tag["#"]
+		}
+		delete(tag, "#")
+		fmtr.tag = tag
 	}
 
+	// FILTER
+	filter := new(ttyFilter)
+
 	// DEVICE
 	dev := &ttyDevice{
 		fmtr:   fmtr,
@@ -341,12 +945,40 @@ func (cfg *Config) TTY() *TTY {
 		filter: filter,
 
 		ref:     cfg.ref,
+		scopes:  cfg.scopes,
 		replace: cfg.replace,
+		skip:    cfg.skip,
+
+		addStack:   cfg.addStack,
+		stackLevel: cfg.stackLevel,
+
+		ctxAttrs: cfg.ctxAttrs,
+		observe:  cfg.observe,
+		onRecord: cfg.onRecord,
+		repanic:  cfg.repanic,
+		consume:  cfg.consume,
+		tagJoin:  cfg.tagJoin,
+		tagKey:   cfg.tagKey,
+
+		reveal:   cfg.reveal,
+		sanitize: cfg.sanitize,
+		links:    cfg.addColors && cfg.enableTTY,
+		tree:     cfg.fmtr.groupTree && cfg.enableTTY,
+		dedup:    cfg.dedupKeys,
+		timePrec: cfg.timePrec,
+		last:     new(ttyLast),
+
+		filterAux: cfg.filterAux,
+	}
+
+	if cfg.auxTagKey != nil {
+		dev.auxRekey, dev.auxTagKey = true, *cfg.auxTagKey
 	}
 
 	// TTY
 	tty := &TTY{
-		dev: dev,
+		dev:   dev,
+		store: Store{replace: cfg.replace},
 	}
 
 	setDefault := cfg.setDefault
@@ -371,16 +1003,24 @@ func (cfg *Config) TTY() *TTY {
 			}
 
 			// build a JSON handler
-			enc := slog.NewJSONHandler(w, &slog.HandlerOptions{
-				Level:       cfg.ref,
+			var enc slog.Handler = slog.NewJSONHandler(w, &slog.HandlerOptions{
+				Level:       cfg.auxLevel(),
 				AddSource:   cfg.fmtr.addSource,
-				ReplaceAttr: cfg.replace,
+				ReplaceAttr: cfg.handlerReplace(),
 			})
 
+			if as := fmtr.instanceAttrs(); len(as) > 0 {
+				enc = enc.WithAttrs(as)
+			}
+
 			h := &Handler{
 				enc:       enc,
 				addSource: cfg.fmtr.addSource,
 				replace:   cfg.replace,
+				scopes:    cfg.scopes,
+				store:     Store{replace: cfg.replace},
+				tagKey:    cfg.tagKey,
+				// (tags reaching aux are already joined by the TTY)
 			}
 
 			tty.aux = h
@@ -399,33 +1039,68 @@ func (cfg *Config) TTY() *TTY {
 // Otherwise, the returned [*Logger] a JSONHandler]-based
 func (cfg *Config) Logger() Logger {
 	tty := cfg.TTY()
-	return newLogger(tty)
+	return newLogger(tty).Depth(cfg.skip)
 }
 
-// Printer returns a [TTY]-based Logger that only emits tags and messages.
+// Printer returns a [TTY]-based Logger that only emits tags and messages, or the fields given to [Config.PrinterLayout].
 // If the configured Writer is a terminal, the returned [Logger] is [TTY]-based
 // Otherwise, the returned [Logger] a JSONHandler]-based
+//
+// The printer layout replaces the layout given by [Config.ShowLayout] only for the returned Logger;
+// the Config is unchanged.
 func (cfg *Config) Printer() Logger {
-	tty := cfg.
-		ShowLayout("tags", "message").
-		TTY()
-	return newLogger(tty)
+	tty := cfg.tty(cfg.printer)
+	return newLogger(tty).Depth(cfg.skip)
 }
 
-// JSON returns a Logger using a [slog.JSONHandler] for encoding.
+// PrinterLayout configures the layout of a Logger returned by [Config.Printer], with fields as in [Config.ShowLayout].
+// By default, the layout is "tags", "message".
+func (cfg *Config) PrinterLayout(fields ...string) *Config {
+	cfg.printer = parseLayout([]ttyField{}, fields)
+	return cfg
+}
+
+// JSON returns a Logger using a [slog.JSONHandler] for encoding, or a native encoder if [Config.NativeJSON] is set.
 //
 // Only [Config.Writer], [Config.Level], [Config.AddSource], and [Config.ReplaceFunc] configuration is applied.
+// The native encoder also applies [Config.RevealSecrets] and [Config.DedupKeys].
 func (cfg *Config) JSON() Logger {
-	enc := slog.NewJSONHandler(cfg.w.Writer, &slog.HandlerOptions{
-		Level:       cfg.ref,
-		AddSource:   cfg.fmtr.addSource,
-		ReplaceAttr: cfg.replace,
-	})
+	var enc slog.Handler
+	if cfg.nativeJSON {
+		jh := newJSONHandler(cfg.w, cfg.ref, cfg.addSource, cfg.replace, cfg.reveal)
+		jh.keys = cfg.keys
+		jh.timePrec = cfg.timePrec
+		if cfg.timePrecOk {
+			jh.attrPrec = cfg.timePrec
+		}
+		jh.dedup = cfg.dedupKeys
+		enc = jh
+	} else {
+		enc = slog.NewJSONHandler(cfg.w.Writer, &slog.HandlerOptions{
+			Level:       cfg.ref,
+			AddSource:   cfg.addSource,
+			ReplaceAttr: cfg.handlerReplace(),
+		})
+	}
 
 	h := &Handler{
 		enc:       enc,
-		addSource: cfg.fmtr.addSource,
+		addSource: cfg.addSource,
 		replace:   cfg.replace,
+		scopes:    cfg.scopes,
+		store:     Store{replace: cfg.replace},
+
+		addStack:   cfg.addStack,
+		stackLevel: cfg.stackLevel,
+
+		ctxAttrs: cfg.ctxAttrs,
+		observe:  cfg.observe,
+		onRecord: cfg.onRecord,
+		repanic:  cfg.repanic,
+		consume:  cfg.consume,
+		timePrec: cfg.timePrec,
+		tagJoin:  cfg.tagJoin,
+		tagKey:   cfg.tagKey,
 	}
 
 	if cfg.setDefault {
@@ -433,7 +1108,7 @@ func (cfg *Config) JSON() Logger {
 		cfg.setDefault = false
 	}
 
-	return newLogger(h)
+	return newLogger(h).Depth(cfg.skip)
 }
 
 // Text returns a Logger using a [slog.TextHandler] for encoding.
@@ -442,14 +1117,28 @@ func (cfg *Config) JSON() Logger {
 func (cfg *Config) Text() Logger {
 	enc := slog.NewTextHandler(cfg.w.Writer, &slog.HandlerOptions{
 		Level:       cfg.ref,
-		AddSource:   cfg.fmtr.addSource,
-		ReplaceAttr: cfg.replace,
+		AddSource:   cfg.addSource,
+		ReplaceAttr: cfg.handlerReplace(),
 	})
 
 	h := &Handler{
 		enc:       enc,
-		addSource: cfg.fmtr.addSource,
+		addSource: cfg.addSource,
 		replace:   cfg.replace,
+		scopes:    cfg.scopes,
+		store:     Store{replace: cfg.replace},
+
+		addStack:   cfg.addStack,
+		stackLevel: cfg.stackLevel,
+
+		ctxAttrs: cfg.ctxAttrs,
+		observe:  cfg.observe,
+		onRecord: cfg.onRecord,
+		repanic:  cfg.repanic,
+		consume:  cfg.consume,
+		timePrec: cfg.timePrec,
+		tagJoin:  cfg.tagJoin,
+		tagKey:   cfg.tagKey,
 	}
 
 	if cfg.setDefault {
@@ -457,5 +1146,5 @@ func (cfg *Config) Text() Logger {
 		cfg.setDefault = false
 	}
 
-	return newLogger(h)
+	return newLogger(h).Depth(cfg.skip)
 }
@@ -0,0 +1,101 @@
+package logf
+
+import (
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// RecordCounters counts records by level and by tag, as observed by [Config.Observer].
+// Records dropped by filtering are counted apart.
+//
+// A RecordCounters is an [expvar.Var], and may be published with [RecordCounters.Publish]:
+//
+//	c := logf.Counters().Publish("logf")
+//	log := logf.New().Observer(c.Observe).Logger()
+type RecordCounters struct {
+	levels  sync.Map // slog.Level -> *atomic.Int64
+	tags    sync.Map // string -> *atomic.Int64
+	dropped atomic.Int64
+}
+
+// Counters returns a new, zeroed [RecordCounters].
+func Counters() *RecordCounters {
+	return new(RecordCounters)
+}
+
+// Publish publishes the counters with [expvar.Publish] under the given name, and returns them.
+// As with expvar.Publish, publishing a name twice panics.
+func (c *RecordCounters) Publish(name string) *RecordCounters {
+	expvar.Publish(name, c)
+	return c
+}
+
+// Observe counts a record. It has the signature expected by [Config.Observer].
+func (c *RecordCounters) Observe(level slog.Level, tag string, dropped bool) {
+	if dropped {
+		c.dropped.Add(1)
+		return
+	}
+	counter(&c.levels, level).Add(1)
+	if tag != "" {
+		counter(&c.tags, tag).Add(1)
+	}
+}
+
+// Level returns the count of records written at the given level.
+func (c *RecordCounters) Level(level slog.Level) int64 {
+	return load(&c.levels, level)
+}
+
+// Tag returns the count of records written with the given tag.
+func (c *RecordCounters) Tag(tag string) int64 {
+	return load(&c.tags, tag)
+}
+
+// Dropped returns the count of records dropped by filtering.
+func (c *RecordCounters) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// String returns the counts as a JSON object, satisfying [expvar.Var].
+func (c *RecordCounters) String() string {
+	v := struct {
+		Levels  map[string]int64 `json:"levels"`
+		Tags    map[string]int64 `json:"tags"`
+		Dropped int64            `json:"dropped"`
+	}{
+		Levels:  make(map[string]int64),
+		Tags:    make(map[string]int64),
+		Dropped: c.dropped.Load(),
+	}
+
+	c.levels.Range(func(k, n any) bool {
+		v.Levels[k.(slog.Level).String()] = n.(*atomic.Int64).Load()
+		return true
+	})
+	c.tags.Range(func(k, n any) bool {
+		v.Tags[k.(string)] = n.(*atomic.Int64).Load()
+		return true
+	})
+
+	text, _ := json.Marshal(v)
+	return string(text)
+}
+
+func counter(m *sync.Map, key any) *atomic.Int64 {
+	if n, ok := m.Load(key); ok {
+		return n.(*atomic.Int64)
+	}
+	n, _ := m.LoadOrStore(key, new(atomic.Int64))
+	return n.(*atomic.Int64)
+}
+
+func load(m *sync.Map, key any) int64 {
+	if n, ok := m.Load(key); ok {
+		return n.(*atomic.Int64).Load()
+	}
+	return 0
+}
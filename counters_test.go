@@ -0,0 +1,68 @@
+package logf
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCounters(t *testing.T) {
+	c := Counters()
+
+	tty := New().
+		Writer(io.Discard).
+		Observer(c.Observe).
+		ForceTTY(true).
+		TagJoin(".").
+		TTY()
+	tty.Filter("db")
+
+	log := tty.Logger()
+	log.With("#", "db").Info("ok")
+	log.With("#", "db").Warn("ok", "#", "query")
+	log.With("#", "http").Info("dropped")
+	log.Debug("disabled")
+
+	jlog := New().
+		Writer(io.Discard).
+		Observer(c.Observe).
+		JSON()
+	jlog.Tags("http").Error("ok", nil)
+	jlog.Info("ok")
+
+	for _, tc := range []struct {
+		name      string
+		got, want int64
+	}{
+		{"INFO", c.Level(INFO), 2},
+		{"WARN", c.Level(WARN), 1},
+		{"ERROR", c.Level(ERROR), 1},
+		{"DEBUG", c.Level(DEBUG), 0},
+		{"db", c.Tag("db"), 1},
+		{"db.query", c.Tag("db.query"), 1},
+		{"http", c.Tag("http"), 1},
+		{"dropped", c.Dropped(), 1},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: want %d, got %d", tc.name, tc.want, tc.got)
+		}
+	}
+
+	var _ expvar.Var = c
+	var v map[string]any
+	if err := json.Unmarshal([]byte(c.String()), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["dropped"] != 1.0 {
+		t.Errorf("dropped: %v", v["dropped"])
+	}
+
+	// (names are unique across repeated runs, as publishing a name twice panics)
+	name := fmt.Sprintf("logf_counters_%d", time.Now().UnixNano())
+	if c.Publish(name) != c || expvar.Get(name) != c {
+		t.Errorf("publish: %s not found", name)
+	}
+}
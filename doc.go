@@ -25,6 +25,15 @@ Generating output similar to the earlier Hello, world progam:
 	log = log.With("place", "Roswell")
 	log.Infof("Hello, {place}")
 
+Positional tokens refer to arguments by index, and fallbacks are written when a key is missing:
+
+	log.Infof("{0} -> {1} ({0} again)", "from", "a", "to", "b")
+	log.Infof("Hello, {name|stranger}")
+
+The token {*} interpolates every attribute, in key=value form:
+
+	err := log.WrapErr("request failed {*}", err)
+
 Reporting a UFO sighting:
 
 	ufo := errors.New("🛸 spotted")
@@ -35,6 +44,11 @@ Generating a wrapped error:
 	ufo := errors.New("🛸 spotted")
 	err := log.WrapErr("{place}", errors.New("🛸 spotted"))
 
+An error from [WrapErr] given arguments also carries them; logging it later with a logf handler re-exports them as structure:
+
+	err := logf.WrapErr("{place}", ufo, "place", "Roswell")
+	log.Error("", err) // err:{msg:Roswell: 🛸 spotted place:Roswell}
+
 # TTY
 
 The [TTY] component is a [Handler] designed for logging to human eyes.
@@ -70,7 +84,9 @@ Construct a [Logger], given a [slog.Handler]:
 
 	log := logf.UsingHandler(h)
 
-The resulting logger may be unable interpolate over any attrbiutes set on a non-logf-Handler.
+Attributes already set on a non-logf handler are recovered for interpolation if the handler offers them,
+via a `LogValue() slog.Value` method or an `Attrs() []slog.Attr` method.
+Otherwise, the resulting logger is unable to interpolate over them, and such keys interpolate as missing.
 In general, effort is made via type assertions to recover logf types, but recovery isn't always possible.
 
 # testlog
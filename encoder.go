@@ -1,37 +1,104 @@
 package logf
 
 import (
+	"bytes"
+	"cmp"
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"log/slog"
 	"maps"
+	"slices"
 )
 
 // ttyFormatter manages state relevant to encoding a record to bytes
 type ttyFormatter struct {
-	layout []ttyField
-	tag    map[string]ttyEncoder[Attr]
+	layout       []ttyField
+	levelLayouts []levelLayout
+	fieldMin     map[ttyField]slog.Level // minimum levels at which fields are shown
+	tag          map[string]ttyEncoder[Attr]
 
 	time       ttyEncoder[time.Time]
 	level      ttyEncoder[slog.Level]
 	message    ttyEncoder[string]
 	key        ttyEncoder[string]
 	value      ttyEncoder[Value]
+	attrs      ttyEncoder[[]Attr] // if set, encodes the attrs field in place of key and value encoders
 	source     ttyEncoder[*slog.Source]
 	groupOpen  Encoder[int]
 	groupClose Encoder[int]
 
 	groupPen pen
+	stackPen pen
+	tracePen pen
 	debugPen pen
 	infoPen  pen
 	warnPen  pen
 	errorPen pen
 
+	quote     quoteMode
+	groupDots bool
+	groupTree bool
+
+	// replaces preformatted attrs unchanged since the previous line with a placeholder
+	attrsDiff bool
+	diffPen   pen
+
+	// renders structs in KindAny values with field names
+	structFields bool
+
+	// bytes of a byte slice rendered in hex, or all of them if not positive
+	hexMax int
+
+	// sorts the keys of maps rendered as groups
+	mapSort bool
+
+	// float format and precision, and whether whole numbers are trimmed
+	floatFmt  byte
+	floatPrec int
+	floatTrim bool
+
+	// shows the keys of tag-keyed attrs
+	tagKeys   bool
+	tagKeyPen pen
+
+	// ranks of tag keys, pinning the order of tags; replaced as a whole by Config.ShowTagOrder
+	tagOrder map[string]int
+
+	// per-key colors, by key or dotted key
+	keyColors map[string]keyPens
+
+	// keys shown abbreviated, by key or dotted key, and group names shown abbreviated
+	keyAbbrev   map[string]string
+	scopeAbbrev map[string]string
+
+	// highlights interpolated values in messages
+	ipolPen pen
+
+	// renders the chain of errors wrapped by a record's error, to a depth
+	errChain      bool
+	errChainDepth int
+
+	// process instance, computed at config time
+	host    string
+	pid     int
+	hostPen pen
+
 	addSource bool
 }
 
 func newTTYFormatter() *ttyFormatter {
+	host, _ := os.Hostname()
+
 	return &ttyFormatter{
+		// instance
+		host: host,
+		pid:  os.Getpid(),
+
 		// layout
 		layout: []ttyField{
 			ttyLevelField,
@@ -70,14 +137,28 @@ func newTTYFormatter() *ttyFormatter {
 		groupOpen:  EncodeFunc(encGroupOpen),
 		groupClose: EncodeFunc(encGroupClose),
 
+		structFields: true,
+		hexMax:       32,
+		mapSort:      true,
+		floatFmt:     'g',
+		floatPrec:    -1,
+
+		// error chains
+		errChainDepth: 8,
+
 		// level colors
 		groupPen: "\x1b[2m",
+		hostPen:  "\x1b[2m",
+		diffPen:  "\x1b[2m",
+		stackPen: "\x1b[2m",
+		tracePen: "\x1b[34;2m",
 		debugPen: "\x1b[2m",
 		infoPen:  "\x1b[32;1m",
 		warnPen:  "\x1b[33;1m",
 		errorPen: "\x1b[31;1m",
 
 		// tags
+		tagKeyPen: "\x1b[2m",
 		tag: map[string]ttyEncoder[Attr]{
 			"#": {
 				"\x1b[35;1m",
@@ -90,25 +171,28 @@ func newTTYFormatter() *ttyFormatter {
 func (fmtr *ttyFormatter) clone(addSource, addColors bool) *ttyFormatter {
 	fmtr2 := *fmtr
 
-	// source
-	var sourceInLayout bool
-	if addSource {
-		fmtr2.addSource = true
-		for _, f := range fmtr.layout {
-			if f == ttySourceField {
-				sourceInLayout = true
-				break
-			}
-		}
-	}
+	// layouts & source
+	fmtr2.addSource = addSource
+	fmtr2.layout = cloneLayout(fmtr.layout, addSource)
 
-	if addSource && !sourceInLayout {
-		fmtr2.layout = append(fmtr2.layout, ttyNewlineField, ttySourceField)
+	fmtr2.levelLayouts = make([]levelLayout, len(fmtr.levelLayouts))
+	for i, ll := range fmtr.levelLayouts {
+		fmtr2.levelLayouts[i] = levelLayout{ll.level, cloneLayout(ll.layout, addSource)}
 	}
 
 	// tags
 	fmtr2.tag = maps.Clone(fmtr.tag)
 
+	// field levels
+	fmtr2.fieldMin = maps.Clone(fmtr.fieldMin)
+
+	// per-key colors
+	fmtr2.keyColors = maps.Clone(fmtr.keyColors)
+
+	// abbreviations
+	fmtr2.keyAbbrev = maps.Clone(fmtr.keyAbbrev)
+	fmtr2.scopeAbbrev = maps.Clone(fmtr.scopeAbbrev)
+
 	// colors
 	if !addColors {
 		fmtr2.time.color = ""
@@ -116,9 +200,17 @@ func (fmtr *ttyFormatter) clone(addSource, addColors bool) *ttyFormatter {
 		fmtr2.message.color = ""
 		fmtr2.key.color = ""
 		fmtr2.value.color = ""
+		fmtr2.attrs.color = ""
 		fmtr2.source.color = ""
 
 		fmtr2.groupPen = ""
+		fmtr2.hostPen = ""
+		fmtr2.diffPen = ""
+		fmtr2.tagKeyPen = ""
+		fmtr2.keyColors = nil
+		fmtr2.ipolPen = ""
+		fmtr2.stackPen = ""
+		fmtr2.tracePen = ""
 		fmtr2.debugPen = ""
 		fmtr2.infoPen = ""
 		fmtr2.warnPen = ""
@@ -133,9 +225,68 @@ func (fmtr *ttyFormatter) clone(addSource, addColors bool) *ttyFormatter {
 	return &fmtr2
 }
 
+// levelLayout is a layout used at or above a level
+type levelLayout struct {
+	level  slog.Level
+	layout []ttyField
+}
+
+// copies a layout, appending a source field if needed
+func cloneLayout(layout []ttyField, addSource bool) []ttyField {
+	layout = slices.Clone(layout)
+	if addSource && !slices.Contains(layout, ttySourceField) {
+		layout = append(layout, ttyNewlineField, ttySourceField)
+	}
+	return layout
+}
+
+// reports whether a field appears in any layout
+func (fmtr *ttyFormatter) hasField(f ttyField) bool {
+	if slices.Contains(fmtr.layout, f) {
+		return true
+	}
+	for _, ll := range fmtr.levelLayouts {
+		if slices.Contains(ll.layout, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns "host" and "pid" attrs, for those fields in a layout
+func (fmtr *ttyFormatter) instanceAttrs() (as []Attr) {
+	if fmtr.hasField(ttyHostField) {
+		as = append(as, slog.String("host", fmtr.host))
+	}
+	if fmtr.hasField(ttyPidField) {
+		as = append(as, slog.Int("pid", fmtr.pid))
+	}
+	return
+}
+
+// reports whether a field is shown at the given level
+func (fmtr *ttyFormatter) showsAt(field ttyField, level slog.Level) bool {
+	min, found := fmtr.fieldMin[field]
+	return !found || level >= min
+}
+
+// returns the layout registered at the greatest level not exceeding the given level,
+// or the default layout
+func (fmtr *ttyFormatter) layoutAt(level slog.Level) []ttyField {
+	for i := len(fmtr.levelLayouts) - 1; i >= 0; i-- {
+		if fmtr.levelLayouts[i].level <= level {
+			return fmtr.levelLayouts[i].layout
+		}
+	}
+	return fmtr.layout
+}
+
 // ENCODERS
 
 // Encoder writes values of type T to a [Buffer] containing a [TTY] log line.
+// Beyond writing strings or bytes, an Encoder may append numbers, durations, times, and quoted strings
+// without allocating, with [Buffer.AppendInt], [Buffer.AppendUint], [Buffer.AppendFloat],
+// [Buffer.AppendDuration], [Buffer.AppendTimeRFC3339], and [Buffer.AppendQuote].
 //
 // Flavors of Encoder expected by [TTY] encoding:
 //   - time: Encoder[time.Time]
@@ -144,6 +295,7 @@ func (fmtr *ttyFormatter) clone(addSource, addColors bool) *ttyFormatter {
 //   - tag: Encoder[Attr]
 //   - attr key: Encoder[string]
 //   - attr value: Encoder[Value]
+//   - attrs: Encoder[[]Attr]
 //   - source: Encoder[*slog.Source]
 type Encoder[T any] interface {
 	Encode(*Buffer, T)
@@ -167,6 +319,7 @@ type ttyEncoder[T any] struct {
 
 func (enc ttyEncoder[T]) Encode(b *Buffer, t T) {
 	enc.color.use(b)
+	b.mark = len(b.text)
 	enc.Encoder.Encode(b, t)
 	enc.color.drop(b)
 }
@@ -177,6 +330,81 @@ func (enc ttyEncoder[T]) Encode(b *Buffer, t T) {
 type Buffer struct {
 	*splicer
 	sep byte
+
+	// where the text of the current field begins
+	mark int
+
+	// dotted key prefix of open groups
+	prefix string
+
+	// time of the previous record, given to TimeDelta
+	prevTime time.Time
+}
+
+// Pad pads the text written by the current [Encoder] to the given width, measured in terminal cells.
+// Wide characters (e.g., CJK, or emoji) count as two cells, joined emoji sequences count as one character,
+// and ANSI escape sequences count as none.
+// Align determines where spaces are added:
+//   - '<': text is aligned left, spaces are added to the right
+//   - '>': text is aligned right, spaces are added to the left
+//   - '^': text is centered, with any odd space added to the right
+//
+// Text already at least as wide as the given width is unchanged.
+func (b *Buffer) Pad(width int, align byte) {
+	n := width - cellWidth(b.text[b.mark:])
+	if n <= 0 {
+		return
+	}
+
+	var left int
+	switch align {
+	case '>':
+		left = n
+	case '^':
+		left = n / 2
+	}
+
+	b.text = slices.Insert(b.text, b.mark, spaces(left)...)
+	b.text = append(b.text, spaces(n-left)...)
+}
+
+// returns n spaces
+func spaces(n int) []byte {
+	const s = "                                "
+	if n <= len(s) {
+		return []byte(s[:n])
+	}
+	return bytes.Repeat([]byte{' '}, n)
+}
+
+// AppendInt appends the decimal form of i.
+func (b *Buffer) AppendInt(i int64) {
+	b.text = strconv.AppendInt(b.text, i, 10)
+}
+
+// AppendUint appends the decimal form of u.
+func (b *Buffer) AppendUint(u uint64) {
+	b.text = strconv.AppendUint(b.text, u, 10)
+}
+
+// AppendFloat appends f as the [TTY] writes float values, in the format and precision given by [Config.FloatFormat].
+func (b *Buffer) AppendFloat(f float64) {
+	b.writeFloat(f)
+}
+
+// AppendDuration appends d as the [TTY] writes duration values, e.g. "1.5s".
+func (b *Buffer) AppendDuration(d time.Duration) {
+	b.text = appendDuration(b.text, d)
+}
+
+// AppendTimeRFC3339 appends t in RFC 3339 format, with the fractional-second digits given by [Config.TimePrecision].
+func (b *Buffer) AppendTimeRFC3339(t time.Time) {
+	b.text = appendTimeRFC3339(b.text, t, b.timePrec)
+}
+
+// AppendQuote appends s as a double-quoted Go string literal, as with [strconv.Quote].
+func (b *Buffer) AppendQuote(s string) {
+	b.text = strconv.AppendQuote(b.text, s)
 }
 
 func (b *Buffer) writeSep() {
@@ -185,7 +413,7 @@ func (b *Buffer) writeSep() {
 	case ' ':
 		b.WriteByte(' ')
 	case '\n':
-		b.WriteByte('\n')
+		b.WriteString("\n\t")
 	case '\t':
 		b.WriteByte('\t')
 	case '?':
@@ -206,46 +434,108 @@ const (
 	ttyAttrsField
 	ttyTagsField
 	ttySourceField
+	ttyHostField
+	ttyPidField
 
 	ttyNewlineField
 	ttySpaceField
 	ttyTabField
 )
 
-func (tty *TTY) encFields(
-	s *splicer,
-	level slog.Level,
-	msg string,
-	err error,
-	src *slog.Source,
-) {
-	b := &Buffer{s, 0}
-	for _, field := range tty.dev.fmtr.layout {
+// ttyRecord holds the built-in fields of a record, as the attrs a [Config.ReplaceFunc] is given.
+// An attr with an empty key is not encoded.
+type ttyRecord struct {
+	level slog.Level
+
+	time, lvl, msg, source Attr
+
+	// the spans of values interpolated into the message
+	spans []int
+	err   error
+
+	// whether attrs are written as a tree, following the line
+	tree bool
+}
+
+// returns the built-in fields of a record, replaced as configured.
+// A zero record time is replaced by the current time.
+func (tty *TTY) newTTYRecord(r slog.Record, spans []int, err error) ttyRecord {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	rec := ttyRecord{
+		level: r.Level,
+		time:  slog.Time(slog.TimeKey, t),
+		lvl:   slog.Any(slog.LevelKey, r.Level),
+		msg:   slog.String(slog.MessageKey, r.Message),
+		spans: spans,
+		err:   err,
+	}
+
+	if tty.dev.fmtr.addSource && tty.dev.fmtr.showsAt(ttySourceField, r.Level) {
+		rec.source = slog.Any(slog.SourceKey, source(r))
+	}
+
+	if replace := tty.dev.replace; replace != nil {
+		rec.time = replace(nil, rec.time)
+		rec.lvl = replace(nil, rec.lvl)
+		rec.msg = replace(nil, rec.msg)
+		if rec.source.Key != "" {
+			rec.source = replace(nil, rec.source)
+		}
+
+		// a rewritten message isn't highlighted
+		if rec.msg.Key == "" || rec.msg.Value.Kind() != slog.KindString || rec.msg.Value.String() != r.Message {
+			rec.spans = nil
+		}
+	}
+
+	return rec
+}
+
+func (tty *TTY) encFields(s *splicer, rec *ttyRecord) {
+	b := &Buffer{splicer: s}
+	for _, field := range tty.dev.fmtr.layoutAt(rec.level) {
+		if !tty.dev.fmtr.showsAt(field, rec.level) {
+			continue
+		}
+
 		switch field {
 		case ttyTimeField:
-			tty.encTime(b)
+			tty.encTime(b, rec.time)
 		case ttyLevelField:
-			tty.encLevel(b, level)
+			tty.encLevel(b, rec.level, rec.lvl)
 		case ttyMessageField:
-			tty.encMsg(b, msg, err)
+			var msg string
+			if rec.msg.Key != "" {
+				msg = rec.msg.Value.Resolve().String()
+			}
+			tty.encMsg(b, msg, rec.spans, rec.err)
 		case ttyAttrsField:
-			tty.encExportAttrs(b)
+			if tty.dev.tree && tty.dev.fmtr.attrs.Encoder == nil {
+				rec.tree = true
+			} else {
+				tty.encExportAttrs(b, rec.level)
+			}
 		case ttyTagsField:
 			tty.encExportTags(b)
 		case ttySourceField:
-			tty.encSource(b, src)
+			tty.encSource(b, rec.source)
+		case ttyHostField:
+			tty.encHost(b)
+		case ttyPidField:
+			tty.encPid(b)
 		case ttyNewlineField:
+			// (written with the next field, as with other spacing)
 			b.sep = '\n'
-			b.writeSep()
-			b.sep = '\t'
-			b.writeSep()
-			b.sep = 0
 		case ttySpaceField:
-			if b.sep != 0 {
+			if b.sep != 0 && b.sep != '\n' {
 				b.sep = ' '
 			}
 		case ttyTabField:
-			if b.sep != 0 {
+			if b.sep != 0 && b.sep != '\n' {
 				b.sep = '\t'
 			}
 		}
@@ -255,22 +545,89 @@ func (tty *TTY) encFields(
 	s.WriteByte('\n')
 }
 
-func (tty *TTY) encTime(b *Buffer) {
+// writes stack frames, indented, on lines following a log line
+func (tty *TTY) encStack(s *splicer, stack []runtime.Frame) {
+	b := &Buffer{splicer: s}
+	for _, f := range stack {
+		b.WriteByte('\t')
+		tty.dev.fmtr.stackPen.use(b)
+		b.WriteString(f.Function)
+		tty.dev.fmtr.stackPen.drop(b)
+		b.WriteString("\n\t\t")
+		tty.dev.fmtr.stackPen.use(b)
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		tty.dev.fmtr.stackPen.drop(b)
+		b.WriteByte('\n')
+	}
+	b.splicer = nil
+}
+
+func (tty *TTY) encHost(b *Buffer) {
 	b.writeSep()
-	tty.dev.fmtr.time.Encode(b, time.Now())
+	tty.dev.fmtr.hostPen.use(b)
+	b.WriteString(tty.dev.fmtr.host)
+	tty.dev.fmtr.hostPen.drop(b)
 	b.sep = ' '
 }
 
-func (tty *TTY) encLevel(b *Buffer, level slog.Level) {
+func (tty *TTY) encPid(b *Buffer) {
 	b.writeSep()
-	p := tty.levelPen(level)
+	tty.dev.fmtr.hostPen.use(b)
+	b.text = strconv.AppendInt(b.text, int64(tty.dev.fmtr.pid), 10)
+	tty.dev.fmtr.hostPen.drop(b)
+	b.sep = ' '
+}
+
+// writes a built-in field replaced by a value its encoder doesn't take
+func (tty *TTY) encReplaced(b *Buffer, p pen, v Value) {
 	p.use(b)
-	tty.dev.fmtr.level.Encoder.Encode(b, level)
+	b.mark = len(b.text)
+	b.WriteValue(v, nil)
 	p.drop(b)
-	b.sep = 0
 }
 
-func (tty *TTY) encMsg(b *Buffer, msg string, err error) {
+func (tty *TTY) encTime(b *Buffer, a Attr) {
+	if a.Key == "" {
+		return
+	}
+
+	b.writeSep()
+	if v := a.Value.Resolve(); v.Kind() == slog.KindTime {
+		if _, isDelta := tty.dev.fmtr.time.Encoder.(timeDelta); isDelta {
+			b.prevTime = tty.dev.swapTime(v.Time())
+		}
+		tty.dev.fmtr.time.Encode(b, v.Time())
+	} else {
+		tty.encReplaced(b, tty.dev.fmtr.time.color, v)
+	}
+	b.sep = ' '
+}
+
+func (tty *TTY) encLevel(b *Buffer, level slog.Level, a Attr) {
+	if a.Key == "" {
+		return
+	}
+
+	b.writeSep()
+	v := a.Value.Resolve()
+	if l, ok := v.Any().(slog.Level); ok {
+		p := tty.levelPen(l)
+		p.use(b)
+		b.mark = len(b.text)
+		tty.dev.fmtr.level.Encoder.Encode(b, l)
+		p.drop(b)
+		b.sep = 0
+		return
+	}
+
+	tty.encReplaced(b, tty.levelPen(level), v)
+	b.sep = ' '
+}
+
+// writes the message, highlighting the spans of interpolated values
+func (tty *TTY) encMsg(b *Buffer, msg string, spans []int, err error) {
 	if len(msg) == 0 && err == nil {
 		return
 	}
@@ -278,52 +635,349 @@ func (tty *TTY) encMsg(b *Buffer, msg string, err error) {
 	b.writeSep()
 
 	tty.dev.fmtr.message.color.use(b)
-	b.splicer.WriteString(msg)
+	if len(spans) > 0 && len(tty.dev.fmtr.ipolPen) > 0 {
+		var last int
+		for i := 0; i+1 < len(spans); i += 2 {
+			b.writeSanitized(msg[last:spans[i]])
+			tty.dev.fmtr.ipolPen.use(b)
+			b.writeSanitized(msg[spans[i]:spans[i+1]])
+			tty.dev.fmtr.ipolPen.drop(b)
+			tty.dev.fmtr.message.color.use(b)
+			last = spans[i+1]
+		}
+		b.writeSanitized(msg[last:])
+	} else {
+		b.writeSanitized(msg)
+	}
 	tty.dev.fmtr.message.color.drop(b)
 
 	// merge error into message
 	if err != nil {
 		if len(msg) > 0 {
-			b.WriteString(": ")
+			b.WriteByte(':')
+			if joinedErrs(err) == nil {
+				b.WriteByte(' ')
+			}
 		}
 
 		tty.dev.fmtr.errorPen.use(b)
-		b.WriteString(err.Error())
+		encErr(b, err, 0)
 		tty.dev.fmtr.errorPen.drop(b)
 	}
 
 	b.sep = ' '
 }
 
+// writes an error's text, with each error joined by [errors.Join] on its own line,
+// indented by depth of nesting
+func encErr(b *Buffer, err error, depth int) {
+	msg := err.Error()
+
+	if errs := joinedErrs(err); errs != nil {
+		for _, err := range errs {
+			// a nested join begins its own lines
+			if joinedErrs(err) == nil {
+				b.WriteByte('\n')
+				for i := 0; i <= depth; i++ {
+					b.WriteByte('\t')
+				}
+			}
+			encErr(b, err, depth+1)
+		}
+		return
+	}
+
+	// a prefix wrapping a joined error
+	if inner := errors.Unwrap(err); inner != nil {
+		if innerMsg := inner.Error(); strings.Contains(innerMsg, "\n") && strings.HasSuffix(msg, innerMsg) {
+			b.writeSanitized(strings.TrimRight(msg[:len(msg)-len(innerMsg)], " "))
+			encErr(b, inner, depth)
+			return
+		}
+	}
+
+	b.writeSanitized(msg)
+}
+
+// errCause is an error wrapped by another, and the errors it wraps in turn,
+// when it is one of several errors wrapped together
+type errCause struct {
+	msg     string
+	causes  []errCause
+	sibling bool
+}
+
+// returns the chain of errors wrapped by err, following [errors.Unwrap] at most depth times.
+// Each of several errors wrapped together (as by [errors.Join]) carries its own chain.
+func errChain(err error, depth int) (chain []errCause) {
+	for ; depth > 0; depth-- {
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				chain = append(chain, errCause{e.Error(), errChain(e, depth-1), true})
+			}
+			return
+		case interface{ Unwrap() error }:
+			if err = u.Unwrap(); err == nil {
+				return
+			}
+			// a join is represented by its siblings
+			if joinedErrs(err) == nil {
+				chain = append(chain, errCause{msg: err.Error()})
+			}
+		default:
+			return
+		}
+	}
+	return
+}
+
+// returns a group of an error chain, keyed by index, with each cause a group of "msg" and any "causes"
+func errChainValue(chain []errCause) Value {
+	as := make([]Attr, 0, len(chain))
+	for i, c := range chain {
+		cause := []Attr{slog.String("msg", c.msg)}
+		if len(c.causes) > 0 {
+			cause = append(cause, slog.Attr{Key: "causes", Value: errChainValue(c.causes)})
+		}
+		as = append(as, slog.Attr{Key: strconv.Itoa(i), Value: slog.GroupValue(cause...)})
+	}
+	return slog.GroupValue(as...)
+}
+
+// writes the chain of errors wrapped by err, each on an indented line following a log line.
+// Joined errors already written on their own lines in the message are not repeated, but their chains are written.
+func (tty *TTY) encErrChain(s *splicer, err error) {
+	b := &Buffer{splicer: s}
+	tty.encErrCauses(b, err.Error(), errChain(err, tty.dev.fmtr.errChainDepth), 1)
+	b.splicer = nil
+}
+
+func (tty *TTY) encErrCauses(b *Buffer, msg string, chain []errCause, indent int) {
+	for _, c := range chain {
+		if c.sibling && (strings.Contains(msg, "\n"+c.msg) || strings.Contains(msg, c.msg+"\n")) {
+			tty.encErrCauses(b, msg, c.causes, indent+1)
+			continue
+		}
+
+		for i := 0; i < indent; i++ {
+			b.WriteByte('\t')
+		}
+		tty.dev.fmtr.stackPen.use(b)
+		b.WriteString("caused by: ")
+		b.writeSanitized(c.msg)
+		tty.dev.fmtr.stackPen.drop(b)
+		b.WriteByte('\n')
+
+		tty.encErrCauses(b, msg, c.causes, indent+1)
+	}
+}
+
+// TREES
+
+// writes attrs as an indented tree, on lines following a log line
+func (tty *TTY) encAttrTree(s *splicer, as []Attr) {
+	b := &Buffer{splicer: s}
+	tty.encTreeBranches(b, as, "")
+	b.splicer = nil
+}
+
+func (tty *TTY) encTreeBranches(b *Buffer, as []Attr, indent string) {
+	as = tty.treeAttrs(b, nil, as)
+	for i, a := range as {
+		branch, stem := "├─ ", "│  "
+		if i == len(as)-1 {
+			branch, stem = "└─ ", "   "
+		}
+
+		b.WriteByte('\t')
+		tty.dev.fmtr.groupPen.use(b)
+		b.WriteString(indent)
+		b.WriteString(branch)
+		tty.dev.fmtr.groupPen.drop(b)
+
+		key, value := tty.attrEncoders(b.prefix, a.Key)
+		if a.Value.Kind() == slog.KindGroup {
+			key.Encode(b, tty.dev.fmtr.abbrevScope(a.Key))
+		} else {
+			key.Encode(b, tty.dev.fmtr.abbrevKey(b.prefix, a.Key))
+		}
+
+		if a.Value.Kind() == slog.KindGroup {
+			b.WriteByte('\n')
+			prefix := b.prefix
+			b.prefix += a.Key + "."
+			tty.encTreeBranches(b, a.Value.Group(), indent+stem)
+			b.prefix = prefix
+			continue
+		}
+
+		value.Encode(b, a.Value)
+		b.WriteByte('\n')
+	}
+}
+
+// appends the attrs of a tree branch to dst: resolved, eliding empty groups, and inlining groups with an empty key
+func (tty *TTY) treeAttrs(b *Buffer, dst, as []Attr) []Attr {
+	for _, a := range as {
+		a.Value = b.resolve(a.Value)
+		switch {
+		case a.Value.Kind() == slog.KindGroup && len(a.Value.Group()) == 0:
+		case a.Value.Kind() == slog.KindGroup && a.Key == "":
+			dst = tty.treeAttrs(b, dst, a.Value.Group())
+		case a.Key != "":
+			dst = append(dst, a)
+		}
+	}
+	return dst
+}
+
+// returns the errors joined by err, if err's text is that of [errors.Join]
+func joinedErrs(err error) []error {
+	multi, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+
+	errs := multi.Unwrap()
+	var n int
+	msg := err.Error()
+	for i, err := range errs {
+		if i > 0 {
+			if n >= len(msg) || msg[n] != '\n' {
+				return nil
+			}
+			n++
+		}
+		errMsg := err.Error()
+		if !strings.HasPrefix(msg[n:], errMsg) {
+			return nil
+		}
+		n += len(errMsg)
+	}
+
+	if n != len(msg) {
+		return nil
+	}
+	return errs
+}
+
 func (tty *TTY) encAttr(b *Buffer, a Attr) {
 	if a.Key == "" {
 		return
 	}
 
 	if a.Value.Kind() == slog.KindLogValuer {
-		if lv, ok := a.Value.Any().(slog.LogValuer); ok {
-			a.Value = lv.LogValue().Resolve()
-		}
+		a.Value = b.resolve(a.Value)
 	}
 
 	if a.Value.Kind() == slog.KindGroup {
-		tty.encAttrGroup(b, a)
+		// as with slog, empty groups are elided
+		if len(a.Value.Group()) > 0 {
+			tty.encAttrGroup(b, a)
+		}
 		return
 	}
 
+	key, value := tty.attrEncoders(b.prefix, a.Key)
+
 	b.writeSep()
-	tty.dev.fmtr.key.Encode(b, a.Key)
-	tty.dev.fmtr.value.Encode(b, a.Value)
+	if tty.dev.fmtr.groupDots {
+		key.Encode(b, tty.dev.fmtr.abbrevDotted(b.prefix, a.Key))
+	} else {
+		key.Encode(b, tty.dev.fmtr.abbrevKey(b.prefix, a.Key))
+	}
+	value.Encode(b, a.Value)
 	b.sep = ' '
 }
 
-func (tty *TTY) encTag(b *Buffer, a Attr) {
+// keyPens are colors set for the key and value of attrs with a particular key.
+// An empty pen defers to the global pen.
+type keyPens struct {
+	key, value pen
+}
+
+// returns key and value encoders for an attr, colored as configured for its key.
+// A color set for the dotted key (including open groups) takes precedence over one set for the key alone.
+func (tty *TTY) attrEncoders(prefix, key string) (ttyEncoder[string], ttyEncoder[Value]) {
+	keyEnc, valueEnc := tty.dev.fmtr.key, tty.dev.fmtr.value
+	if len(tty.dev.fmtr.keyColors) == 0 {
+		return keyEnc, valueEnc
+	}
+
+	pens, found := tty.dev.fmtr.keyColors[prefix+key]
+	if !found && prefix != "" {
+		pens, found = tty.dev.fmtr.keyColors[key]
+	}
+	if !found {
+		return keyEnc, valueEnc
+	}
+
+	if pens.key != "" {
+		keyEnc.color = pens.key
+	}
+	if pens.value != "" {
+		valueEnc.color = pens.value
+	}
+	return keyEnc, valueEnc
+}
+
+// ABBREVIATIONS
+
+// returns the key shown for an attr, abbreviated as configured.
+// An abbreviation of the dotted key (including open groups) takes precedence over one of the key alone.
+func (fmtr *ttyFormatter) abbrevKey(prefix, key string) string {
+	if len(fmtr.keyAbbrev) == 0 {
+		return key
+	}
+	if prefix != "" {
+		if short, found := fmtr.keyAbbrev[prefix+key]; found {
+			return short
+		}
+	}
+	if short, found := fmtr.keyAbbrev[key]; found {
+		return short
+	}
+	return key
+}
+
+// returns the name shown for a group, abbreviated as configured
+func (fmtr *ttyFormatter) abbrevScope(name string) string {
+	if short, found := fmtr.scopeAbbrev[name]; found {
+		return short
+	}
+	return name
+}
+
+// returns the dotted key shown for an attr in the "dots" group style, with group names and the key abbreviated as configured.
+// An abbreviation of the dotted key replaces it entirely.
+func (fmtr *ttyFormatter) abbrevDotted(prefix, key string) string {
+	if len(fmtr.keyAbbrev)+len(fmtr.scopeAbbrev) == 0 {
+		return prefix + key
+	}
+	if short, found := fmtr.keyAbbrev[prefix+key]; found {
+		return short
+	}
+
+	var dotted strings.Builder
+	for prefix != "" {
+		name, rest, _ := strings.Cut(prefix, ".")
+		dotted.WriteString(fmtr.abbrevScope(name))
+		dotted.WriteByte('.')
+		prefix = rest
+	}
+	dotted.WriteString(fmtr.abbrevKey("", key))
+	return dotted.String()
+}
+
+// encodes a tag-keyed attr; prefix holds the dotted keys of enclosing groups
+func (tty *TTY) encTag(b *Buffer, prefix string, a Attr) {
 	if a.Value.Kind() == slog.KindLogValuer {
-		a.Value = a.Value.Resolve()
+		a.Value = b.resolve(a.Value)
 	}
 
 	if a.Value.Kind() == slog.KindGroup {
-		tty.encTagGroup(b, a.Key, a)
+		tty.encTagGroup(b, prefix+a.Key+".", a)
 		return
 	}
 
@@ -334,49 +988,150 @@ func (tty *TTY) encTag(b *Buffer, a Attr) {
 	}
 
 	b.writeSep()
+	if tty.dev.fmtr.tagKeys && a.Key != tty.dev.tagKey {
+		tty.dev.fmtr.tagKeyPen.use(b)
+		b.WriteString(prefix)
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		tty.dev.fmtr.tagKeyPen.drop(b)
+	}
 	tag.Encode(b, a)
 	b.sep = ' '
 }
 
-func (tty *TTY) encSource(b *Buffer, src *slog.Source) {
-	if !tty.dev.fmtr.addSource {
+func (tty *TTY) encSource(b *Buffer, a Attr) {
+	if !tty.dev.fmtr.addSource || a.Key == "" {
 		return
 	}
 
 	b.writeSep()
-	tty.dev.fmtr.source.Encode(b, src)
+	if src, ok := a.Value.Resolve().Any().(*slog.Source); ok {
+		tty.dev.fmtr.source.Encode(b, src)
+	} else {
+		tty.encReplaced(b, tty.dev.fmtr.source.color, a.Value.Resolve())
+	}
 	b.sep = ' '
 }
 
 // LISTS
 
-func (tty *TTY) encExportAttrs(b *Buffer) {
-	if len(tty.attrText)+len(b.splicer.export) == 0 {
+func (tty *TTY) encExportAttrs(b *Buffer, level slog.Level) {
+	if enc := tty.dev.fmtr.attrs; enc.Encoder != nil {
+		as := tty.mergeAttrs(b.splicer.export)
+		if len(as) == 0 {
+			return
+		}
+
+		b.writeSep()
+		enc.Encode(b, as)
+		b.sep = ' '
+		return
+	}
+
+	if tty.dev.dedup {
+		tty.encListAttrs(b, tty.mergeAttrs(b.splicer.export))
 		return
 	}
 
-	if len(tty.attrText) > 0 {
+	pre := tty.preText()
+	if tty.dev.fmtr.attrsDiff {
+		prev := tty.dev.swapAttrText(pre.attrText)
+		if len(pre.attrText) > 0 && pre.attrText == prev && level < slog.LevelError {
+			pre = tty.encAttrsUnchanged(b, pre)
+		}
+	}
+
+	if len(pre.attrText)+len(b.splicer.export) == 0 {
+		return
+	}
+
+	if len(pre.attrText) > 0 {
 		b.writeSep()
-		b.WriteString(tty.attrText)
-		b.sep = tty.attrSep
+		b.WriteString(pre.attrText)
+		b.sep = pre.attrSep
 	}
 
+	opened := pre.opened
 	if len(b.splicer.export) > 0 {
-		tty.encListAttrs(b, b.splicer.export)
-		b.sep = ' '
+		b.prefix = tty.store.scopeKey(len(tty.store.scope))
+		opened = tty.encScope(b, pre.opened, func() { tty.encListAttrs(b, b.splicer.export) })
+		b.prefix = ""
 	}
 
-	if len(tty.store.scope) > 0 {
-		tty.encAttrGroupClose(b, len(tty.store.scope))
+	if opened > 0 {
+		tty.encAttrGroupClose(b, opened)
 	}
 }
 
-func (tty *TTY) encListAttrs(b *Buffer, as []Attr) {
-	for _, a := range as {
-		if tty.dev.replace != nil {
-			a = tty.dev.replace(nil, a)
+// writes a placeholder for unchanged preformatted attrs,
+// returning empty preformatted text in which no scope groups are opened
+func (tty *TTY) encAttrsUnchanged(b *Buffer, pre *ttyPreText) *ttyPreText {
+	b.writeSep()
+	tty.dev.fmtr.diffPen.use(b)
+	b.WriteString("(+")
+	b.AppendInt(int64(pre.attrCount))
+	b.WriteString(" ctx)")
+	tty.dev.fmtr.diffPen.drop(b)
+	b.sep = ' '
+	return &ttyPreTextEmpty
+}
+
+// reports whether attrs are encoded from a merged list, by an attrs encoder, as a tree, or deduplicated, rather than from preformatted text
+func (tty *TTY) attrsMerged() bool {
+	return tty.dev.fmtr.attrs.Encoder != nil || tty.dev.tree || tty.dev.dedup
+}
+
+// mergeAttrs returns the attrs given to WithAttrs, followed by exported attrs, nested in the groups of their scopes.
+// As with slog, empty groups are elided. If keys are deduplicated (see [Config.DedupKeys]), so are the merged attrs.
+func (tty *TTY) mergeAttrs(export []Attr) []Attr {
+	scope := tty.store.scope
+	frames := make([][]Attr, len(scope)+1)
+
+	add := func(depth int, as []Attr) {
+		for _, a := range as {
+			if a.Key != "" {
+				frames[depth] = append(frames[depth], a)
+			}
 		}
+	}
+
+	for _, pre := range tty.pre.chain() {
+		add(len(pre.store.scope), pre.as)
+	}
+	add(len(scope), export)
+
+	return nestFrames(scope, frames, tty.dev.dedup)
+}
+
+// encScope writes attrs with enc, first opening any scope groups not yet opened in preformatted text.
+// If enc writes nothing, neither are the groups opened.
+// The returned count of open scope groups is used to close them.
+func (tty *TTY) encScope(b *Buffer, opened int, enc func()) int {
+	if tty.dev.fmtr.groupDots {
+		enc()
+		return 0
+	}
 
+	mark, sep := len(b.text), b.sep
+	for _, name := range tty.store.scope[opened:] {
+		b.writeSep()
+		b.sep = 0
+
+		tty.dev.fmtr.key.Encode(b, tty.dev.fmtr.abbrevScope(name))
+		tty.encAttrGroupOpen(b)
+	}
+
+	opens := len(b.text)
+	enc()
+	if len(b.text) == opens {
+		b.text, b.sep = b.text[:mark], sep
+		return opened
+	}
+	return len(tty.store.scope)
+}
+
+func (tty *TTY) encListAttrs(b *Buffer, as []Attr) {
+	for _, a := range as {
 		if a.Key == "source" {
 			defer func() {
 				b.writeSep()
@@ -392,15 +1147,20 @@ func (tty *TTY) encListAttrs(b *Buffer, as []Attr) {
 }
 
 func (tty *TTY) encExportTags(b *Buffer) {
-	if tty.label.Key == "#" {
+	for _, label := range tty.labels {
 		b.writeSep()
-		tty.dev.fmtr.tag["#"].Encode(b, tty.label)
+		tty.dev.fmtr.tag[tty.dev.tagKey].Encode(b, label)
 		b.sep = ' '
 	}
 
-	if len(tty.tagText) > 0 {
+	if len(tty.dev.fmtr.tagOrder) > 0 {
+		tty.encOrderedTags(b)
+		return
+	}
+
+	if pre := tty.preText(); len(pre.tagText) > 0 {
 		b.writeSep()
-		b.WriteString(tty.tagText)
+		b.WriteString(pre.tagText)
 		b.sep = ' '
 	}
 
@@ -409,12 +1169,78 @@ func (tty *TTY) encExportTags(b *Buffer) {
 	}
 }
 
-func (tty *TTY) encListTags(b *Buffer, as []Attr) {
-	for _, a := range as {
-		if tty.dev.replace != nil {
-			a = tty.dev.replace(nil, a)
+// tagEntry is a tag-keyed attr, and the dotted keys of the groups enclosing it
+type tagEntry struct {
+	prefix string
+	a      Attr
+}
+
+// writes tags given to WithAttrs and with the record, in the order set by [Config.ShowTagOrder]
+func (tty *TTY) encOrderedTags(b *Buffer) {
+	var tags []tagEntry
+	var sources []Attr
+	collect := func(as []Attr) {
+		for _, a := range as {
+			if a.Key == "source" {
+				sources = append(sources, a)
+				continue
+			}
+			tags = tty.appendTags(b, tags, "", a)
+		}
+	}
+
+	for _, pre := range tty.pre.chain() {
+		collect(pre.as)
+	}
+	collect(b.splicer.export)
+
+	slices.SortStableFunc(tags, func(x, y tagEntry) int {
+		return cmp.Compare(tty.tagRank(x.a.Key), tty.tagRank(y.a.Key))
+	})
+
+	for _, tag := range tags {
+		tty.encTag(b, tag.prefix, tag.a)
+	}
+	for _, a := range sources {
+		b.writeSep()
+		tty.dev.fmtr.source.color.use(b)
+		b.WriteValue(a.Value, nil)
+		tty.dev.fmtr.source.color.drop(b)
+	}
+}
+
+// appends a tag-keyed attr, or those in a group, to tags
+func (tty *TTY) appendTags(b *Buffer, tags []tagEntry, prefix string, a Attr) []tagEntry {
+	if a.Value.Kind() == slog.KindLogValuer {
+		a.Value = b.resolve(a.Value)
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			tags = tty.appendTags(b, tags, prefix+a.Key+".", ga)
 		}
+		return tags
+	}
 
+	if _, found := tty.dev.fmtr.tag[a.Key]; found {
+		tags = append(tags, tagEntry{prefix, a})
+	}
+	return tags
+}
+
+// returns the rank of a tag key in the tag order: a logger's tag is first, and unlisted keys are last
+func (tty *TTY) tagRank(key string) int {
+	if key == tty.dev.tagKey {
+		return -1
+	}
+	if rank, found := tty.dev.fmtr.tagOrder[key]; found {
+		return rank
+	}
+	return len(tty.dev.fmtr.tagOrder)
+}
+
+func (tty *TTY) encListTags(b *Buffer, as []Attr) {
+	for _, a := range as {
 		if a.Key == "source" {
 			defer func() {
 				b.writeSep()
@@ -425,21 +1251,33 @@ func (tty *TTY) encListTags(b *Buffer, as []Attr) {
 			continue
 		}
 
-		tty.encTag(b, a)
+		tty.encTag(b, "", a)
 	}
 }
 
 // GROUPS
 
-// encodes a group with [key=val]-style text
+// encodes a group with [key=val]-style text, or as dotted keys
 func (tty *TTY) encAttrGroup(b *Buffer, a Attr) {
+	prefix := b.prefix
+	defer func() { b.prefix = prefix }()
+
+	if tty.dev.fmtr.groupDots {
+		b.prefix += a.Key + "."
+		tty.encListAttrs(b, a.Value.Group())
+		return
+	}
+
+	key, _ := tty.attrEncoders(prefix, a.Key)
+
 	b.writeSep()
 	b.sep = 0
 
-	tty.dev.fmtr.key.color.use(b)
-	tty.dev.fmtr.key.Encode(b, a.Key)
-	tty.dev.fmtr.key.color.drop(b)
+	key.color.use(b)
+	key.Encode(b, tty.dev.fmtr.abbrevScope(a.Key))
+	key.color.drop(b)
 
+	b.prefix += a.Key + "."
 	tty.encAttrGroupOpen(b)
 	group := a.Value.Group()
 	tty.encListAttrs(b, group)
@@ -464,9 +1302,9 @@ func (tty *TTY) encAttrGroupClose(b *Buffer, count int) {
 	b.sep = '?'
 }
 
-func (tty *TTY) encTagGroup(b *Buffer, scope string, a Attr) {
+func (tty *TTY) encTagGroup(b *Buffer, prefix string, a Attr) {
 	group := a.Value.Group()
 	for _, a := range group {
-		tty.encTag(b, a)
+		tty.encTag(b, prefix, a)
 	}
 }
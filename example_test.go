@@ -1,8 +1,10 @@
 package logf_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/AndrewHarrisSPU/logf"
@@ -26,7 +28,51 @@ func ExampleEncoder() {
 
 	// Output:
 	// ▏ ??? ...
-	//	example_test.go:25
+	//	example_test.go:27
+}
+
+func ExampleEncoder_levels() {
+	for _, enc := range []logf.Encoder[logf.Level]{
+		logf.LevelBar,
+		logf.LevelBullet,
+		logf.LevelText,
+		logf.LevelEmoji,
+		logf.LevelNerd,
+	} {
+		log := logf.New().
+			ForceTTY(true).
+			ShowColor(false).
+			ShowLayout("level", "message").
+			ShowLevel(enc).
+			Logger()
+
+		log.Log(logf.INFO, "info")
+		log.Log(logf.INFO+1, "info+1")
+		log.Log(logf.WARN, "warn")
+		log.Log(logf.ERROR, "error")
+	}
+
+	// Output:
+	// ▏ info
+	//  ▏ info+1
+	// ▕▎ warn
+	// ▐▋ error
+	//  ╼ info
+	//  ╼ info+1
+	//  ╼ warn
+	//  ╼ error
+	//    INFO    info
+	//   INFO+1   info+1
+	//    WARN    warn
+	//    ERROR   error
+	// ℹ️ info
+	// ℹ️ info+1
+	// ⚠️ warn
+	// ❌ error
+	//   info
+	//   info+1
+	//   warn
+	//   error
 }
 
 type mapWithLogValueMethod map[string]any
@@ -128,6 +174,22 @@ func Example_interpolationArguments() {
 	// The rocketing brindle Boston Terrier ...
 }
 
+func Example_interpolationPositional() {
+	log := logf.New().
+		ShowColor(false).
+		ForceTTY(true).
+		Printer()
+
+	// Indexed `{0}`, `{1}` symbols refer to argument pairs by position, and can be reused
+	log.Infof("{0} -> {1} ({0} again)",
+		"from", "a",
+		"to", "b",
+	)
+
+	// Output:
+	// a -> b (a again)
+}
+
 func Example_interpolationArgumentsMixed() {
 	log := logf.New().
 		ShowColor(false).
@@ -267,7 +329,7 @@ func Example_structure() {
 	log.Info("The Truth Is Out There")
 
 	// Output:
-	// The Truth Is Out There	agent:{files:X title:Special Agent name:Fox Mulder}
+	// The Truth Is Out There	agent:{files:X title:"Special Agent" name:"Fox Mulder"}
 }
 
 // Logging, wrapping, and bubbling errors are all possible
@@ -300,8 +362,8 @@ func ExampleWrapErr() {
 	fmt.Println(err3.Error())
 
 	// Output:
-	// the system is down	emails:{user:Strong Bad id:12345 err:the system is down}
-	// Strong Bad: the system is down	emails:{user:Strong Bad id:12345 err:the system is down}
+	// the system is down	emails:{user:"Strong Bad" id:12345 err:"the system is down"}
+	// Strong Bad: the system is down	emails:{user:"Strong Bad" id:12345 err:"the system is down"}
 	// the system is down
 	// Strong Bad: the system is down
 	// Strong Bad: the system is down
@@ -447,6 +509,25 @@ func ExampleLogger_tag() {
 	// Log-9001 Plus one!
 }
 
+func ExampleConfig_TagJoin() {
+	log := logf.New().
+		ShowLayout("message", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		TagJoin("/").
+		Printer()
+
+	parent := log.With("#", "parent")
+	child := parent.With("#", "child")
+
+	parent.Info("Hi!")
+	child.Info("Hi!")
+
+	// Output:
+	// parent Hi!
+	// parent/child Hi!
+}
+
 func ExampleJSONValue() {
 	log := logf.New().
 		ShowLayout("message", "attrs").
@@ -472,6 +553,46 @@ func ExampleJSONValue() {
 	log.Info(logf.Fmt("{recipe.vegetables.1}", recipe))
 
 	// Output:
-	// recipe:{vegetables:{0:tomato 1:pepper 2:green onion} protein:tofu}
+	// recipe:{vegetables:{0:tomato 1:pepper 2:"green onion"} protein:tofu}
 	// pepper
 }
+
+type traceparentKey struct{}
+
+// traceAttrs extracts trace_id and span_id attrs from a W3C traceparent held by a context.
+func traceAttrs(ctx context.Context) []logf.Attr {
+	tp, ok := ctx.Value(traceparentKey{}).(string)
+	if !ok {
+		return nil
+	}
+
+	// version-trace_id-span_id-flags
+	fields := strings.Split(tp, "-")
+	if len(fields) != 4 {
+		return nil
+	}
+
+	return []logf.Attr{
+		logf.KV("trace_id", fields[1]),
+		logf.KV("span_id", fields[2]),
+	}
+}
+
+func ExampleConfig_ContextAttrs() {
+	log := logf.New().
+		ContextAttrs(traceAttrs).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	ctx := context.WithValue(context.Background(), traceparentKey{},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	log.InfoContext(ctx, "traced")
+	log.Info("untraced")
+
+	// Output:
+	// traced	trace_id:4bf92f3577b34da6a3ce929d0e0e4736 span_id:00f067aa0ba902b7
+	// untraced
+}
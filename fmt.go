@@ -1,18 +1,22 @@
 package logf
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"runtime"
+	"slices"
+
+	"log/slog"
 )
 
-func logFmt(l Logger, f string, args []any) string {
+// returns the store and replace function of a logf-native handler
+func loggerStore(l Logger) (store Store, replace replaceFunc, ok bool) {
 	h, ok := l.Handler().(handler)
 	if !ok {
-		return f
+		return
 	}
 
-	var store Store
-	var replace func([]string, Attr) Attr
 	switch h := h.(type) {
 	case *Handler:
 		store = h.store
@@ -21,56 +25,302 @@ func logFmt(l Logger, f string, args []any) string {
 		store = h.store
 		replace = h.dev.replace
 	}
+	return
+}
 
+// returns a splicer for interpolating with a Logger.
+// A [TTY] handler's group style applies; its treatment of secrets does not.
+func loggerSplicer(l Logger) *splicer {
 	s := newSplicer()
-	defer s.free()
+	switch h := l.Handler().(type) {
+	case *TTY:
+		s.dots = h.dev.fmtr.groupDots
+		s.structs = h.dev.fmtr.structFields
+		s.timePrec = h.dev.timePrec
+		s.hexBytes, s.hexMax = true, h.dev.fmtr.hexMax
+		s.maps, s.mapSort = true, h.dev.fmtr.mapSort
+		s.floatFmt, s.floatPrec, s.floatTrim = h.dev.fmtr.floatFmt, h.dev.fmtr.floatPrec, h.dev.fmtr.floatTrim
+	case *Handler:
+		s.timePrec = h.timePrec
+	}
+	return s
+}
 
+// scans, joins, and interpolates f
+func (s *splicer) splice(f string, store Store, replace replaceFunc, args []any) {
 	s.scanMessage(f)
-	s.joinStore(store, replace)
+	s.joinStore(store)
 	for _, a := range Attrs(args...) {
-		s.joinLocal(store.scope, a, replace)
+		s.joinLocal(store, a, replace)
 	}
 	s.ipol(f)
+}
 
-	return s.line()
+// builds an error from spliced text, wrapping err
+// Errors among the exported attrs are wrapped as well, as with multiple %w verbs given to [fmt.Errorf].
+func (s *splicer) wrapErr(err error) error {
+	var errs []error
+	for _, a := range s.export {
+		if argErr, ok := a.Value.Any().(error); ok && a.Value.Kind() != slog.KindLogValuer {
+			errs = append(errs, argErr)
+		}
+	}
+
+	var e error
+	switch {
+	case len(errs) > 0:
+		msg := s.line()
+		if err != nil {
+			if len(msg) > 0 {
+				msg += ": "
+			}
+			msg += err.Error()
+			errs = append([]error{err}, errs...)
+		}
+		e = &wrapErrors{msg, errs}
+	case err == nil:
+		e = errors.New(s.line())
+	default:
+		if len(s.text) > 0 {
+			s.WriteString(": ")
+		}
+		s.WriteString("%w")
+		e = fmt.Errorf(s.line(), err)
+	}
+
+	if len(s.export) == 0 {
+		return e
+	}
+	return &attrsError{e, slices.Clone(s.export), nil}
 }
 
-func logFmtErr(l Logger, f string, err error, args []any) error {
-	h, ok := l.Handler().(handler)
-	if !ok {
-		return err
+// attrsError is an error carrying the attrs it was interpolated with, and
+// possibly a stack. It is otherwise indistinguishable from the error it encapsulates.
+type attrsError struct {
+	err   error
+	attrs []Attr
+	stack []runtime.Frame
+}
+
+func (e *attrsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *attrsError) Unwrap() error {
+	return errors.Unwrap(e.err)
+}
+
+// Is reports whether any error wrapped by e matches target.
+func (e *attrsError) Is(target error) bool {
+	if _, multi := e.err.(*wrapErrors); !multi {
+		return false
 	}
+	return errors.Is(e.err, target)
+}
 
-	var store Store
-	var replace func([]string, Attr) Attr
-	switch h := h.(type) {
-	case *Handler:
-		store = h.store
-		replace = h.replace
-	case *TTY:
-		store = h.store
-		replace = h.dev.replace
+// As finds the first error wrapped by e that matches target.
+func (e *attrsError) As(target any) bool {
+	if _, multi := e.err.(*wrapErrors); !multi {
+		return false
 	}
+	return errors.As(e.err, target)
+}
+
+// wrapErrors wraps several errors, like the result of [fmt.Errorf] given multiple %w verbs.
+type wrapErrors struct {
+	msg  string
+	errs []error
+}
+
+func (e *wrapErrors) Error() string {
+	return e.msg
+}
+
+func (e *wrapErrors) Unwrap() []error {
+	return e.errs
+}
+
+// StackTrace returns the stack captured by [WrapErrStack], if any.
+func (e *attrsError) StackTrace() []runtime.Frame {
+	return e.stack
+}
+
+// logValue returns a group of the error message, keyed "msg", followed by the attrs used to interpolate it,
+// and any captured stack, keyed "stack".
+// It isn't a LogValue method: only logf handlers export the group, while other handlers see the error as is.
+func (e *attrsError) logValue() Value {
+	as := make([]Attr, 0, len(e.attrs)+2)
+	as = append(as, slog.String("msg", e.err.Error()))
+	as = append(as, e.attrs...)
+	if e.stack != nil {
+		as = append(as, slog.Attr{Key: "stack", Value: stackValue(e.stack)})
+	}
+	return slog.GroupValue(as...)
+}
+
+// errValue returns the group of an error carrying attrs, as logf handlers export it, or v as given
+func errValue(v Value) Value {
+	if v.Kind() != slog.KindAny {
+		return v
+	}
+	if ae, ok := v.Any().(*attrsError); ok {
+		return ae.logValue()
+	}
+	return v
+}
 
+// expandErrs returns as, with the values of errors carrying attrs replaced by their groups.
+// If there are no such errors, as is returned as given.
+func expandErrs(as []Attr) []Attr {
+	var expanded []Attr
+	for i, a := range as {
+		v := errValue(a.Value)
+		if expanded == nil {
+			if v.Kind() == a.Value.Kind() {
+				continue
+			}
+			expanded = slices.Clone(as)
+		}
+		expanded[i].Value = v
+	}
+	if expanded == nil {
+		return as
+	}
+	return expanded
+}
+
+// expandRecordErrs returns r, with the values of errors carrying attrs replaced by their groups
+func expandRecordErrs(r slog.Record) slog.Record {
+	var found bool
+	r.Attrs(func(a Attr) bool {
+		found = errValue(a.Value).Kind() != a.Value.Kind()
+		return !found
+	})
+	if !found {
+		return r
+	}
+
+	as := make([]Attr, 0, r.NumAttrs())
+	r.Attrs(func(a Attr) bool {
+		as = append(as, a)
+		return true
+	})
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(expandErrs(as)...)
+	return r2
+}
+
+// sansStack returns a copy of the error without a stack
+func (e *attrsError) sansStack() *attrsError {
+	return &attrsError{e.err, e.attrs, nil}
+}
+
+// stackTracer is implemented by errors from [WrapErrStack]
+type stackTracer interface {
+	StackTrace() []runtime.Frame
+}
+
+// errStack returns the stack captured by the first error in err's tree offering one
+func errStack(err error) []runtime.Frame {
+	var st stackTracer
+	if errors.As(err, &st) {
+		return st.StackTrace()
+	}
+	return nil
+}
+
+// WrapErrStack is like [WrapErr], but also captures the call stack.
+// The returned error has a method `StackTrace() []runtime.Frame` returning the stack,
+// and logf handlers export it as a group including a "stack" group, as with [Config.AddStack].
+// At most 32 frames are captured, and frames from logf itself are trimmed.
+//
+// A [TTY] configured with [Config.AddSource] renders the stack on lines following the error.
+func WrapErrStack(f string, err error, args ...any) error {
 	s := newSplicer()
 	defer s.free()
 
-	s.scanMessage(f)
-	s.joinStore(store, replace)
-	for _, a := range Attrs(args...) {
-		s.joinLocal(store.scope, a, replace)
+	s.splice(f, Store{}, nil, args)
+
+	e := s.wrapErr(err)
+	ae, ok := e.(*attrsError)
+	if !ok {
+		ae = &attrsError{err: e}
 	}
-	s.ipol(f)
+	ae.stack = callers(1)
+	return ae
+}
 
-	if err == nil {
-		return errors.New(s.line())
+func logFmt(l Logger, f string, args []any) string {
+	store, replace, ok := loggerStore(l)
+	if !ok {
+		return f
 	}
 
-	if len(s.text) > 0 {
-		s.WriteString(": ")
+	s := loggerSplicer(l)
+	defer s.free()
+
+	s.splice(f, store, replace, args)
+	return s.line()
+}
+
+// logFmtArgs interpolates f as logFmt does, returning the arguments to export with the record:
+// if the Logger consumes interpolated arguments (see [Config.ConsumeInterpolated]), those not interpolated.
+// The returned ctx carries the spans of interpolated values if the Logger's [TTY] highlights them;
+// a nil ctx is returned as given otherwise.
+func logFmtArgs(ctx context.Context, l Logger, f string, args []any) (context.Context, string, []any) {
+	store, replace, ok := loggerStore(l)
+	if !ok {
+		return ctx, f, args
+	}
+
+	s := loggerSplicer(l)
+	defer s.free()
+
+	consume := loggerConsumes(l)
+	if consume {
+		s.audit = new(ipolAudit)
+	}
+	if tty, ok := l.Handler().(*TTY); ok && len(tty.dev.fmtr.ipolPen) > 0 {
+		s.markSpans = true
+	}
+
+	s.splice(f, store, replace, args)
+	if consume {
+		args = s.unconsumed(store, args)
+	}
+
+	msg := s.line()
+	if s.markSpans && len(s.spans) > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = context.WithValue(ctx, spansKey{}, &ipolSpans{msg, slices.Clone(s.spans)})
+	}
+	return ctx, msg, args
+}
+
+// reports whether a Logger's handler consumes interpolated arguments
+func loggerConsumes(l Logger) bool {
+	switch h := l.Handler().(type) {
+	case *Handler:
+		return h.consume
+	case *TTY:
+		return h.dev.consume
+	}
+	return false
+}
+
+func logFmtErr(l Logger, f string, err error, args []any) error {
+	store, replace, ok := loggerStore(l)
+	if !ok {
+		return err
 	}
-	s.WriteString("%w")
-	return fmt.Errorf(s.line(), err)
+
+	s := loggerSplicer(l)
+	defer s.free()
+
+	s.splice(f, store, replace, args)
+	return s.wrapErr(err)
 }
 
 // Fmt interpolates the f string with the given arguments.
@@ -79,35 +329,69 @@ func Fmt(f string, args ...any) string {
 	s := newSplicer()
 	defer s.free()
 
-	s.scanMessage(f)
-	for _, a := range Attrs(args...) {
-		s.joinLocal(nil, a, nil)
-	}
-	s.ipol(f)
-
+	s.splice(f, Store{}, nil, args)
 	return s.line()
 }
 
 // WrapErr interpolates the f string with the given arguments and error.
 // The arguments parse as with [Attrs].
 // The returned error matches [errors.Is]/[errors.As] behavior, as with [fmt.Errorf].
+// Error-valued arguments are wrapped too, as with multiple %w verbs.
+// Given any arguments, logging the returned error with a logf [Handler] or [TTY] exports a group
+// of the error message (keyed "msg") and the arguments, preserving structure.
+// Other handlers see only the error message.
 func WrapErr(f string, err error, args ...any) error {
 	s := newSplicer()
 	defer s.free()
 
-	s.scanMessage(f)
-	for _, a := range Attrs(args...) {
-		s.joinLocal(nil, a, nil)
-	}
-	s.ipol(f)
+	s.splice(f, Store{}, nil, args)
+	return s.wrapErr(err)
+}
 
-	if err == nil {
-		return errors.New(s.line())
-	}
+// TryFmt is like [Fmt], but also reports interpolation faults.
+// The returned string is the same best-effort result as [Fmt].
+// The returned error is non-nil if any keyed interpolation site is unmatched,
+// any unkeyed or positional site lacks an argument, any argument is unused,
+// or the argument list is malformed (as with `!missing-key` or `!missing-arg`).
+func TryFmt(f string, args ...any) (string, error) {
+	s := newSplicer()
+	defer s.free()
 
-	if len(s.text) > 0 {
-		s.WriteString(": ")
-	}
-	s.WriteString("%w")
-	return fmt.Errorf(s.line(), err)
+	s.audit = new(ipolAudit)
+	s.splice(f, Store{}, nil, args)
+	return s.line(), s.auditErr(Store{})
+}
+
+// TryWrapErr is like [WrapErr], but also reports interpolation faults, as with [TryFmt].
+func TryWrapErr(f string, err error, args ...any) (error, error) {
+	s := newSplicer()
+	defer s.free()
+
+	s.audit = new(ipolAudit)
+	s.splice(f, Store{}, nil, args)
+	fault := s.auditErr(Store{})
+	return s.wrapErr(err), fault
+}
+
+func logTryFmt(l Logger, f string, args []any) (string, error) {
+	store, replace, _ := loggerStore(l)
+
+	s := loggerSplicer(l)
+	defer s.free()
+
+	s.audit = new(ipolAudit)
+	s.splice(f, store, replace, args)
+	return s.line(), s.auditErr(store)
+}
+
+func logTryFmtErr(l Logger, f string, err error, args []any) (error, error) {
+	store, replace, _ := loggerStore(l)
+
+	s := loggerSplicer(l)
+	defer s.free()
+
+	s.audit = new(ipolAudit)
+	s.splice(f, store, replace, args)
+	fault := s.auditErr(store)
+	return s.wrapErr(err), fault
 }
@@ -1,8 +1,11 @@
 package logf
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -82,6 +85,8 @@ func TestFmtKinds(t *testing.T) {
 
 		// any fmting
 		{struct{}{}, "", `{}`},
+		{testAgent{"Fox", "Mulder", 42}, "", `{Fox Mulder 42}`},
+		{&testAgent{"Dana", "Scully", 0}, "", `&{Dana Scully 0}`},
 
 		// group
 		{slog.GroupValue(slog.Int("A", 1), slog.Int("B", 2)), "", `[A=1 B=2]`},
@@ -99,6 +104,247 @@ func TestFmtKinds(t *testing.T) {
 	}
 }
 
+type (
+	testNilErr    struct{ msg string }
+	testFormatter struct{}
+)
+
+func (e *testNilErr) Error() string { return e.msg }
+
+func (testFormatter) Format(f fmt.State, verb rune) { f.Write([]byte("formatted")) }
+func (testFormatter) Error() string                 { return "error" }
+
+// fast paths for KindAny values are byte-for-byte %v
+func TestFmtAnyParity(t *testing.T) {
+	for _, x := range []any{
+		errors.New("boom"),
+		(*testNilErr)(nil),
+		testFormatter{},
+		testStringer{1},
+		[]byte("ab"),
+		[]byte(nil),
+		int8(-1), int16(-1), int32(-1),
+		uint8(1), uint16(1), uint32(1), uintptr(1),
+		float32(1.1), float32(math.Inf(-1)),
+	} {
+		s := newSplicer()
+		s.writeAny(x)
+		if got, want := s.line(), fmt.Sprintf("%v", x); got != want {
+			t.Errorf("%T: want %q, got %q", x, want, got)
+		}
+		s.free()
+	}
+}
+
+func TestFmtBytes(t *testing.T) {
+	digest := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	for _, f := range []struct {
+		arg  any
+		verb string
+		want string
+	}{
+		{digest, "", `[222 173 190 239]`},
+		{digest, "hex", `deadbeef`},
+		{digest, "b64", `3q2+7w==`},
+		{digest, "len", `4`},
+		{"hi", "hex", `6869`},
+		{"hi", "b64", `aGk=`},
+		{"héllo", "len", `6`},
+		{[]byte(nil), "hex", ``},
+		{1, "hex", `1`},
+	} {
+		msg := fmt.Sprintf("{key:%s}", f.verb)
+		if got := Fmt(msg, "key", f.arg); got != f.want {
+			t.Errorf("%s: want %s, got %s", f.verb, f.want, got)
+		}
+	}
+
+	// with a TTY, byte slices are hex, and truncated
+	var b bytes.Buffer
+	cfg := New().
+		Writer(&b).
+		ShowLayout("attrs").
+		ShowColor(false).
+		ForceTTY(true)
+
+	cfg.Logger().Info("", "digest", digest, "blob", make([]byte, 40))
+	want := "digest:deadbeef blob:" + strings.Repeat("00", 32) + "…(40B)\n"
+	if got := b.String(); got != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+	b.Reset()
+
+	cfg.ShowBytesMax(2).Logger().Info("", "digest", digest)
+	if want, got := "digest:dead…(4B)\n", b.String(); got != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+}
+
+// a TTY's float format applies without a verb; verbs apply as given
+func TestFmtFloats(t *testing.T) {
+	// (a constant sum would be exact)
+	a, b := 0.1, 0.2
+	noisy := a + b
+
+	for _, f := range []struct {
+		cfg  *Config
+		arg  float64
+		verb string
+		want string
+	}{
+		{New(), noisy, "", `0.30000000000000004`},
+		{New(), 3, "", `3`},
+		{New().FloatFormat('f', 2), noisy, "", `0.30`},
+		{New().FloatFormat('f', 2), 3, "", `3.00`},
+		{New().FloatFormat('f', 2), noisy, "%.4f", `0.3000`},
+		{New().FloatFormat('f', 2), noisy, "%v", `0.30000000000000004`},
+		{New().FloatFormat('e', 3), 12345.678, "", `1.235e+04`},
+		{New().FloatFormat('?', 2), 0.5, "", `0.5`},
+		{New().FloatFormat('f', 2).ShowFloatTrimZero(true), 3, "", `3`},
+		{New().FloatFormat('f', 2).ShowFloatTrimZero(true), -3, "", `-3`},
+		{New().FloatFormat('f', 2).ShowFloatTrimZero(true), 3.5, "", `3.50`},
+		{New().FloatFormat('f', 2).ShowFloatTrimZero(true), 3, "%.1f", `3.0`},
+		{New().ShowFloatTrimZero(true), math.Inf(1), "", `+Inf`},
+	} {
+		log := f.cfg.ForceTTY(true).Logger()
+		msg := fmt.Sprintf("{x:%s}", f.verb)
+		if got := log.Fmt(msg, "x", f.arg); got != f.want {
+			t.Errorf("%s: want %s, got %s", msg, f.want, got)
+		}
+	}
+
+	// without a TTY, floats are rendered as before
+	if got := New().FloatFormat('f', 2).JSON().Fmt("{}", 0.5); got != "0.5" {
+		t.Errorf("JSON: got %s", got)
+	}
+}
+
+type testAgent struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Age   int    `json:"age,omitempty"`
+}
+
+type testCase struct {
+	Agent testAgent
+	Ref   *testAgent
+	Open  bool
+	Err   error
+	Since time.Duration
+	note  string
+}
+
+type testBadge struct {
+	testAgent
+	Badge int `json:"badge"`
+}
+
+type testPartner struct {
+	*testAgent
+	Partner testAgent `json:"partner"`
+}
+
+type testStringer struct {
+	V int
+}
+
+func (s testStringer) String() string {
+	return fmt.Sprintf("#%d", s.V)
+}
+
+// with a TTY, structs in KindAny values are written with field names
+func TestFmtKindsStructFields(t *testing.T) {
+	for _, f := range []struct {
+		arg  any
+		want string
+	}{
+		{struct{}{}, `{}`},
+		{struct{ a, b int }{1, 2}, `{1 2}`},
+		{testAgent{"Fox", "Mulder", 42}, `{first:Fox last:Mulder age:42}`},
+		{testAgent{"Dana", "Scully", 0}, `{first:Dana last:Scully}`},
+		{&testAgent{"Fox", "Mulder", 42}, `&{first:Fox last:Mulder age:42}`},
+		{testCase{Agent: testAgent{First: "Fox"}, Since: time.Second, note: "x"}, `{Agent:{first:Fox last:} Ref:<nil> Open:false Err:<nil> Since:1s}`},
+		// embedded structs are promoted, as with StructAttrs
+		{testBadge{testAgent{"Fox", "Mulder", 42}, 1}, `{first:Fox last:Mulder age:42 badge:1}`},
+		{testPartner{&testAgent{First: "Fox"}, testAgent{First: "Dana"}}, `{first:Fox last: partner:{first:Dana last:}}`},
+		{testPartner{nil, testAgent{First: "Dana"}}, `{partner:{first:Dana last:}}`},
+		{testStringer{1}, `#1`},
+		{[]testAgent{{First: "Fox"}}, `[{Fox  0}]`},
+	} {
+		log := New().ForceTTY(true).Logger()
+		if got := log.Fmt("{}", f.arg); got != f.want {
+			t.Errorf("want: %s, got: %s", f.want, got)
+		}
+
+		log = New().ForceTTY(true).ShowStructFields(false).Logger()
+		if got, want := log.Fmt("{}", f.arg), fmt.Sprintf("%v", f.arg); got != want {
+			t.Errorf("want: %s, got: %s", want, got)
+		}
+	}
+}
+
+func TestTemplateCache(t *testing.T) {
+	msgs := []string{
+		"{a} {b}",
+		"{a|none} {b:%03d}",
+		`{a\:b} {0} {}`,
+	}
+
+	// hits with different arguments
+	for i := 0; i < 3; i++ {
+		for _, args := range [][]any{
+			{"a", 1, "b", 2},
+			{"b", 3, "a", "x"},
+			{"a:b", true},
+		} {
+			for _, msg := range msgs {
+				templates.disabled.Store(true)
+				want := Fmt(msg, args...)
+				templates.disabled.Store(false)
+
+				if got := Fmt(msg, args...); got != want {
+					t.Errorf("%s %v: want %q, got %q", msg, args, want, got)
+				}
+			}
+		}
+	}
+
+	for _, msg := range msgs {
+		if _, ok := templates.get(msg); !ok {
+			t.Errorf("%s: not cached", msg)
+		}
+	}
+
+	// bounded, and recently used messages survive
+	for i := 0; i < 2*templateCacheSize; i++ {
+		Fmt(fmt.Sprintf("{%d-%s}", i, msgs[0]))
+		Fmt(msgs[0])
+	}
+	if n := templates.len(); n > templateCacheSize {
+		t.Errorf("cache holds %d messages", n)
+	}
+	if _, ok := templates.get(msgs[0]); !ok {
+		t.Errorf("%s: evicted", msgs[0])
+	}
+	if _, ok := templates.get(msgs[1]); ok {
+		t.Errorf("%s: not evicted", msgs[1])
+	}
+
+	// messages without clips aren't cached
+	Fmt("no clips")
+	if _, ok := templates.get("no clips"); ok {
+		t.Errorf("cached a message without clips")
+	}
+
+	// disabled
+	defer templates.disabled.Store(false)
+	DisableTemplateCache()
+	if got := Fmt(msgs[0], "a", 1, "b", 2); got != "1 2" || templates.len() > 0 {
+		t.Errorf("disabled: got %q, holding %d", got, templates.len())
+	}
+}
+
 func TestLoggerLogValuer(t *testing.T) {
 	want := func(ok string, got string) {
 		if ok != got {
@@ -212,3 +458,470 @@ func TestGroupsFmt(t *testing.T) {
 
 	want("[1=[first=Fox last=Mulder] 2=[first=Dana last=Scully]] 1?", Fmt("{} {first}", agents, "first", "1?"))
 }
+
+func TestPositionalFmt(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	want("x -> y (x again)", Fmt("{0} -> {1} ({0} again)", "a", "x", "b", "y"))
+	want("  1.50", Fmt("{1:%6.2f}", "a", "x", "b", 1.5))
+	want(missingAttr, Fmt("{2}", "a", "x", "b", "y"))
+
+	// positional tokens don't advance the unkeyed cursor
+	want("y x y", Fmt("{1} {} {}", "a", "x", "b", "y"))
+}
+
+func TestFallbackFmt(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	want("anonymous", Fmt("{user|anonymous}"))
+	want("gopher", Fmt("{user|anonymous}", "user", "gopher"))
+
+	// unkeyed and positional
+	want("1 n/a", Fmt("{} {|n/a}", "x", 1))
+	want("n/a", Fmt("{3|n/a}", "x", 1))
+
+	// fallback precedes verb; verbs apply only to matched values
+	want("0", Fmt("{count|0:%03d}"))
+	want("007", Fmt("{count|0:%03d}", "count", 7))
+
+	// escaped pipes
+	want("a|b", Fmt(`{key|a\|b}`))
+	want("x", Fmt(`{pipe\|key}`, "pipe|key", "x"))
+
+	// empty fallback
+	want("[]", Fmt("[{user|}]"))
+}
+
+func TestStarFmt(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	want("[]", Fmt("[{*}]"))
+	want("[a=1 b=two]", Fmt("[{*}]", "a", 1, "b", "two"))
+	want(`a="x" g.b="y"`, Fmt("{*:%q}", "a", "x", slog.Group("g", "b", "y")))
+
+	// doesn't advance the unkeyed cursor
+	want("a=1 b=2: 1", Fmt("{*}: {}", "a", 1, "b", 2))
+
+	// store attrs, scoped
+	log := New().
+		ForceTTY(true).
+		Logger().
+		With("id", 7).
+		WithGroup("req")
+
+	want("request failed: id=7 req.path=/", log.Fmt("request failed: {*}", "path", "/"))
+
+	err := log.WrapErr("request failed {*}", errors.New("EOF"), "path", "/")
+	want("request failed id=7 req.path=/: EOF", err.Error())
+}
+
+func TestQuoteJSONVerbs(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	group := slog.GroupValue(slog.String("a", "x y"), slog.Group("b", "c", 1, "d", nil))
+
+	fs := []struct {
+		arg  any
+		verb string
+		want string
+	}{
+		// quoting
+		{"a b\n", "%q", `"a b\n"`},
+		{1, "%q", `"1"`},
+		{time.Second, "%q", `"1s"`},
+		{time.Unix(0, 0).UTC(), "%q", `"1970-01-01T00:00:00.000Z"`},
+		{group, "%q", `"[a=x y b=[c=1 d=<nil>]]"`},
+		{spoof2{}, "%q", `"spoof"`},
+
+		// json
+		{"a \"b\"\n", "json", `"a \"b\"\n"`},
+		{1.5, "json", `1.5`},
+		{true, "json", `true`},
+		{time.Second, "json", `1000000000`},
+		{time.Unix(0, 0).UTC(), "json", `"1970-01-01T00:00:00.000Z"`},
+		{group, "json", `{"a":"x y","b":{"c":1,"d":null}}`},
+		{spoof2{}, "json", `"spoof"`},
+		{nil, "json", `null`},
+		{errors.New("oops"), "json", `"oops"`},
+		{[]int{1, 2}, "json", `[1,2]`},
+	}
+
+	for _, f := range fs {
+		msg := fmt.Sprintf("{key:%s}", f.verb)
+		want(f.want, Fmt(msg, "key", f.arg))
+	}
+}
+
+func TestHumanVerbs(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	want("512 B", Fmt("{size:bytes}", "size", 512))
+	want("1.0 KiB", Fmt("{size:bytes}", "size", 1024))
+	want("1.2 MiB", Fmt("{size:bytes}", "size", uint64(1258291)))
+	want("1.5 GiB", Fmt("{size:bytes}", "size", 1.5*(1<<30)))
+	want("8.0 EiB", Fmt("{size:bytes}", "size", uint64(1<<63)))
+
+	want("999", Fmt("{count:si}", "count", 999))
+	want("3.4M", Fmt("{count:si}", "count", 3_400_000))
+	want("1.0k", Fmt("{count:si}", "count", 1000))
+	want("2.5", Fmt("{count:si}", "count", 2.5))
+
+	// values rounding up to the next unit
+	want("1.0 MiB", Fmt("{size:bytes}", "size", 1048575))
+	want("1023.9 KiB", Fmt("{size:bytes}", "size", 1048524))
+	want("1.0 GiB", Fmt("{size:bytes}", "size", 1<<30-1))
+	want("1.0M", Fmt("{count:si}", "count", 999_950))
+	want("999.9k", Fmt("{count:si}", "count", 999_949))
+	want("1.0G", Fmt("{count:si}", "count", 999_999_999))
+	want("8192.0 EiB", Fmt("{size:bytes}", "size", math.Ldexp(1, 73)))
+
+	// fallbacks
+	want("-1024", Fmt("{size:bytes}", "size", -1024))
+	want("big", Fmt("{size:bytes}", "size", "big"))
+	want("NaN", Fmt("{count:si}", "count", math.NaN()))
+
+	// values
+	want("1.2 MiB", Fmt("{size}", "size", BytesValue(1258291)))
+	want("1.2 MiB", Fmt("{size:bytes}", "size", BytesValue(1258291)))
+	want("3.4M", Fmt("{count}", "count", SIValue(3_400_000)))
+
+	var b bytes.Buffer
+	slog.New(slog.NewJSONHandler(&b, nil)).Info("", "size", BytesValue(1258291))
+	if !strings.Contains(b.String(), `"size":1258291`) {
+		t.Errorf("json: got %s", b.String())
+	}
+}
+
+func TestAgoVerb(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return now }
+
+	ago := func(d time.Duration) string {
+		return Fmt("{t:ago}", "t", now.Add(-d))
+	}
+
+	// times: the most significant unit, truncated
+	want("now", ago(0))
+	want("now", ago(999*time.Millisecond))
+	want("1s ago", ago(time.Second))
+	want("59s ago", ago(59*time.Second+999*time.Millisecond))
+	want("1m ago", ago(time.Minute))
+	want("3m ago", ago(3*time.Minute+59*time.Second))
+	want("23h ago", ago(24*time.Hour-time.Nanosecond))
+	want("2d ago", ago(48*time.Hour))
+	want("in 5s", ago(-5*time.Second))
+	want("in 1h", ago(-time.Hour-30*time.Minute))
+
+	// durations: two most significant units, rounded
+	want("1h24m ago", Fmt("{d:ago}", "d", time.Hour+23*time.Minute+30*time.Second))
+	want("1h23m ago", Fmt("{d:ago}", "d", time.Hour+23*time.Minute+29*time.Second))
+	want("1h ago", Fmt("{d:ago}", "d", 59*time.Minute+59*time.Second+500*time.Millisecond))
+	want("1m30s ago", Fmt("{d:ago}", "d", 90*time.Second))
+	want("1s500ms ago", Fmt("{d:ago}", "d", 1500*time.Millisecond))
+	want("2d ago", Fmt("{d:ago}", "d", 47*time.Hour+30*time.Minute))
+	want("in 2m5s", Fmt("{d:ago}", "d", -(2*time.Minute+5*time.Second)))
+
+	// with WrapErr, and logging
+	want("deployed 2h ago", WrapErr("deployed {t:ago}", nil, "t", now.Add(-2*time.Hour)).Error())
+
+	var b bytes.Buffer
+	New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		Infof("deployed {t:ago}", "t", now.Add(-2*time.Hour))
+	want("deployed 2h ago\n", b.String())
+}
+
+func TestCaseVerbs(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	want("ERROR: USER NOT FOUND", Fmt("ERROR: {:upper}", "err", "user not found"))
+	want("user not found", Fmt("{:lower}", "err", "User Not Found"))
+	want("User Not Found", Fmt("{:title}", "err", "user not found"))
+
+	// non-ASCII
+	want("ÉCLAIR", Fmt("{:upper}", "", "éclair"))
+	want("Éclair Über", Fmt("{:title}", "", "éclair über"))
+
+	// non-string kinds
+	want("TRUE", Fmt("{:upper}", "", true))
+	want("1H1M1S", Fmt("{:upper}", "", time.Hour+time.Minute+time.Second))
+	want("[A=X]", Fmt("{:upper}", "", slog.GroupValue(slog.String("a", "x"))))
+
+	// exported attrs keep their case
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.Infof("{user:upper}", "user", "gopher")
+	want("GOPHER\tuser:gopher\n", b.String())
+}
+
+func TestIndexFmt(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %s, got: %s", ok, got)
+		}
+	}
+
+	list := []string{"tomato", "pepper"}
+	want("pepper", Fmt("{list.1}", "list", list))
+	want("tomato", Fmt("{list.0}", "list", [2]string{"tomato", "pepper"}))
+	want(missingMatch.String(), Fmt("{list.2}", "list", list))
+	want(missingMatch.String(), Fmt("{list.x}", "list", list))
+
+	m := map[string]any{
+		"a": 1,
+		"b": []int{2, 3},
+		"c": map[int]string{4: "four"},
+	}
+	want("1", Fmt("{m.a}", "m", m))
+	want("3", Fmt("{m.b.1}", "m", m))
+	want("four", Fmt("{m.c.4}", "m", &m))
+	want(missingMatch.String(), Fmt("{m.z}", "m", m))
+
+	// scoped, in a store
+	log := New().
+		ForceTTY(true).
+		Logger().
+		WithGroup("g").
+		With("list", list)
+
+	want("tomato", log.Fmt("{g.list.0}"))
+}
+
+func TestUnbalancedFmt(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("want: %q, got: %q", ok, got)
+		}
+	}
+
+	want("end {", Fmt("end {"))
+	want("Hello {name", Fmt("Hello {name", "name", "x"))
+	want("x, {y", Fmt("{}, {y", "x", "x"))
+	want(`esc {`, Fmt(`esc \{`))
+	want(`trail \`, Fmt(`trail \`))
+	want("right }", Fmt("right }"))
+}
+
+func TestTryFmt(t *testing.T) {
+	fs := []struct {
+		msg    string
+		args   []any
+		want   string
+		faults []string
+	}{
+		{"{name}", []any{"name", "gopher"}, "gopher", nil},
+		{"{} {1}", []any{"a", 1, "b", 2}, "1 2", nil},
+		{"{name|anon}", nil, "anon", nil},
+		{"{*}", []any{"a", 1}, "a=1", nil},
+		{"{g.x}", []any{Group("g", "x", 1)}, "1", nil},
+		{"{name}", nil, missingMatch.String(), []string{"unmatched key {name}"}},
+		{"{} {}", []any{"a", 1}, "1 " + missingAttr, []string{"no argument for unkeyed {} number 2"}},
+		{"{3}", []any{"a", 1}, missingAttr, []string{"no argument for {3}", "argument 0: unused a=1"}},
+		{"{a}", []any{"a", 1, "b", 2}, "1", []string{"argument 1: unused b=2"}},
+		{"{a}", []any{"a", 1, 2}, "1", []string{"argument 1: no key for value 2"}},
+		{"{a}", []any{"a"}, missingArg, []string{`argument 0: no value for key "a"`}},
+	}
+
+	for _, f := range fs {
+		got, err := TryFmt(f.msg, f.args...)
+		if got != f.want {
+			t.Errorf("%s: want %q, got %q", f.msg, f.want, got)
+		}
+
+		if len(f.faults) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", f.msg, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("%s: expected error", f.msg)
+			continue
+		}
+		for _, fault := range f.faults {
+			if !strings.Contains(err.Error(), fault) {
+				t.Errorf("%s: want fault %q in %q", f.msg, fault, err.Error())
+			}
+		}
+	}
+
+	// wrapping
+	base := errors.New("base")
+	err, fault := TryWrapErr("{op}", base, "op", "read")
+	if err.Error() != "read: base" || !errors.Is(err, base) || fault != nil {
+		t.Errorf("TryWrapErr: got %v, %v", err, fault)
+	}
+
+	// logger methods see stored attrs
+	log := New().
+		ForceTTY(true).
+		Logger().
+		With("user", "gopher")
+
+	if got, err := log.TryFmt("{user}"); got != "gopher" || err != nil {
+		t.Errorf("Logger.TryFmt: got %q, %v", got, err)
+	}
+	if _, fault := log.TryWrapErr("{user} {path}", base); fault == nil {
+		t.Errorf("Logger.TryWrapErr: expected fault")
+	}
+}
+
+func TestConsumeInterpolated(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		ConsumeInterpolated(true)
+
+	for _, log := range []Logger{cfg.Logger(), cfg.Text()} {
+		log = log.With("a", 0)
+
+		for _, tc := range []struct {
+			msg  string
+			args []any
+			tty  string
+			text string
+		}{
+			{"Hello, {}", []any{"place", "Roswell"}, "Hello, Roswell\ta:0", "msg=\"Hello, Roswell\" a=0"},
+			{"{place} {1}", []any{"place", "Roswell", "n", 1, "m", 2}, "Roswell 1\ta:0 m:2", "msg=\"Roswell 1\" a=0 m=2"},
+			{"{g.x}", []any{Group("g", "x", 1), "y", 2}, "1\ta:0 y:2", "msg=1 a=0 y=2"},
+			{"{*}", []any{"x", 1}, "a=0 x=1\ta:0", "msg=\"a=0 x=1\" a=0"},
+			// the stored attr is exported, and the argument with the same key is consumed
+			{"{a}", []any{"a", 1}, "1\ta:0", "msg=1 a=0"},
+			// nothing interpolated
+			{"no args", []any{"b", 2}, "no args\ta:0 b:2", "msg=\"no args\" a=0 b=2"},
+		} {
+			log.Log(INFO, tc.msg, tc.args...)
+
+			want, got := tc.tty+"\n", b.String()
+			if _, isTTY := log.Handler().(*TTY); !isTTY {
+				want, got = "level=INFO "+tc.text+"\n", got[strings.Index(got, "level="):]
+			}
+			if got != want {
+				t.Errorf("%s:\n\twant %q\n\tgot  %q", tc.msg, want, got)
+			}
+			b.Reset()
+		}
+	}
+
+	// Errorf consumes an interpolated error
+	cfg.Logger().Errorf("failed: {err}", errors.New("boom"), "n", 1)
+	if want, got := "failed: boom\tn:1\n", b.String(); want != got {
+		t.Errorf("Errorf:\n\twant %q\n\tgot  %q", want, got)
+	}
+}
+
+func TestWrapErrAttrs(t *testing.T) {
+	base := errors.New("base")
+
+	err := WrapErr("{op} failed", base, "op", "read")
+	if err.Error() != "read failed: base" {
+		t.Errorf("message: got %q", err.Error())
+	}
+	if !errors.Is(err, base) || errors.Unwrap(err) != base {
+		t.Errorf("unwrap: %v does not wrap %v", err, base)
+	}
+
+	if _, ok := err.(slog.LogValuer); ok {
+		t.Errorf("%T is a LogValuer", err)
+	}
+	v := errValue(slog.AnyValue(err))
+	want := slog.GroupValue(slog.String("msg", "read failed: base"), slog.String("op", "read"))
+	if !v.Equal(want) {
+		t.Errorf("errValue: want %v, got %v", want, v)
+	}
+
+	// nil error
+	err = WrapErr("{op} failed", nil, "op", "read")
+	if err.Error() != "read failed" || errors.Unwrap(err) != nil {
+		t.Errorf("nil wrap: got %q, %v", err.Error(), errors.Unwrap(err))
+	}
+
+	// no arguments, no structure
+	if _, ok := WrapErr("failed", base).(*attrsError); ok {
+		t.Errorf("no arguments: unexpected attrs")
+	}
+
+	// re-exported by a logf handler
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.Error("", WrapErr("{op} failed", base, "op", "read"))
+	if got := b.String(); got != "read failed: base\terr:{msg:\"read failed: base\" op:read}\n" {
+		t.Errorf("logged: got %q", got)
+	}
+
+	// and by a logf JSON handler, also via WithAttrs
+	b.Reset()
+	jlog := New().Writer(&b).JSON()
+	jlog.With("err", WrapErr("{op} failed", base, "op", "read")).Info("")
+	if got := b.String(); !strings.Contains(got, `"err":{"msg":"read failed: base","op":"read"}`) {
+		t.Errorf("logf JSON: got %q", got)
+	}
+
+	// plain slog handlers see only the message
+	b.Reset()
+	slog.New(slog.NewJSONHandler(&b, nil)).Error("", "err", WrapErr("{op} failed", base, "op", "read"))
+	if got := b.String(); !strings.Contains(got, `"err":"read failed: base"}`) || strings.Contains(got, `"op"`) {
+		t.Errorf("slog JSON: got %q", got)
+	}
+}
@@ -11,48 +11,150 @@ import (
 type handler interface {
 	slog.Handler
 	slog.LogValuer
+	withStore(Store) slog.Handler
 }
 
 type Handler struct {
 	enc   slog.Handler
 	store Store
 
-	label     Attr
+	// the handler before any WithAttrs or WithGroup calls
+	root *Handler
+
+	labels    []Attr
 	replace   replaceFunc
 	addSource bool
+
+	// minimum levels by scope, consulted before the encoder's level
+	scopes *LevelTree
+
+	// joins a new tag to an inherited one, if non-empty
+	tagJoin string
+
+	// the key of label attrs; if empty, no labels are detected
+	tagKey string
+
+	// stack capture
+	addStack   bool
+	stackLevel slog.Level
+
+	ctxAttrs func(context.Context) []Attr
+	observe  func(slog.Level, string, bool)
+	onRecord recordHooks
+
+	// resume a panic after Logger.Recover logs it
+	repanic bool
+
+	// interpolated arguments aren't exported; see [Config.ConsumeInterpolated]
+	consume bool
+
+	// fractional-second digits of times in interpolated messages
+	timePrec int
 }
 
 func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
+	if lvl, ok := h.scopes.level(h.store); ok {
+		return l >= lvl
+	}
 	return h.enc.Enabled(ctx, l)
 }
 
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.ctxAttrs != nil {
+		if as := h.ctxAttrs(ctx); len(as) > 0 {
+			r = r.Clone()
+			r.AddAttrs(as...)
+		}
+	}
+
+	r, keep := h.onRecord.run(ctx, r)
+	if h.observe != nil {
+		defer h.observe(r.Level, recordTag(r, h.labels, h.tagJoin, h.tagKey), !keep)
+	}
+	if !keep {
+		return nil
+	}
+
+	// errors carrying attrs are exported as groups
+	r = expandRecordErrs(r)
+
+	if h.addStack && r.Level >= h.stackLevel {
+		r = r.Clone()
+		r.AddAttrs(slog.Attr{Key: "stack", Value: stackValue(callers(1))})
+	}
 	return h.enc.Handle(ctx, r)
 }
 
-func (h *Handler) WithAttrs(as []Attr) slog.Handler {
-	h2 := &Handler{
-		enc:       h.enc.WithAttrs(as),
-		store:     h.store.WithAttrs(as),
-		replace:   h.replace,
-		addSource: h.addSource,
+// recordHooks run in order on a record, before it's encoded; see [Config.OnRecord]
+type recordHooks []func(context.Context, *slog.Record) bool
+
+// run returns the record as modified by hooks, and whether to keep it. Hooks modify a clone of the record.
+// (The clone escapes to hooks, so the record is passed by value, and nothing escapes without hooks.)
+func (hooks recordHooks) run(ctx context.Context, r slog.Record) (slog.Record, bool) {
+	if len(hooks) == 0 {
+		return r, true
 	}
-	_, h2.label = detectLabel(as, h.label)
 
-	return h2
+	r2 := r.Clone()
+	for _, hook := range hooks {
+		if !hook(ctx, &r2) {
+			return r2, false
+		}
+	}
+	return r2, true
+}
+
+func (h *Handler) WithAttrs(as []Attr) slog.Handler {
+	as = joinTags(as, h.labels, h.tagJoin, h.tagKey)
+
+	h2 := *h
+	h2.enc = h.enc.WithAttrs(expandErrs(as))
+	h2.store = h.store.WithAttrs(as)
+	h2.root = h.rootHandler()
+	_, h2.labels = detectLabel(as, h.labels, h.tagKey)
+
+	return &h2
 }
 
 func (h *Handler) WithGroup(name string) slog.Handler {
-	return &Handler{
-		enc:       h.enc.WithGroup(name),
-		store:     h.store.WithGroup(name),
-		label:     h.label,
-		replace:   h.replace,
-		addSource: h.addSource,
+	if name == "" {
+		return h
 	}
+
+	h2 := *h
+	h2.enc = h.enc.WithGroup(name)
+	h2.store = h.store.WithGroup(name)
+	h2.root = h.rootHandler()
+
+	return &h2
 }
 
 // iterates out through stored handlerFrames, LIFO
 func (h *Handler) LogValue() Value {
 	return h.store.LogValue()
 }
+
+func (h *Handler) rootHandler() *Handler {
+	if h.root == nil {
+		return h
+	}
+	return h.root
+}
+
+// withStore rebuilds the handler from its root, using the given store.
+// Attrs the root's store was seeded with (recovered from a foreign handler)
+// are already held by the root's encoder, and aren't replayed.
+func (h *Handler) withStore(store Store) slog.Handler {
+	root := h.rootHandler()
+
+	// stored tags are already joined
+	unjoined := *root
+	unjoined.tagJoin = ""
+
+	h2 := *store.sansSeed(root.store).replay(&unjoined).(*Handler)
+	h2.store = store
+	h2.root = root
+	h2.labels = h.labels
+	h2.tagJoin = h.tagJoin
+	return &h2
+}
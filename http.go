@@ -0,0 +1,168 @@
+package logf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"log/slog"
+)
+
+// HTTPMiddleware returns middleware deriving a request-scoped [Logger] for each request.
+//
+// The request-scoped logger is based on log.WithGroup("http"), carrying method, path, and request_id attrs.
+// The request_id is taken from an X-Request-Id header, or generated if the header is absent.
+// The logger is stored in the request's context, and is available from [FromContext].
+//
+// When the wrapped handler returns, a summary record is logged with status, bytes, and duration attrs.
+// The summary is tagged "http", and logged at INFO, or at WARN for 4xx statuses, or at ERROR for 5xx statuses.
+func HTTPMiddleware(log Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get("X-Request-Id")
+			if id == "" {
+				id = newRequestID()
+			}
+
+			l := log.WithGroup("http").With(
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_id", id,
+			)
+
+			sw := &statusWriter{ResponseWriter: w}
+			r = r.WithContext(NewContext(r.Context(), l))
+
+			next.ServeHTTP(sw, r)
+
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+
+			level := INFO
+			switch {
+			case sw.status >= 500:
+				level = ERROR
+			case sw.status >= 400:
+				level = WARN
+			}
+
+			if key := loggerTagKey(l); key != "" {
+				l = l.With(key, "http")
+			}
+			l.Log(level, "{http.method} {http.path}",
+				"status", sw.status,
+				"bytes", sw.bytes,
+				"duration", time.Since(start),
+			)
+		})
+	}
+}
+
+// headers included by [RequestAttrs] when no others are given
+var defaultRequestHeaders = []string{"Accept", "Content-Type", "Referer", "X-Forwarded-For", "X-Request-Id"}
+
+// RequestAttrs returns a group, keyed "http", summarizing a request:
+// method, path, host, remote_addr, user_agent, and content_length attrs,
+// and a headers group holding only the named headers.
+// If no headers are named, Accept, Content-Type, Referer, X-Forwarded-For, and X-Request-Id are included.
+// Credential-bearing headers like Authorization or Cookie are only included if named.
+//
+// The request body is not read. Within a handler wrapped by [HTTPMiddleware],
+// the request-scoped logger already carries method and path attrs;
+// RequestAttrs complements it with the remaining details.
+func RequestAttrs(r *http.Request, headers ...string) Attr {
+	if len(headers) == 0 {
+		headers = defaultRequestHeaders
+	}
+
+	var hs []Attr
+	for _, name := range headers {
+		if vs := r.Header.Values(name); len(vs) > 0 {
+			hs = append(hs, valuesAttr(http.CanonicalHeaderKey(name), vs))
+		}
+	}
+
+	as := []Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("host", r.Host),
+		slog.String("remote_addr", r.RemoteAddr),
+		slog.String("user_agent", r.UserAgent()),
+		slog.Int64("content_length", r.ContentLength),
+	}
+	if len(hs) > 0 {
+		as = append(as, slog.Attr{Key: "headers", Value: slog.GroupValue(hs...)})
+	}
+
+	return slog.Attr{Key: "http", Value: slog.GroupValue(as...)}
+}
+
+// QueryAttrs returns an Attr for each key of v, in sorted order.
+// A key with several values is a group, keyed by index.
+func QueryAttrs(v url.Values) []Attr {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	as := make([]Attr, 0, len(keys))
+	for _, k := range keys {
+		as = append(as, valuesAttr(k, v[k]))
+	}
+	return as
+}
+
+// a single value is a string, and several values are a group keyed by index
+func valuesAttr(key string, vs []string) Attr {
+	if len(vs) == 1 {
+		return slog.String(key, vs[0])
+	}
+
+	as := make([]Attr, 0, len(vs))
+	for i, v := range vs {
+		as = append(as, slog.String(strconv.Itoa(i), v))
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(as...)}
+}
+
+func newRequestID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// statusWriter records the status and size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap allows an [http.ResponseController] to reach the underlying [http.ResponseWriter].
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
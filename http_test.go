@@ -0,0 +1,111 @@
+package logf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("level", "tags", "message", "\t", "attrs").
+		ShowLevel(LevelText).
+		ShowColor(false).
+		ForceTTY(true).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == "duration" {
+				return Attr{}
+			}
+			return a
+		}).
+		Logger()
+
+	mw := HTTPMiddleware(log)
+
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"/", "   INFO    http GET /\thttp:{method:GET path:/ request_id:test status:200 bytes:2}\n"},
+		{"/missing", "   WARN    http GET /missing\thttp:{method:GET path:/missing request_id:test status:404 bytes:19}\n"},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("X-Request-Id", "test")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if tc.want != b.String() {
+			t.Errorf("\n\twant %q\n\tgot  %q", tc.want, b.String())
+		}
+		b.Reset()
+	}
+}
+
+func TestHTTPMiddlewareContext(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	h := HTTPMiddleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Infof("handling {http.request_id}")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "test")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := "handling test\nGET /\n"
+	if want != b.String() {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}
+
+func TestRequestAttrs(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://example.com/upload?tag=a&tag=b&x=1", strings.NewReader("body"))
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Content-Type", "text/plain")
+	r.Header.Add("Accept", "text/plain")
+	r.Header.Add("Accept", "text/html")
+
+	a := RequestAttrs(r)
+	want := "http=[method=POST path=/upload host=example.com remote_addr=192.0.2.1:1234 user_agent=test content_length=4 headers=[Accept=[0=text/plain 1=text/html] Content-Type=text/plain]]"
+	if a.String() != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, a.String())
+	}
+
+	// allowlist
+	a = RequestAttrs(r, "authorization")
+	if !strings.Contains(a.String(), "headers=[Authorization=Bearer secret]") {
+		t.Errorf("allowlist: got %s", a.String())
+	}
+
+	// the body is untouched
+	if body, _ := io.ReadAll(r.Body); string(body) != "body" {
+		t.Errorf("body: got %q", body)
+	}
+
+	as := QueryAttrs(r.URL.Query())
+	if got := fmt.Sprint(as); got != "[tag=[0=a 1=b] x=1]" {
+		t.Errorf("query: got %s", got)
+	}
+}
@@ -2,10 +2,15 @@ package logf
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"unicode/utf8"
 
 	"log/slog"
+	"slices"
 )
 
 var missingMatch = slog.StringValue(`!missing-match`)
@@ -13,6 +18,24 @@ var missingMatch = slog.StringValue(`!missing-match`)
 // SCAN
 
 func (s *splicer) scanMessage(msg string) (unkeyed int) {
+	if strings.IndexByte(msg, '{') < 0 {
+		return
+	}
+
+	keys, cached := templates.get(msg)
+	if !cached {
+		keys = s.scanKeys(msg)
+		templates.put(msg, keys)
+	}
+
+	for _, key := range keys {
+		s.dictAdd(key)
+	}
+	return
+}
+
+// returns the unescaped keys of a message's clips
+func (s *splicer) scanKeys(msg string) (keys []string) {
 	var clip string
 	var found bool
 	for {
@@ -23,14 +46,88 @@ func (s *splicer) scanMessage(msg string) (unkeyed int) {
 
 		key := s.scanClip(clip)
 		if len(key) > 0 {
-			key = s.scanUnescapeKey(key)
-			s.dict[key] = missingMatch
+			keys = append(keys, s.scanUnescapeKey(key))
 		}
 	}
-
 	return
 }
 
+// TEMPLATE CACHE
+
+// the number of messages held by the template cache
+const templateCacheSize = 256
+
+// templates maps messages to the keys scanned from them, shared by splicers
+var templates templateCache
+
+// DisableTemplateCache stops caching the keys scanned from interpolated messages.
+// Caching benefits programs logging a fixed set of messages; programs logging unbounded, dynamic messages may prefer to disable it.
+func DisableTemplateCache() {
+	templates.disable()
+}
+
+// templateCache approximates an LRU cache with two generations of entries.
+// When the current generation is full, it becomes the previous generation, and a hit in the previous generation is promoted.
+// At most templateCacheSize entries are held.
+type templateCache struct {
+	mu         sync.RWMutex
+	curr, prev map[string][]string
+	disabled   atomic.Bool
+}
+
+func (tc *templateCache) get(msg string) ([]string, bool) {
+	if tc.disabled.Load() {
+		return nil, false
+	}
+
+	tc.mu.RLock()
+	keys, ok := tc.curr[msg]
+	promote := false
+	if !ok {
+		keys, ok = tc.prev[msg]
+		promote = ok
+	}
+	tc.mu.RUnlock()
+
+	if promote {
+		tc.put(msg, keys)
+	}
+	return keys, ok
+}
+
+func (tc *templateCache) put(msg string, keys []string) {
+	if tc.disabled.Load() {
+		return
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	switch {
+	case tc.curr == nil:
+		tc.curr = make(map[string][]string)
+	case len(tc.curr) >= templateCacheSize/2:
+		tc.prev = tc.curr
+		tc.curr = make(map[string][]string)
+	}
+	tc.curr[msg] = keys
+}
+
+func (tc *templateCache) disable() {
+	tc.disabled.Store(true)
+
+	tc.mu.Lock()
+	tc.curr, tc.prev = nil, nil
+	tc.mu.Unlock()
+}
+
+// the count of cached messages
+func (tc *templateCache) len() int {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	return len(tc.curr) + len(tc.prev)
+}
+
 func scanNext(msg string) (tail, clip string, found bool) {
 	var lpos, rpos int
 
@@ -66,35 +163,64 @@ func scanUntilRune(msg string, sep rune) (tail string, n int) {
 	return "", -1
 }
 
-// count unkeyed
+// returns the (still escaped) key of a clip, or "" if the clip is unkeyed
 func (s *splicer) scanClip(clip string) (key string) {
 	n := strings.LastIndexByte(clip, ':')
 
+	switch {
 	// no colon, no verb
-	if n < 0 {
-		// the unique string that is unkeyed with no verb -> unkeyed
-		if clip == "{}" {
-			return ""
-		}
-		// otherwise -> keyed
-		return clip
-	}
+	// -> clip is key
+	case n < 0:
+		key = clip
 
 	// colon in 0-pos can't be escaped
 	// -> unkeyed
-	if n == 0 {
+	case n == 0:
 		return ""
-	}
 
 	// last colon escaped
 	// -> clip is key
-	if clip[n-1] == '\\' {
-		return s.scanUnescapeKey(clip)
-	}
+	case clip[n-1] == '\\':
+		key = clip
 
 	// last colon unescaped
 	// -> clip up to n is key
-	return s.scanUnescapeKey(clip[:n])
+	default:
+		key = clip[:n]
+	}
+
+	// a fallback follows the first unescaped pipe
+	if n := scanPipe(key); n >= 0 {
+		key = key[:n]
+	}
+
+	// the unique string that is unkeyed with no verb -> unkeyed
+	if key == "{}" {
+		return ""
+	}
+
+	// an index -> positional
+	if _, ok := ipolIndex([]byte(key)); ok {
+		return ""
+	}
+
+	return key
+}
+
+// finds the first unescaped '|' in a clip
+func scanPipe[T string | []byte](clip T) int {
+	var esc bool
+	for i := 0; i < len(clip); i++ {
+		switch {
+		case esc:
+			esc = false
+		case clip[i] == '\\':
+			esc = true
+		case clip[i] == '|':
+			return i
+		}
+	}
+	return -1
 }
 
 // TODO: micro-optimizing allocs etc. here could be possible.
@@ -142,6 +268,10 @@ func (s *splicer) ipolNext(msg string) (tail string, clip []byte, found bool) {
 	}
 
 	if msg, rpos = s.ipolUntilRune(msg, '}'); rpos < 0 {
+		// unterminated clip: restore the left bracket, keeping the text after it
+		s.text = append(s.text, 0)
+		copy(s.text[lpos+1:], s.text[lpos:])
+		s.text[lpos] = '{'
 		return "", nil, false
 	}
 
@@ -163,6 +293,11 @@ func (s *splicer) ipolUntilRune(msg string, sep rune) (tail string, n int) {
 				s.WriteString(`\:`)
 				continue
 			}
+			// special case: preserve the `\` from escaping a pipe in a clip
+			if r == '|' && sep == '}' {
+				s.WriteString(`\|`)
+				continue
+			}
 			fallthrough
 		default:
 			s.writeRune(r)
@@ -172,85 +307,170 @@ func (s *splicer) ipolUntilRune(msg string, sep rune) (tail string, n int) {
 			return msg[n+1:], len(s.text)
 		}
 	}
+
+	// a trailing `\` escapes nothing, and is kept
+	if esc {
+		s.WriteByte('\\')
+	}
 	return "", -1
 }
 
 func (s *splicer) ipolAttr(clip []byte) {
-	key, verb := ipolClip(clip)
+	if len(s.ipolPen) > 0 {
+		// clip aliases text that is overwritten by writes
+		clip = bytes.Clone(clip)
+		s.WriteString(string(s.ipolPen))
+		s.ipolClip(clip)
+		s.WriteString("\x1b[0m")
+		s.WriteString(string(s.ipolRestore))
+		return
+	}
+
+	mark := len(s.text)
+	s.ipolClip(clip)
+	if s.markSpans {
+		s.spans = append(s.spans, mark, len(s.text))
+	}
+}
+
+func (s *splicer) ipolClip(clip []byte) {
+	key, fallback, verb := ipolClip(clip)
 
 	if len(key) == 0 {
-		s.ipolUnkeyed(verb)
+		s.ipolUnkeyed(fallback, verb)
+	} else if string(key) == "*" {
+		s.ipolStar(verb)
+	} else if i, ok := ipolIndex(key); ok {
+		s.ipolIndexed(i, fallback, verb)
 	} else {
-		s.ipolKeyed(key, verb)
+		s.ipolKeyed(key, fallback, verb)
 	}
 }
 
-func (s *splicer) ipolUnkeyed(verb []byte) {
+// interpolates every scoped store and exported attr, without advancing the unkeyed cursor
+func (s *splicer) ipolStar(verb []byte) {
+	// verb aliases text that is overwritten by writes
+	verb = bytes.Clone(verb)
+
+	var sep bool
+	s.writeStar("", s.star, verb, &sep)
+}
+
+// writes the fallback of a clip, if there is one, or the given sentinel
+func (s *splicer) ipolMissing(fallback []byte, sentinel string) {
+	if fallback != nil {
+		s.Write(fallback)
+	} else {
+		s.WriteString(sentinel)
+	}
+}
+
+// interpolates the ith unkeyed attr, without advancing the unkeyed cursor
+func (s *splicer) ipolIndexed(i int, fallback, verb []byte) {
+	if i >= len(s.export) {
+		if fallback == nil && s.audit != nil {
+			s.audit.fault(fmt.Sprintf("no argument for {%d}", i))
+		}
+		s.ipolMissing(fallback, missingAttr)
+		return
+	}
+
+	if s.audit != nil {
+		s.audit.indexed = append(s.audit.indexed, i)
+	}
+	s.WriteValue(s.export[i].Value, verb)
+}
+
+// parses a key consisting only of decimal digits as an index
+func ipolIndex(key []byte) (i int, ok bool) {
+	if len(key) == 0 || len(key) > 9 {
+		return 0, false
+	}
+
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		i = i*10 + int(c-'0')
+	}
+	return i, true
+}
+
+func (s *splicer) ipolUnkeyed(fallback, verb []byte) {
 	var a Attr
 
 	if s.iUnkeyed < len(s.export) {
 		a = s.export[s.iUnkeyed]
 		s.iUnkeyed++
 	} else {
-		s.WriteString(missingAttr)
+		if fallback == nil && s.audit != nil {
+			s.audit.fault(fmt.Sprintf("no argument for unkeyed {} number %d", s.iUnkeyed+1))
+		}
+		s.ipolMissing(fallback, missingAttr)
 		return
 	}
 
-	// if len(s.list) < s.unkeyed {
-	// 	a = s.export[]
-	// }
-
-	// if len(s.list) > 0 {
-	// 	a = s.list[0]
-	// 	s.list = s.list[1:]
-	// } else {
-	// 	s.WriteString(missingAttr)
-	// 	return
-	// }
-
 	s.WriteValue(a.Value, verb)
 }
 
-func (s *splicer) ipolKeyed(key, verb []byte) {
-	v, ok := s.dict[string(key)]
+func (s *splicer) ipolKeyed(key, fallback, verb []byte) {
+	v, ok := s.dictGet(key)
 
 	// should be unreachable, but I kept reaching it
 	if !ok {
-		s.WriteString(missingAttr)
+		if fallback == nil && s.audit != nil {
+			s.audit.fault(fmt.Sprintf("unmatched key {%s}", key))
+		}
+		s.ipolMissing(fallback, missingAttr)
 		return
 	}
 
+	if v.Equal(missingMatch) {
+		if fallback != nil {
+			s.Write(fallback)
+			return
+		}
+		if s.audit != nil {
+			s.audit.fault(fmt.Sprintf("unmatched key {%s}", key))
+		}
+	}
+
 	s.WriteValue(v, verb)
 }
 
-func ipolClip(clip []byte) (key, verb []byte) {
+// splits a clip into key, fallback, and verb, as in `{key|fallback:verb}`.
+// A nil fallback means the clip has no fallback.
+func ipolClip(clip []byte) (key, fallback, verb []byte) {
 	n := bytes.LastIndexByte(clip, ':')
 
+	switch {
 	// no colon found
 	// -> no verb
-	if n < 0 {
+	case n < 0:
 		key, verb = clip, nil
-		return
-	}
 
 	// colon in 0-pos can't be escaped
 	// -> no key
-	if n == 0 {
-		key, verb = nil, clip[1:]
-		return
-	}
+	case n == 0:
+		return nil, nil, clip[1:]
 
 	// last colon is escaped
 	// -> no verb
-	if clip[n-1] == '\\' {
-		key, verb = ipolUnescapeKey(clip), nil
-		return
-	}
+	case clip[n-1] == '\\':
+		key, verb = clip, nil
 
 	// colon found at n
 	// -> key up to n, verb after n
-	key, verb = ipolUnescapeKey(clip[:n]), clip[n+1:]
-	return
+	default:
+		key, verb = clip[:n], clip[n+1:]
+	}
+
+	// fallback after the first unescaped pipe
+	if n := scanPipe(key); n >= 0 {
+		key, fallback = key[:n], ipolUnescapeKey(key[n+1:])
+	}
+
+	return ipolUnescapeKey(key), fallback, verb
 }
 
 func ipolUnescapeKey(key []byte) []byte {
@@ -271,3 +491,79 @@ func ipolUnescapeKey(key []byte) []byte {
 	// key, sans-escapes, is of length n
 	return key[:n]
 }
+
+// AUDIT
+
+// ipolAudit records faults found while interpolating, for TryFmt and friends
+type ipolAudit struct {
+	faults  []string
+	indexed []int
+}
+
+func (a *ipolAudit) fault(msg string) {
+	a.faults = append(a.faults, msg)
+}
+
+// auditErr reports recorded faults, malformed arguments, and unused arguments as one error
+func (s *splicer) auditErr(store Store) error {
+	faults := s.audit.faults
+	scope := store.scopeKey(len(store.scope))
+
+	for i, a := range s.export {
+		switch {
+		case a.Key == missingKey:
+			faults = append(faults, fmt.Sprintf("argument %d: no key for value %v", i, a.Value))
+		case a.Value.Kind() == slog.KindString && a.Value.String() == missingArg:
+			faults = append(faults, fmt.Sprintf("argument %d: no value for key %q", i, a.Key))
+		case !s.auditUsed(i, a, scope):
+			faults = append(faults, fmt.Sprintf("argument %d: unused %s=%v", i, a.Key, a.Value))
+		}
+	}
+
+	if len(faults) == 0 {
+		return nil
+	}
+	return errors.New("logf: " + strings.Join(faults, "; "))
+}
+
+// reports whether the ith exported attr was interpolated
+func (s *splicer) auditUsed(i int, a Attr, scope string) bool {
+	if i < s.iUnkeyed || slices.Contains(s.audit.indexed, i) || s.dictHas("*") {
+		return true
+	}
+	return s.dictRefers(a.Key) || s.dictRefers(scope+a.Key)
+}
+
+// unconsumed returns the arguments not interpolated, as audited, as a list of attrs.
+// If every argument is unused, args is returned as given.
+func (s *splicer) unconsumed(store Store, args []any) []any {
+	scope := store.scopeKey(len(store.scope))
+
+	var kept []any
+	var consumed bool
+	for i, a := range Attrs(args...) {
+		if s.auditUsed(i, s.export[i], scope) {
+			consumed = true
+			continue
+		}
+		kept = append(kept, a)
+	}
+
+	if !consumed {
+		return args
+	}
+	return kept
+}
+
+// reports whether the dict holds key, or a key within a group at key
+func (s *splicer) dictRefers(key string) bool {
+	if s.dictHas(key) {
+		return true
+	}
+	for dictKey := range s.dict {
+		if len(dictKey) > len(key) && dictKey[len(key)] == '.' && dictKey[:len(key)] == key {
+			return true
+		}
+	}
+	return false
+}
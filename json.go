@@ -0,0 +1,463 @@
+package logf
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"log/slog"
+)
+
+// JSON ENCODING
+
+// writeValueJSON writes a compact JSON encoding of the value.
+// Groups are encoded as objects, LogValuers are resolved, and durations are encoded as nanoseconds.
+func (s *splicer) writeValueJSON(v slog.Value) {
+	s.text = appendJSONValue(s.text, v, s.reveal, s.timePrec)
+}
+
+// appends a JSON encoding of v, with times at the given precision, or in [time.RFC3339Nano] format given a negative precision
+func appendJSONValue(buf []byte, v slog.Value, reveal bool, prec int) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		buf = appendJSONString(buf, v.String())
+	case slog.KindBool:
+		buf = strconv.AppendBool(buf, v.Bool())
+	case slog.KindFloat64:
+		f := v.Float64()
+		// JSON has no representation for these
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			buf = appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, 64))
+		} else {
+			buf = strconv.AppendFloat(buf, f, 'g', -1, 64)
+		}
+	case slog.KindInt64:
+		buf = strconv.AppendInt(buf, v.Int64(), 10)
+	case slog.KindUint64:
+		buf = strconv.AppendUint(buf, v.Uint64(), 10)
+	case slog.KindDuration:
+		buf = strconv.AppendInt(buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		buf = append(buf, '"')
+		if prec < 0 {
+			buf = v.Time().AppendFormat(buf, time.RFC3339Nano)
+		} else {
+			buf = appendTimeRFC3339(buf, v.Time(), prec)
+		}
+		buf = append(buf, '"')
+	case slog.KindGroup:
+		buf = appendJSONGroup(buf, v.Group(), reveal, prec)
+	case slog.KindLogValuer:
+		buf = appendJSONValue(buf, resolveSecret(v, reveal), reveal, prec)
+	case slog.KindAny:
+		buf = appendJSONAny(buf, v.Any())
+	default:
+		panic(corruptKind)
+	}
+	return buf
+}
+
+func appendJSONGroup(buf []byte, as []Attr, reveal bool, prec int) []byte {
+	buf = append(buf, '{')
+	var sep bool
+	for _, a := range as {
+		buf = appendJSONAttr(buf, a, &sep, reveal, prec)
+	}
+	return append(buf, '}')
+}
+
+// appends an attr as a [slog.JSONHandler] does, at any depth:
+// attrs with an empty key and a nil value are elided, as are groups without attrs,
+// and the attrs of a group with an empty key are inlined.
+func appendJSONAttr(buf []byte, a Attr, sep *bool, reveal bool, prec int) []byte {
+	v := resolveSecret(a.Value, reveal)
+
+	if v.Kind() != slog.KindGroup {
+		if a.Key == "" && v.Kind() == slog.KindAny && v.Any() == nil {
+			return buf
+		}
+		buf = appendJSONKey(buf, a.Key, sep)
+		return appendJSONValue(buf, v, reveal, prec)
+	}
+
+	as := v.Group()
+	if len(as) == 0 {
+		return buf
+	}
+
+	mark, markSep := len(buf), *sep
+	if a.Key != "" {
+		buf = appendJSONKey(buf, a.Key, sep)
+		buf = append(buf, '{')
+		*sep = false
+	}
+
+	open := len(buf)
+	for _, a := range as {
+		buf = appendJSONAttr(buf, a, sep, reveal, prec)
+	}
+	if len(buf) == open {
+		*sep = markSep
+		return buf[:mark]
+	}
+
+	if a.Key != "" {
+		buf = append(buf, '}')
+		*sep = true
+	}
+	return buf
+}
+
+func appendJSONAny(buf []byte, x any) []byte {
+	if x == nil {
+		return append(buf, "null"...)
+	}
+
+	// errors without a MarshalJSON method encode as their message
+	if _, isMarshaler := x.(json.Marshaler); !isMarshaler {
+		if err, isErr := x.(error); isErr {
+			return appendJSONString(buf, err.Error())
+		}
+	}
+
+	b, err := json.Marshal(x)
+	if err != nil {
+		return appendJSONString(buf, "!"+err.Error())
+	}
+	return append(buf, b...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends a quoted, escaped JSON string.
+func appendJSONString(buf []byte, str string) []byte {
+	buf = append(buf, '"')
+
+	start := 0
+	for i := 0; i < len(str); {
+		c := str[i]
+
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+
+			buf = append(buf, str[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, str[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		// line and paragraph separators break JavaScript parsers
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, str[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	buf = append(buf, str[start:]...)
+	return append(buf, '"')
+}
+
+// JSON HANDLER
+
+// jsonHandler is a native JSON encoder, with output in the format of a [slog.JSONHandler]:
+// built-in time, level, source, and msg fields, followed by attrs, with groups as nested objects.
+// Attrs given to WithAttrs are encoded once, when given.
+type jsonHandler struct {
+	w         *ttySyncWriter
+	ref       slog.Leveler
+	addSource bool
+	replace   replaceFunc
+	reveal    bool
+
+	// fractional-second digits of the record time, and of time values, which are encoded in
+	// RFC3339Nano format if attrPrec is negative
+	timePrec int
+	attrPrec int
+
+	// renames built-in fields, if non-nil
+	keys *builtinKeys
+
+	// preformatted attrs, without a leading comma, and the groups opened in them
+	pre    string
+	opened int
+
+	// whether an attr following pre is preceded by a comma
+	sep bool
+
+	// groups given to WithGroup
+	scope []string
+
+	// if set, attrs given to WithAttrs are held rather than preformatted, so that repeated keys are dropped when encoding;
+	// frames[i] holds the replaced attrs given at scope[:i]
+	dedup  bool
+	frames [][]Attr
+}
+
+func newJSONHandler(w *ttySyncWriter, ref slog.Leveler, addSource bool, replace replaceFunc, reveal bool) *jsonHandler {
+	return &jsonHandler{
+		w:         w,
+		ref:       ref,
+		addSource: addSource,
+		replace:   replace,
+		reveal:    reveal,
+		timePrec:  timeMillis,
+		attrPrec:  -1,
+	}
+}
+
+func (h *jsonHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.ref.Level()
+}
+
+func (h *jsonHandler) WithAttrs(as []Attr) slog.Handler {
+	if len(as) == 0 {
+		return h
+	}
+
+	if h.dedup {
+		h2 := *h
+		h2.frames = h.heldFrames()
+		depth := len(h.scope)
+		for _, a := range as {
+			h2.frames[depth] = appendHeld(h2.frames[depth], replaceAttr(h.scope, a, h.replace))
+		}
+		return &h2
+	}
+
+	s := newSplicer()
+	defer s.free()
+
+	h2 := *h
+	sep := h.sep
+	s.text = h.appendScope(s.text, as, &sep, h.opened)
+	if len(s.text) == 0 {
+		return h
+	}
+
+	h2.pre = h.pre + s.line()
+	h2.opened = len(h.scope)
+	h2.sep = sep
+	return &h2
+}
+
+func (h *jsonHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.scope = concatOne(h.scope, name)
+	return &h2
+}
+
+func (h *jsonHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := newSplicer()
+	defer s.free()
+
+	buf := append(s.text, '{')
+	sep := false
+
+	// built-in fields
+	if !r.Time.IsZero() {
+		buf = h.appendBuiltin(buf, slog.Time(slog.TimeKey, r.Time), &sep)
+	}
+	buf = h.appendBuiltin(buf, slog.Any(slog.LevelKey, r.Level), &sep)
+	if h.addSource && r.PC != 0 {
+		buf = h.appendBuiltin(buf, slog.Any(slog.SourceKey, source(r)), &sep)
+	}
+	buf = h.appendBuiltin(buf, slog.String(slog.MessageKey, r.Message), &sep)
+
+	// preformatted attrs
+	opened := h.opened
+	if len(h.pre) > 0 {
+		if sep {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, h.pre...)
+		sep = h.sep
+	}
+
+	// record attrs
+	if h.dedup {
+		buf = h.appendDedup(buf, r, &sep)
+	} else {
+		buf, opened = h.appendRecord(buf, r, &sep, opened)
+	}
+
+	for i := 0; i < opened; i++ {
+		buf = append(buf, '}')
+	}
+	buf = append(buf, '}', '\n')
+
+	s.text = buf
+	_, err := h.w.Write(buf)
+	return err
+}
+
+// appends record attrs, first opening any groups of the scope past opened; returns the count of open groups
+func (h *jsonHandler) appendRecord(buf []byte, r slog.Record, sep *bool, opened int) ([]byte, int) {
+	r.Attrs(func(a Attr) bool {
+		mark, markSep := len(buf), *sep
+		if opened < len(h.scope) {
+			buf = h.appendOpen(buf, opened, sep)
+		}
+
+		open := len(buf)
+		buf = h.appendAttr(buf, replaceAttr(h.scope, a, h.replace), sep)
+		if len(buf) == open {
+			buf, *sep = buf[:mark], markSep
+		} else {
+			opened = len(h.scope)
+		}
+		return true
+	})
+	return buf, opened
+}
+
+// returns a copy of held frames, with a frame for each depth of the scope
+func (h *jsonHandler) heldFrames() [][]Attr {
+	frames := make([][]Attr, len(h.scope)+1)
+	for i, as := range h.frames {
+		frames[i] = slices.Clone(as)
+	}
+	return frames
+}
+
+// appends a replaced attr to held attrs, unless it was elided
+func appendHeld(as []Attr, a Attr) []Attr {
+	if a.Key == "" && a.Value.Kind() != slog.KindGroup {
+		return as
+	}
+	return append(as, a)
+}
+
+// appends held attrs and record attrs, nested in the groups of their scopes, less repeated keys
+func (h *jsonHandler) appendDedup(buf []byte, r slog.Record, sep *bool) []byte {
+	frames := h.heldFrames()
+	depth := len(h.scope)
+	r.Attrs(func(a Attr) bool {
+		frames[depth] = appendHeld(frames[depth], replaceAttr(h.scope, a, h.replace))
+		return true
+	})
+
+	for _, a := range nestFrames(h.scope, frames, true) {
+		buf = h.appendAttr(buf, a, sep)
+	}
+	return buf
+}
+
+// appends attrs, first opening any groups of the scope past opened.
+// If no attr is appended, neither are groups opened.
+func (h *jsonHandler) appendScope(buf []byte, as []Attr, sep *bool, opened int) []byte {
+	mark, markSep := len(buf), *sep
+	buf = h.appendOpen(buf, opened, sep)
+
+	open := len(buf)
+	for _, a := range as {
+		buf = h.appendAttr(buf, replaceAttr(h.scope, a, h.replace), sep)
+	}
+
+	if len(buf) == open {
+		*sep = markSep
+		return buf[:mark]
+	}
+	return buf
+}
+
+// appends the opening of groups of the scope past opened
+func (h *jsonHandler) appendOpen(buf []byte, opened int, sep *bool) []byte {
+	for _, name := range h.scope[opened:] {
+		buf = appendJSONKey(buf, name, sep)
+		buf = append(buf, '{')
+		*sep = false
+	}
+	return buf
+}
+
+// appends a built-in field, after replacement
+func (h *jsonHandler) appendBuiltin(buf []byte, a Attr, sep *bool) []byte {
+	if h.replace != nil {
+		a = h.replace(nil, a)
+	}
+	if h.keys != nil {
+		a = h.keys.rename(a)
+	}
+	if a.Key == "" {
+		return buf
+	}
+
+	switch v := a.Value; v.Kind() {
+	case slog.KindTime:
+		buf = appendJSONKey(buf, a.Key, sep)
+		buf = append(buf, '"')
+		buf = appendTimeRFC3339(buf, v.Time(), h.timePrec)
+		return append(buf, '"')
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case slog.Level:
+			buf = appendJSONKey(buf, a.Key, sep)
+			return appendJSONString(buf, levelName(x))
+		case *slog.Source:
+			buf = appendJSONKey(buf, a.Key, sep)
+			return appendJSONSource(buf, x)
+		}
+	}
+	return h.appendAttr(buf, a, sep)
+}
+
+func (h *jsonHandler) appendAttr(buf []byte, a Attr, sep *bool) []byte {
+	return appendJSONAttr(buf, a, sep, h.reveal, h.attrPrec)
+}
+
+func appendJSONKey(buf []byte, key string, sep *bool) []byte {
+	if *sep {
+		buf = append(buf, ',')
+	}
+	*sep = true
+
+	buf = appendJSONString(buf, key)
+	return append(buf, ':')
+}
+
+func appendJSONSource(buf []byte, src *slog.Source) []byte {
+	buf = append(buf, `{"function":`...)
+	buf = appendJSONString(buf, src.Function)
+	buf = append(buf, `,"file":`...)
+	buf = appendJSONString(buf, src.File)
+	buf = append(buf, `,"line":`...)
+	buf = strconv.AppendInt(buf, int64(src.Line), 10)
+	return append(buf, '}')
+}
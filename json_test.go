@@ -0,0 +1,352 @@
+package logf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+func TestNativeJSON(t *testing.T) {
+	var native, std bytes.Buffer
+
+	replace := func(scope []string, a Attr) Attr {
+		if a.Key == "secret" {
+			a.Value = slog.StringValue("redacted")
+		}
+		return a
+	}
+
+	nativeLog := New().
+		Writer(&native).
+		AddSource(true).
+		ReplaceFunc(replace).
+		NativeJSON(true).
+		JSON().
+		Handler()
+
+	stdLog := slog.NewJSONHandler(&std, &slog.HandlerOptions{
+		AddSource:   true,
+		ReplaceAttr: replace,
+	})
+
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	tm := time.Date(2023, time.March, 1, 2, 3, 4, 5e6, time.FixedZone("", -8*3600))
+
+	for _, tc := range []struct {
+		name   string
+		handle func(h slog.Handler) slog.Handler
+		attrs  []Attr
+	}{
+		{"empty", nil, nil},
+		{"kinds", nil, Attrs(
+			"string", "a \"quoted\"\n line",
+			"int", -1,
+			"uint", uint64(2),
+			"float", 1.5,
+			"bool", true,
+			"duration", time.Second,
+			"time", tm,
+			"err", errors.New("fail"),
+			"nil", nil,
+			"slice", []int{1, 2},
+		)},
+		{"groups", nil, Attrs(
+			Group("g", "a", 1, Group("h", "b", 2)),
+			Group("empty"),
+			Group("", "inline", 3),
+		)},
+		{"empty keys", nil, Attrs(
+			"", 1,
+			Group("g", "", 2, Group("", "inline", 3)),
+			slog.Any("", nil),
+		)},
+		{"empty nested groups", nil, Attrs(
+			Group("g", Group("h")),
+			Group("i", Group("j", Group("k")), "a", 1),
+			Group("", Group("l")),
+		)},
+		{"nanoseconds", nil, Attrs("time", tm.Add(123456789), "zero", time.Time{})},
+		{"replace", nil, Attrs("secret", 1, Group("g", "secret", 2))},
+		{"with", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs(Attrs("a", 1))
+		}, Attrs("b", 2)},
+		{"with group", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs(Attrs("a", 1)).WithGroup("g").WithAttrs(Attrs("b", 2)).WithGroup("h")
+		}, Attrs("c", 3)},
+		{"empty group", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs(Attrs("a", 1)).WithGroup("g").WithGroup("h")
+		}, nil},
+		{"partial group", func(h slog.Handler) slog.Handler {
+			return h.WithGroup("g").WithAttrs(Attrs("a", 1)).WithGroup("h")
+		}, nil},
+	} {
+		r := slog.NewRecord(tm, INFO+1, "message", pcs[0])
+		r.AddAttrs(tc.attrs...)
+
+		for _, h := range []slog.Handler{nativeLog, stdLog} {
+			if tc.handle != nil {
+				h = tc.handle(h)
+			}
+			h.Handle(context.Background(), r)
+		}
+
+		if native.String() != std.String() {
+			t.Errorf("%s\n\tnative %s\n\tslog   %s", tc.name, native.String(), std.String())
+		}
+		native.Reset()
+		std.Reset()
+	}
+
+	// built-ins are replaced, and omitted with an empty key
+	log := New().
+		Writer(&native).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == slog.TimeKey {
+				return Attr{}
+			}
+			return a
+		}).
+		NativeJSON(true).
+		JSON()
+
+	log.WithGroup("g").Info("ok", "a", 1)
+	if want, got := `{"level":"INFO","msg":"ok","g":{"a":1}}`+"\n", native.String(); want != got {
+		t.Errorf("\n\twant %s\n\tgot  %s", want, got)
+	}
+	native.Reset()
+
+	// secrets
+	log = New().
+		Writer(&native).
+		NativeJSON(true).
+		RevealSecrets(true).
+		JSON()
+
+	log.Info("ok", "secret", Redact("hunter2"))
+	if !strings.Contains(native.String(), `"secret":"hunter2"`) {
+		t.Errorf("revealed secret: %s", native.String())
+	}
+}
+
+func TestDedupKeys(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := func(dedup bool) *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("message", "\t", "attrs").
+			ShowColor(false).
+			ForceTTY(true).
+			NativeJSON(true).
+			DedupKeys(dedup).
+			ReplaceFunc(func(scope []string, a Attr) Attr {
+				if a.Key == slog.TimeKey {
+					return Attr{}
+				}
+				return a
+			})
+	}
+
+	for _, tc := range []struct {
+		log       func(Logger)
+		json, tty string
+	}{
+		{
+			func(log Logger) { log.With("secret", 1).Info("ok", "secret", 2) },
+			`{"level":"INFO","msg":"ok","secret":2}`,
+			"ok\tsecret:2",
+		},
+		{
+			func(log Logger) { log.With("a", 1, "b", 2).Info("ok", "a", 3) },
+			`{"level":"INFO","msg":"ok","b":2,"a":3}`,
+			"ok\tb:2 a:3",
+		},
+		{
+			func(log Logger) {
+				log.With("a", 1).WithGroup("g").With("b", 1).With("c", 0).Info("ok", "b", 2, Group("h", "c", 1, "c", 2))
+			},
+			`{"level":"INFO","msg":"ok","a":1,"g":{"c":0,"b":2,"h":{"c":2}}}`,
+			"ok\ta:1 g:{c:0 b:2 h:{c:2}}",
+		},
+		{
+			// interpolation is unaffected
+			func(log Logger) { log.With("a", 1).Infof("{a}", "a", 2) },
+			`{"level":"INFO","msg":"2","a":2}`,
+			"2\ta:2",
+		},
+	} {
+		tc.log(cfg(true).JSON())
+		if got := strings.TrimSuffix(b.String(), "\n"); got != tc.json {
+			t.Errorf("json\n\twant %s\n\tgot  %s", tc.json, got)
+		}
+		b.Reset()
+
+		tc.log(cfg(true).Logger())
+		if got := strings.TrimSuffix(b.String(), "\n"); got != tc.tty {
+			t.Errorf("tty\n\twant %q\n\tgot  %q", tc.tty, got)
+		}
+		b.Reset()
+	}
+
+	// without repeated keys, output is unchanged
+	log := func(log Logger) {
+		log.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").Info("ok", "c", 3, Group("i", "d", 4))
+	}
+	for _, logger := range []func(*Config) Logger{(*Config).JSON, (*Config).Logger} {
+		log(logger(cfg(false)))
+		want := b.String()
+		b.Reset()
+
+		log(logger(cfg(true)))
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+}
+
+func TestKeys(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := func() *Config {
+		return New().
+			Writer(&b).
+			ReplaceFunc(func(scope []string, a Attr) Attr {
+				if a.Key == slog.MessageKey {
+					a.Value = slog.StringValue(strings.ToUpper(a.Value.String()))
+				}
+				return a
+			}).
+			Keys("", "severity", "message", "")
+	}
+
+	want := func(name, want string) {
+		t.Helper()
+		if got := b.String(); got != want+"\n" {
+			t.Errorf("%s\n\twant %s\n\tgot  %s", name, want, got)
+		}
+		b.Reset()
+	}
+
+	cfg().JSON().Info("ok", "a", 1)
+	want("JSON", `{"severity":"INFO","message":"OK","a":1}`)
+
+	cfg().NativeJSON(true).JSON().Info("ok", "a", 1)
+	want("native JSON", `{"severity":"INFO","message":"OK","a":1}`)
+
+	cfg().Text().Info("ok")
+	want("text", `severity=INFO message=OK`)
+
+	cfg().ForceAux(true).Logger().Info("ok")
+	want("aux", `{"severity":"INFO","message":"OK"}`)
+
+	// TTY output is unaffected
+	cfg().ShowLayout("level", "message").ShowLevel(LevelText).ShowColor(false).ForceTTY(true).Logger().Info("ok")
+	want("TTY", `   INFO    OK`)
+}
+
+func TestBuiltinsDropped(t *testing.T) {
+	var b bytes.Buffer
+
+	dropAll := func(scope []string, a Attr) Attr {
+		if len(scope) == 0 {
+			switch a.Key {
+			case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+				return Attr{}
+			}
+		}
+		return a
+	}
+
+	for _, tc := range []struct {
+		name string
+		cfg  func() *Config
+	}{
+		{"Keys", func() *Config { return New().Writer(&b).Keys("", "", "", "") }},
+		{"ReplaceFunc", func() *Config { return New().Writer(&b).ReplaceFunc(dropAll) }},
+	} {
+		for _, native := range []bool{false, true} {
+			log := tc.cfg().NativeJSON(native).JSON()
+
+			for _, c := range []struct {
+				log  Logger
+				want string
+			}{
+				{log, `{"b":2}`},
+				{log.With("a", 1), `{"a":1,"b":2}`},
+				{log.WithGroup("g").With("a", 1), `{"g":{"a":1,"b":2}}`},
+			} {
+				c.log.Info("ok", "b", 2)
+				if got := b.String(); got != c.want+"\n" {
+					t.Errorf("%s, native %v:\n\twant %s\n\tgot  %s", tc.name, native, c.want, got)
+				}
+				b.Reset()
+			}
+		}
+	}
+}
+
+func TestTimePrecision(t *testing.T) {
+	base := time.Date(2009, 11, 10, 23, 4, 5, 123456789, time.UTC)
+	east := time.FixedZone("", 5*3600+30*60)
+	west := time.FixedZone("", -8*3600)
+
+	for _, tc := range []struct {
+		d      time.Duration
+		layout string
+	}{
+		{time.Second, "2006-01-02T15:04:05Z07:00"},
+		{time.Millisecond, "2006-01-02T15:04:05.000Z07:00"},
+		{time.Microsecond, "2006-01-02T15:04:05.000000Z07:00"},
+		{time.Nanosecond, "2006-01-02T15:04:05.000000000Z07:00"},
+		{10 * time.Millisecond, "2006-01-02T15:04:05.00Z07:00"},
+	} {
+		cfg := New().TimePrecision(tc.d)
+		for _, tm := range []time.Time{base, base.In(east), base.In(west)} {
+			want := tm.Format(tc.layout)
+			if got := string(appendTimeRFC3339(nil, tm, cfg.timePrec)); got != want {
+				t.Errorf("%v: want %s, got %s", tc.d, want, got)
+			}
+		}
+	}
+
+	var buf []byte
+	wantAllocs(t, "appendTimeRFC3339", 0, func() {
+		buf = appendTimeRFC3339(buf[:0], base, 6)
+	})
+
+	// TTY values and interpolation
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		TimePrecision(time.Microsecond).
+		ShowLayout("message", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+	log.Infof("{t}", "t", base)
+	if want := `2009-11-10T23:04:05.123456Z t:"2009-11-10T23:04:05.123456Z"` + "\n"; b.String() != want {
+		t.Errorf("tty:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+	b.Reset()
+
+	// native JSON, including the record time
+	log = New().
+		Writer(&b).
+		TimePrecision(time.Second).
+		NativeJSON(true).
+		JSON()
+	log.Info("ok", "t", base)
+	if !strings.Contains(b.String(), `"t":"2009-11-10T23:04:05Z"`) ||
+		!regexp.MustCompile(`"time":"[^".]+"`).MatchString(b.String()) {
+		t.Errorf("native JSON: got %s", b.String())
+	}
+}
@@ -0,0 +1,117 @@
+package logf
+
+import (
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// StdScopes is a global [LevelTree] used in default-ish configurations.
+var StdScopes LevelTree
+
+// SetScopeLevel sets the minimum level of the given scope in [StdScopes].
+func SetScopeLevel(scope string, level slog.Leveler) {
+	StdScopes.Set(scope, level)
+}
+
+// levelRef is a reference level, settable with [TTY.SetRef], as a [slog.LevelVar] is
+type levelRef interface {
+	slog.Leveler
+	Set(slog.Level)
+}
+
+// adaptLeveler returns a Leveler as a reference level.
+// A settable Leveler, e.g. a [slog.LevelVar], is returned as is; otherwise, the Leveler is consulted until a level is set.
+func adaptLeveler(l slog.Leveler) levelRef {
+	if ref, ok := l.(levelRef); ok {
+		return ref
+	}
+	return &levelerRef{leveler: l}
+}
+
+// levelerRef adapts a Leveler, keeping its level dynamic, rather than copying its current level to a LevelVar
+type levelerRef struct {
+	leveler slog.Leveler
+	set     atomic.Bool
+	level   slog.LevelVar
+}
+
+func (ref *levelerRef) Level() slog.Level {
+	if ref.set.Load() {
+		return ref.level.Level()
+	}
+	return ref.leveler.Level()
+}
+
+func (ref *levelerRef) Set(level slog.Level) {
+	ref.level.Set(level)
+	ref.set.Store(true)
+}
+
+// LevelTree maps group scopes to minimum levels.
+// A scope is a dotted path of group names, e.g. "http.client", and governs loggers opened with
+// the same groups, or groups nested deeper, in place of the reference level (see [Config.Ref]).
+// The longest matching scope wins.
+//
+// A LevelTree is safe for concurrent use, and its zero value is an empty tree.
+type LevelTree struct {
+	// immutable once stored; Set and Unset swap in a copy
+	levels atomic.Pointer[map[string]slog.Leveler]
+	mu     sync.Mutex
+}
+
+// Set sets the minimum level of a scope. The empty scope matches every logger.
+func (lt *LevelTree) Set(scope string, level slog.Leveler) {
+	lt.update(func(levels map[string]slog.Leveler) {
+		levels[scopeTreeKey(scope)] = level
+	})
+}
+
+// Unset removes the minimum level of a scope.
+func (lt *LevelTree) Unset(scope string) {
+	lt.update(func(levels map[string]slog.Leveler) {
+		delete(levels, scopeTreeKey(scope))
+	})
+}
+
+func (lt *LevelTree) update(f func(map[string]slog.Leveler)) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	levels := make(map[string]slog.Leveler)
+	if prev := lt.levels.Load(); prev != nil {
+		levels = maps.Clone(*prev)
+	}
+	f(levels)
+	lt.levels.Store(&levels)
+}
+
+// level reports the minimum level of the longest scope matching the store's scope
+func (lt *LevelTree) level(store Store) (slog.Level, bool) {
+	if lt == nil {
+		return 0, false
+	}
+
+	levels := lt.levels.Load()
+	if levels == nil || len(*levels) == 0 {
+		return 0, false
+	}
+
+	for depth := len(store.scope); depth >= 0; depth-- {
+		if level, ok := (*levels)[store.scopeKey(depth)]; ok {
+			return level.Level(), true
+		}
+	}
+	return 0, false
+}
+
+// keys are stored as Store.scopeKey gives them, e.g. "http.client."
+func scopeTreeKey(scope string) string {
+	scope = strings.Trim(scope, ".")
+	if scope == "" {
+		return ""
+	}
+	return scope + "."
+}
@@ -0,0 +1,149 @@
+package logf
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLevelTree(t *testing.T) {
+	var b bytes.Buffer
+	var tree LevelTree
+	tree.Set("http", WARN)
+	tree.Set("http.client", DEBUG)
+
+	logs := map[string]Logger{
+		"tty": New().
+			Writer(&b).
+			Scopes(&tree).
+			ShowLayout("message").
+			ShowColor(false).
+			ForceTTY(true).
+			Logger(),
+		"json": New().
+			Writer(&b).
+			Scopes(&tree).
+			JSON(),
+	}
+
+	for name, log := range logs {
+		log.Debug("root")
+		log.WithGroup("http").Info("http")
+		log.WithGroup("http").WithGroup("client").Debug("client")
+		log.WithGroup("http").WithGroup("client").WithGroup("pool").Debug("pool")
+		log.WithGroup("httpd").Debug("httpd")
+
+		got := b.String()
+		for _, msg := range []string{"client", "pool"} {
+			if !strings.Contains(got, msg) {
+				t.Errorf("%s: missing %q:\n%s", name, msg, got)
+			}
+		}
+		for _, msg := range []string{"root", "http\"", "http\n", "httpd"} {
+			if strings.Contains(got, msg) {
+				t.Errorf("%s: unexpected %q:\n%s", name, msg, got)
+			}
+		}
+		b.Reset()
+	}
+
+	// updates reach extant loggers
+	tree.Unset("http.client")
+	logs["tty"].WithGroup("http").WithGroup("client").Warn("client")
+	logs["tty"].WithGroup("http").WithGroup("client").Info("quiet")
+	if want, got := "client\n", b.String(); got != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+	b.Reset()
+
+	tree.Set("", slog.LevelError)
+	logs["json"].Warn("quiet")
+	if b.Len() > 0 {
+		t.Errorf("unexpected %q", b.String())
+	}
+}
+
+// dynamicLevel is a Leveler that isn't a LevelVar
+type dynamicLevel struct {
+	level atomic.Int64
+}
+
+func (l *dynamicLevel) Level() slog.Level {
+	return slog.Level(l.level.Load())
+}
+
+func TestHandlerOptions(t *testing.T) {
+	var b bytes.Buffer
+	var dyn dynamicLevel
+	dyn.level.Store(int64(INFO))
+
+	opts := &slog.HandlerOptions{
+		Level:     &dyn,
+		AddSource: true,
+		ReplaceAttr: func(scope []string, a Attr) Attr {
+			if a.Key == slog.TimeKey {
+				return Attr{}
+			}
+			return a
+		},
+	}
+
+	log := New().
+		Writer(&b).
+		HandlerOptions(opts).
+		JSON()
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); !strings.Contains(got, want) {
+			t.Errorf("\n\twant %q\n\tin   %q", want, got)
+		}
+		b.Reset()
+	}
+	none := func() {
+		t.Helper()
+		if got := b.String(); got != "" {
+			t.Errorf("unexpected %q", got)
+		}
+	}
+
+	log.Info("ok")
+	want(`{"level":"INFO","source":{`)
+
+	// the Leveler is consulted as its level changes
+	log.Debug("ok")
+	none()
+	dyn.level.Store(int64(DEBUG))
+	log.Debug("ok")
+	want(`"level":"DEBUG"`)
+
+	// until set
+	tty := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		HandlerOptions(opts).
+		AddSource(false).
+		TTY()
+	slog.New(tty).Debug("ok")
+	want("ok\n")
+	tty.SetRef(INFO)
+	slog.New(tty).Debug("ok")
+	none()
+	dyn.level.Store(int64(DEBUG - 4))
+	slog.New(tty).Debug("ok")
+	none()
+
+	// a LevelVar is used as is
+	var ref slog.LevelVar
+	New().
+		HandlerOptions(&slog.HandlerOptions{Level: &ref}).
+		TTY().
+		SetRef(WARN)
+	if ref.Level() != WARN {
+		t.Errorf("LevelVar: want %s, got %s", WARN, ref.Level())
+	}
+}
@@ -1,29 +1,50 @@
 package logf
 
 import (
+	"context"
 	"log/slog"
+	"reflect"
+	"runtime"
+	"slices"
+	"time"
 )
 
 // Logger embeds a [slog.Logger], and offers additional formatting methods:
-//   - Leveled / formatting: [Logger.Debugf], [Logger.Infof], [Logger.Warnf], [Logger.Errorf]
-//   - Formatting to a string or an error: [Logger.Fmt], [Logger.WrapErr]
-//   - Logger tagging: [Logger.Tag]
+//   - Leveled / formatting: [Logger.Debugf], [Logger.Infof], [Logger.Warnf], [Logger.Errorf], [Logger.Logf], [Logger.LogfContext]
+//   - Formatting to a string or an error: [Logger.Fmt], [Logger.WrapErr], [Logger.TryFmt], [Logger.TryWrapErr]
+//   - Logging at [TRACE]: [Logger.Trace], [Logger.Tracef]
+//   - Logger tagging: [Logger.Tags]
+//   - Building a group at the current scope: [Logger.BuildGroup]
+//   - Carrying an error: [Logger.WithError]
+//   - Recovering panics: [Logger.Recover], [Logger.Go]
+//   - Debugging values: [Logger.Dump]
+//   - Inspecting or removing stored attributes: [Logger.Attr], [Logger.Attrs], [Logger.Store], [Logger.Without]
 //
 // The following methods are available on a Logger by way of embedding:
-//   - Leveled logging methods: [slog.Logger.Debug], [slog.Logger.Info], [slog.Logger.Warn], [slog.Logger.Error]
-//   - General logging methods: [slog.Logger.Log], [slog.Logger.LogAttrs], [slog.Logger.LogDepth], [slog.Logger.LogAttrsDepth]
+//   - General logging methods: [slog.Logger.LogAttrs]
 //   - [slog.Logger.Handler]
 //
 // The following methds are overriden to return [Logger]s rather than [*slog.Logger]s:
 //   - [slog.Logger.With]
 //   - [slog.Logger.WithGroup]
+//
+// The following methods are overriden to respect [Logger.Depth]:
+//   - Leveled logging methods: [Logger.Debug], [Logger.Info], [Logger.Warn], [Logger.Error]
+//   - Leveled logging methods with context: [Logger.DebugContext], [Logger.InfoContext], [Logger.WarnContext], [Logger.ErrorContext]
+//   - [Logger.Log]
 type Logger struct {
 	*slog.Logger
+
+	// extra frames skipped when capturing a record's source
+	depth int
 }
 
 // UsingHandler returns a Logger employing the given slog.Handler
 //
 // If the given handler is not of a type native to logf, a new [Handler] is constructed, encapsulating the given handler.
+// Attributes already set on the given handler are recovered for interpolation if the handler offers them,
+// by way of a `LogValue() slog.Value` method resolving to a group, or an `Attrs() []slog.Attr` method.
+// Otherwise, interpolation can't see them.
 func UsingHandler(h slog.Handler) Logger {
 	if h, isLogfHandler := h.(handler); isLogfHandler {
 		return newLogger(h)
@@ -32,19 +53,57 @@ func UsingHandler(h slog.Handler) Logger {
 	lh := &Handler{
 		enc:       h,
 		addSource: true,
+		tagKey:    "#",
+		timePrec:  timeMillis,
+	}
+
+	if as := recoverAttrs(h); len(as) > 0 {
+		as, lh.labels = detectLabel(as, lh.labels, lh.tagKey)
+		lh.store = lh.store.WithAttrs(as)
 	}
 
 	return newLogger(lh)
 }
 
+// recoverAttrs probes a foreign handler for attributes set on it
+func recoverAttrs(h slog.Handler) []Attr {
+	switch h := h.(type) {
+	case slog.LogValuer:
+		if v := h.LogValue().Resolve(); v.Kind() == slog.KindGroup {
+			return slices.Clone(v.Group())
+		}
+	case interface{ Attrs() []Attr }:
+		return slices.Clone(h.Attrs())
+	}
+	return nil
+}
+
 func newLogger(h handler) Logger {
-	return Logger{slog.New(h)}
+	return Logger{slog.New(h), 0}
 }
 
-// See [slog.Logger.With]
+// loggerKey is the context key for a stored [Logger]
+type loggerKey struct{}
+
+// NewContext returns a context carrying the given [Logger].
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the [Logger] carried by the given context.
+// If the context carries no [Logger], a [Logger] using the handler of [slog.Default] is returned.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return l
+	}
+	return UsingHandler(slog.Default().Handler())
+}
+
+// See [slog.Logger.With]. As with [Attrs], map[string]any arguments are expanded.
 func (l Logger) With(args ...any) Logger {
 	return Logger{
-		l.Logger.With(args...),
+		l.Logger.With(expandMaps(args)...),
+		l.depth,
 	}
 }
 
@@ -52,45 +111,342 @@ func (l Logger) With(args ...any) Logger {
 func (l Logger) WithGroup(name string) Logger {
 	return Logger{
 		l.Logger.WithGroup(name),
+		l.depth,
+	}
+}
+
+// GroupBuilder builds a group attr for a [Logger], returned by [Logger.BuildGroup].
+type GroupBuilder struct {
+	l     Logger
+	name  string
+	store Store
+}
+
+// BuildGroup returns a [GroupBuilder] for a group with the given name.
+// Attrs given to [GroupBuilder.With] are collected in the group, and [GroupBuilder.End] returns a Logger storing the group at the Logger's scope.
+// Unlike [Logger.WithGroup], the returned Logger's scope is unchanged, so that subsequent attrs aren't in the group.
+//
+//	log = log.BuildGroup("http").With("method", "GET").With("status", 200).End()
+//	log.Infof("{http.method} {http.status}")
+func (l Logger) BuildGroup(name string) GroupBuilder {
+	return GroupBuilder{l: l, name: name}
+}
+
+// With adds attrs to the group. As with [Attrs], map[string]any arguments are expanded.
+func (g GroupBuilder) With(args ...any) GroupBuilder {
+	g.store = g.store.WithAttrs(Attrs(args...))
+	return g
+}
+
+// End returns the [Logger] that built the group, with the group added at its scope.
+// As with [slog.Group], a group without attrs is elided, and a group with an empty name is inlined.
+func (g GroupBuilder) End() Logger {
+	as := g.store.attrsDepth(0)
+	if len(as) == 0 {
+		return g.l
+	}
+	return g.l.With(slog.Attr{Key: g.name, Value: GroupValue(slices.Clone(as)...)})
+}
+
+// Tags returns a Logger with the given tags added to any it already has.
+// A [TTY] displays each tag in the tags field, and its filter (see [TTY.Filter]) matches a record if any tag is in the filter.
+// By contrast, a tag attr given to [Logger.With] replaces inherited tags, or joins them (see [Config.TagJoin]).
+// Other handlers receive the tags as a list, keyed as configured by [Config.TagKey].
+// If tags are disabled, the Logger is returned unchanged.
+func (l Logger) Tags(tags ...string) Logger {
+	key := loggerTagKey(l)
+	if len(tags) == 0 || key == "" {
+		return l
+	}
+	return l.With(slog.Any(key, tagSet(slices.Clone(tags))))
+}
+
+// returns the key of tag attrs for a Logger's handler, "#" by default
+func loggerTagKey(l Logger) string {
+	switch h := l.Handler().(type) {
+	case *Handler:
+		return h.tagKey
+	case *TTY:
+		return h.dev.tagKey
+	}
+	return "#"
+}
+
+// Depth returns a Logger that skips n additional call frames when capturing source information.
+// Helper functions wrapping a Logger can use Depth to attribute log lines to their callers.
+func (l Logger) Depth(n int) Logger {
+	l.depth += n
+	return l
+}
+
+// WithError returns a Logger carrying the given error under the "err" key.
+// Subsequent logging calls may interpolate "{err}", and a [TTY] displays the error as it would an error given at the call site.
+// Passing a nil error removes any error stored at the Logger's scope.
+func (l Logger) WithError(err error) Logger {
+	if store, _, ok := loggerStore(l); ok {
+		l = l.Without(store.scopeKey(len(store.scope)) + "err")
+	}
+	if err == nil {
+		return l
+	}
+	return l.With(slog.Any("err", err))
+}
+
+// Attr returns the value of a stored attribute with the given key, as with [Store.Get].
+// Keys are qualified by open groups, as in interpolation.
+// If the Logger's handler is not a [Handler] or [TTY], no value is found.
+func (l Logger) Attr(key string) (Value, bool) {
+	store, _, ok := loggerStore(l)
+	if !ok {
+		return Value{}, false
+	}
+	return store.Get(key)
+}
+
+// Attrs returns a copy of the attributes stored by the Logger, with keys qualified by open groups, as with [Store.Snapshot].
+// If the Logger's handler is not a [Handler] or [TTY], nil is returned.
+func (l Logger) Attrs() []Attr {
+	store, _, ok := loggerStore(l)
+	if !ok {
+		return nil
+	}
+	return store.Snapshot()
+}
+
+// Store returns a copy of the [Store] held by the Logger's handler.
+// Modifying the copy, as with [Store.ReplaceAttr], doesn't affect the Logger.
+// If the Logger's handler is not a [Handler] or [TTY], Store reports false.
+// A [Handler] constructed by [UsingHandler] holds the attributes it recovered from the encapsulated handler.
+func (l Logger) Store() (Store, bool) {
+	store, _, ok := loggerStore(l)
+	if !ok {
+		return Store{}, false
+	}
+	return store.clone(), true
+}
+
+// Without returns a Logger, less any stored attributes matching the given keys, as with [Store.Without].
+// Keys are qualified by open groups, as in interpolation.
+// If the Logger's handler is not a [Handler] or [TTY], the Logger is returned unchanged.
+func (l Logger) Without(keys ...string) Logger {
+	h, ok := l.Handler().(handler)
+	if !ok {
+		return l
+	}
+
+	store, _, _ := loggerStore(l)
+	store, removed := store.without(keys...)
+	if !removed {
+		return l
+	}
+
+	return Logger{
+		slog.New(h.withStore(store)),
+		l.depth,
+	}
+}
+
+// spansKey is the context key for the spans of values interpolated into a message
+type spansKey struct{}
+
+// ipolSpans holds the start and end offsets of values interpolated into msg,
+// so that a [TTY] highlighting them (see [Config.ShowInterpolated]) needn't interpolate again
+type ipolSpans struct {
+	msg   string
+	spans []int
+}
+
+// log is the common path of Logger output.
+// It captures the caller's pc, skipping any frames configured with [Logger.Depth].
+func (l Logger) log(ctx context.Context, level slog.Level, msg string, args []any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := l.Handler()
+	if !h.Enabled(ctx, level) {
+		return
 	}
+
+	// skip [runtime.Callers, Logger.log, Logger method]
+	var pcs [1]uintptr
+	runtime.Callers(3+l.depth, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(expandMaps(args)...)
+	h.Handle(ctx, r)
 }
 
+// Log interpolates the msg string and logs at the given level.
 func (l Logger) Log(level slog.Level, msg string, args ...any) {
-	msg = logFmt(l, msg, args)
-	l.Logger.Log(nil, level, msg, args...)
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
+	l.log(ctx, level, msg, args)
+}
+
+// Trace logs at [TRACE].
+func (l Logger) Trace(msg string, args ...any) {
+	l.log(nil, TRACE, msg, args)
+}
+
+// See [slog.Logger.Debug]
+func (l Logger) Debug(msg string, args ...any) {
+	l.log(nil, DEBUG, msg, args)
+}
+
+// See [slog.Logger.Info]
+func (l Logger) Info(msg string, args ...any) {
+	l.log(nil, INFO, msg, args)
+}
+
+// See [slog.Logger.Warn]
+func (l Logger) Warn(msg string, args ...any) {
+	l.log(nil, WARN, msg, args)
+}
+
+// See [slog.Logger.DebugContext]
+func (l Logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, DEBUG, msg, args)
+}
+
+// See [slog.Logger.InfoContext]
+func (l Logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, INFO, msg, args)
+}
+
+// See [slog.Logger.WarnContext]
+func (l Logger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, WARN, msg, args)
+}
+
+// ErrorContext is like [Logger.Error], passing the given context to the handler.
+func (l Logger) ErrorContext(ctx context.Context, msg string, err error, args ...any) {
+	args = append(args, slog.Any("err", err))
+	l.log(ctx, ERROR, msg, args)
+}
+
+// Tracef interpolates the msg string and logs at [TRACE].
+func (l Logger) Tracef(msg string, args ...any) {
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
+	l.log(ctx, TRACE, msg, args)
 }
 
 // Debugf interpolates the msg string and logs at DEBUG.
 func (l Logger) Debugf(msg string, args ...any) {
-	msg = logFmt(l, msg, args)
-	l.Debug(msg, args...)
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
+	l.log(ctx, DEBUG, msg, args)
 }
 
 // Infof interpolates the msg string and logs at INFO.
 func (l Logger) Infof(msg string, args ...any) {
-	msg = logFmt(l, msg, args)
-	l.Info(msg, args...)
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
+	l.log(ctx, INFO, msg, args)
 }
 
 // Warnf interpolates the msg string and logs at WARN.
 func (l Logger) Warnf(msg string, args ...any) {
-	msg = logFmt(l, msg, args)
-	l.Warn(msg, args...)
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
+	l.log(ctx, WARN, msg, args)
+}
+
+// Logf interpolates the msg string and logs at the given level, which needn't be one of the leveled methods' levels.
+// Unlike [Logger.Errorf], it adds no error attr.
+func (l Logger) Logf(level slog.Level, msg string, args ...any) {
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
+	l.log(ctx, level, msg, args)
+}
+
+// LogfContext is like [Logger.Logf], passing the given context to the handler.
+func (l Logger) LogfContext(ctx context.Context, level slog.Level, msg string, args ...any) {
+	ctx, msg, args = logFmtArgs(ctx, l, msg, args)
+	l.log(ctx, level, msg, args)
 }
 
 // Error is log slog.Error, but specifically asks for an error.
 func (l Logger) Error(msg string, err error, args ...any) {
 	args = append(args, slog.Any("err", err))
-	l.Logger.Error(msg, args...)
+	l.log(nil, ERROR, msg, args)
 }
 
 // Errorf interpolates the msg string and logs at ERROR.
 func (l Logger) Errorf(msg string, err error, args ...any) {
 	args = append(args, slog.Any("err", err))
-	msg = logFmt(l, msg, args)
+	ctx, msg, args := logFmtArgs(nil, l, msg, args)
 	err = logFmtErr(l, msg, err, args)
 
-	l.Logger.Error(msg, args...)
+	l.log(ctx, ERROR, msg, args)
+}
+
+// Dump logs v at DEBUG, with the message "dump". The value is keyed by label, and rendered as nested groups:
+// struct fields are keyed as with [StructAttrs], slices and arrays are keyed by index, and maps are keyed by sorted keys.
+// Rendering stops at a depth of 8, at 64 elements of any slice, array, or map, and at cyclic pointers.
+func (l Logger) Dump(label string, v any) {
+	if !l.Handler().Enabled(context.Background(), DEBUG) {
+		return
+	}
+	if label == "" {
+		label = "v"
+	}
+
+	a := slog.Attr{Key: label, Value: dumpValue(reflect.ValueOf(v), 0, make(map[uintptr]bool))}
+	l.log(nil, DEBUG, "dump", []any{a})
+}
+
+// Recover recovers a panic in flight, if any, and logs an ERROR record holding the panic value keyed "panic",
+// the given args, and the stack of the panic keyed "stack". It is meant to be deferred:
+//
+//	defer log.Recover("job", id)
+//
+// If [Config.RepanicAfterRecover] is set, the panic resumes after logging.
+func (l Logger) Recover(args ...any) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	l.logPanic(v, args)
+	if loggerRepanic(l) {
+		panic(v)
+	}
+}
+
+// Go runs fn in a new goroutine, recovering and logging a panic as with [Logger.Recover].
+func (l Logger) Go(fn func()) {
+	go func() {
+		defer l.Recover()
+		fn()
+	}()
+}
+
+// logs a recovered panic, with source at the frame that panicked
+func (l Logger) logPanic(v any, args []any) {
+	ctx := context.Background()
+	h := l.Handler()
+	if !h.Enabled(ctx, ERROR) {
+		return
+	}
+
+	stack := callers(1)
+	var pc uintptr
+	if len(stack) > 0 {
+		pc = stack[0].PC
+	}
+
+	r := slog.NewRecord(time.Now(), ERROR, "panic", pc)
+	r.AddAttrs(slog.Any("panic", v))
+	r.Add(expandMaps(args)...)
+	r.AddAttrs(slog.Attr{Key: "stack", Value: stackValue(stack)})
+	h.Handle(ctx, r)
+}
+
+// reports whether the Logger's handler is configured to repanic after [Logger.Recover]
+func loggerRepanic(l Logger) bool {
+	switch h := l.Handler().(type) {
+	case *Handler:
+		return h.repanic
+	case *TTY:
+		return h.dev.repanic
+	}
+	return false
 }
 
 // Fmt interpolates the f string and returns the result.
@@ -98,6 +454,17 @@ func (l Logger) Fmt(f string, args ...any) string {
 	return logFmt(l, f, args)
 }
 
+// TryFmt is like [Logger.Fmt], but also reports interpolation faults, as with [TryFmt].
+// Unlike [Logger.Fmt], it interpolates the given arguments even if the Logger's handler is not a logf handler.
+func (l Logger) TryFmt(f string, args ...any) (string, error) {
+	return logTryFmt(l, f, args)
+}
+
+// TryWrapErr is like [Logger.WrapErr], but also reports interpolation faults, as with [TryFmt].
+func (l Logger) TryWrapErr(f string, err error, args ...any) (error, error) {
+	return logTryFmtErr(l, f, err, args)
+}
+
 // WrapErr interpolates the f string, and returns an error.
 // If geven a nil error, the resulting error.Error() string is the result of interpolating f.
 // If given a non-nil error, the result includes the given error's string, and matches [errors.Is]/[errors.As] behavior, as with [fmt.Errorf]
@@ -0,0 +1,320 @@
+package logf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	var b bytes.Buffer
+
+	want := func(want string) {
+		t.Helper()
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("\n\texpected %s\n\tin %s", want, b.String())
+		}
+		b.Reset()
+	}
+
+	// absent: falls back to the slog default
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&b, nil)).With("from", "slog"))
+
+	FromContext(context.Background()).Info("default")
+	want(`"msg":"default","from":"slog"`)
+
+	// round trip
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		WithGroup("g").
+		With("a", 1)
+
+	ctx := NewContext(context.Background(), log)
+
+	FromContext(ctx).Infof("{g.a}", "b", 2)
+	want("1\tg:{a:1 b:2}")
+}
+
+// attrsHandler is a foreign handler offering its attrs
+type attrsHandler struct {
+	slog.Handler
+	as []Attr
+}
+
+func (h attrsHandler) WithAttrs(as []Attr) slog.Handler {
+	return attrsHandler{h.Handler.WithAttrs(as), concat(h.as, as)}
+}
+
+func (h attrsHandler) Attrs() []Attr {
+	return h.as
+}
+
+// valuerHandler is a foreign handler offering its attrs as a LogValue
+type valuerHandler struct {
+	slog.Handler
+	v Value
+}
+
+func (h valuerHandler) LogValue() Value {
+	return h.v
+}
+
+func TestUsingHandlerRecovery(t *testing.T) {
+	var b bytes.Buffer
+
+	want := func(want string) {
+		t.Helper()
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("\n\texpected %s\n\tin %s", want, b.String())
+		}
+		b.Reset()
+	}
+
+	foreign := attrsHandler{slog.NewTextHandler(&b, nil), nil}.
+		WithAttrs([]Attr{slog.String("user", "gopher"), slog.String("#", "tagged")})
+
+	// recovered via Attrs
+	log := UsingHandler(foreign)
+	log.Infof("hello {user}")
+	want(`msg="hello gopher" user=gopher #=tagged`)
+
+	h := log.Handler().(*Handler)
+	if len(h.labels) != 1 || h.labels[0].Value.String() != "tagged" {
+		t.Errorf("label: got %v", h.labels)
+	}
+
+	// recovered attrs aren't duplicated when the handler is rebuilt
+	log.With("token", "secret").Without("token").Info("rebuilt")
+	if n := strings.Count(b.String(), "user=gopher"); n != 1 {
+		t.Errorf("rebuilt: want 1 user attr, got %d in %s", n, b.String())
+	}
+	want(`msg=rebuilt user=gopher`)
+
+	// recovered via LogValue
+	as := []Attr{slog.String("user", "gopher")}
+	log = UsingHandler(valuerHandler{slog.NewTextHandler(&b, nil).WithAttrs(as), slog.GroupValue(as...)})
+	log.Infof("hello {user}")
+	want(`msg="hello gopher" user=gopher`)
+
+	// nothing recoverable
+	log = UsingHandler(slog.NewTextHandler(&b, nil).WithAttrs([]Attr{slog.String("user", "gopher")}))
+	log.Infof("hello {user}")
+	want(`msg="hello !missing-match" user=gopher`)
+}
+
+func TestLoggerAttrs(t *testing.T) {
+	log := New().
+		Writer(io.Discard).
+		ForceTTY(true).
+		Logger().
+		With("a", 1).
+		WithGroup("g").
+		With("b", 2, Group("h", "c", 3))
+
+	if got := fmt.Sprint(log.Attrs()); got != "[a=1 g.b=2 g.h=[c=3]]" {
+		t.Errorf("attrs: %s", got)
+	}
+
+	// the returned slice doesn't alias the logger's
+	as := log.Attrs()
+	as[0] = slog.Int("a", 100)
+	if v, _ := log.Attr("a"); v.Int64() != 1 {
+		t.Errorf("attrs: mutated logger, got %v", v)
+	}
+
+	// nor does the returned store
+	store, ok := log.Store()
+	if !ok {
+		t.Fatal("store: not found")
+	}
+	if got := store.LogValue().String(); got != "[a=1 g=[b=2 h=[c=3]]]" {
+		t.Errorf("store: LogValue %s", got)
+	}
+	store.ReplaceAttr(func(scope []string, a Attr) Attr {
+		return slog.Int(a.Key, 0)
+	})
+	if v, _ := store.Get("g.b"); v.Int64() != 0 {
+		t.Errorf("store: not replaced, got %v", v)
+	}
+	if got := store.LogValue().String(); got != "[a=0 g=[b=0 h=0]]" {
+		t.Errorf("store: LogValue not replaced, got %s", got)
+	}
+	if v, _ := log.Attr("g.b"); v.Int64() != 2 {
+		t.Errorf("store: mutated logger, got %v", v)
+	}
+
+	// recovered from a foreign handler
+	foreign := attrsHandler{slog.NewTextHandler(io.Discard, nil), nil}.
+		WithAttrs([]Attr{slog.String("user", "gopher")})
+	if got := fmt.Sprint(UsingHandler(foreign).Attrs()); got != "[user=gopher]" {
+		t.Errorf("recovered attrs: %s", got)
+	}
+
+	// no store
+	if _, ok := (Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}).Store(); ok {
+		t.Errorf("store: found for a foreign handler")
+	}
+}
+
+func TestLoggerBuildGroup(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		With("a", 1)
+
+	http := log.BuildGroup("http").With("method", "GET").With("status", 200)
+	log2 := http.End().With("b", 2)
+
+	log2.Infof("{http.method} {http.status} {b}")
+	if want, got := "GET 200 2\ta:1 http:{method:GET status:200} b:2\n", b.String(); want != got {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+
+	// the builder doesn't share attrs among branches
+	if got := fmt.Sprint(http.With("path", "/").End().Attrs()); got != "[a=1 http=[method=GET status=200 path=/]]" {
+		t.Errorf("attrs: %s", got)
+	}
+	if got := fmt.Sprint(http.With("path", "/x").End().Attrs()); got != "[a=1 http=[method=GET status=200 path=/x]]" {
+		t.Errorf("attrs: %s", got)
+	}
+
+	// at the logger's scope
+	if got := fmt.Sprint(log.WithGroup("g").BuildGroup("h").With("c", 3).End().With("d", 4).Attrs()); got != "[a=1 g.h=[c=3] g.d=4]" {
+		t.Errorf("attrs: %s", got)
+	}
+
+	// empty
+	if got := fmt.Sprint(log.BuildGroup("http").End().Attrs()); got != "[a=1]" {
+		t.Errorf("attrs: %s", got)
+	}
+}
+
+func TestLoggerLogf(t *testing.T) {
+	var b bytes.Buffer
+
+	type ctxKey struct{}
+	log := New().
+		Writer(&b).
+		ContextAttrs(func(ctx context.Context) []Attr {
+			if id, ok := ctx.Value(ctxKey{}).(string); ok {
+				return []Attr{slog.String("id", id)}
+			}
+			return nil
+		}).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == slog.TimeKey {
+				return Attr{}
+			}
+			return a
+		}).
+		Text().
+		With("user", "gopher")
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want+"\n" {
+			t.Errorf("\n\twant %q\n\tgot  %q", want+"\n", got)
+		}
+		b.Reset()
+	}
+
+	log.Logf(INFO+2, "{user}: {n}", "n", 1)
+	want("level=INFO+2 msg=\"gopher: 1\" user=gopher n=1")
+
+	// no error attr is added
+	log.Logf(ERROR+4, "failed: {}", "reason", errors.New("boom"))
+	want("level=ERROR+4 msg=\"failed: boom\" user=gopher reason=boom")
+
+	log.LogfContext(context.WithValue(context.Background(), ctxKey{}, "x"), WARN+2, "{user}")
+	want("level=WARN+2 msg=gopher user=gopher id=x")
+}
+
+func TestLoggerRecover(t *testing.T) {
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		JSON()
+
+	func() {
+		defer log.Recover("job", 1)
+		panic("boom")
+	}()
+
+	var rec struct {
+		Msg   string
+		Panic string
+		Job   int
+		Stack map[string]struct {
+			Func string
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &rec); err != nil {
+		t.Fatalf("%v:\n%s", err, b.String())
+	}
+	if rec.Msg != "panic" || rec.Panic != "boom" || rec.Job != 1 {
+		t.Errorf("unexpected record:\n%s", b.String())
+	}
+	if len(rec.Stack) == 0 {
+		t.Errorf("expected a stack:\n%s", b.String())
+	}
+	for _, f := range rec.Stack {
+		if pkg := framePkg(f.Func); pkg == "github.com/AndrewHarrisSPU/logf" || pkg == "runtime" {
+			t.Errorf("expected %s frames to be elided, got %s", pkg, f.Func)
+		}
+	}
+
+	// repanic
+	b.Reset()
+	log = New().
+		Writer(&b).
+		RepanicAfterRecover(true).
+		JSON()
+
+	v := func() (v any) {
+		defer func() { v = recover() }()
+		defer log.Recover()
+		panic("again")
+	}()
+	if v != "again" || !strings.Contains(b.String(), `"panic":"again"`) {
+		t.Errorf("repanic: got %v\n%s", v, b.String())
+	}
+
+	// in a goroutine
+	b.Reset()
+	done := make(chan struct{})
+	log = New().
+		Writer(&b).
+		Observer(func(slog.Level, string, bool) { close(done) }).
+		JSON()
+
+	log.Go(func() { panic("gone") })
+	<-done
+	if !strings.Contains(b.String(), `"panic":"gone"`) {
+		t.Errorf("go: got\n%s", b.String())
+	}
+
+	// no panic, no overhead
+	b.Reset()
+	wantAllocs(t, "recover", 0, func() {
+		defer log.Recover()
+	})
+	if b.Len() > 0 {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+}
@@ -1,11 +1,18 @@
 package logf
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"log/slog"
@@ -46,47 +53,164 @@ type splicer struct {
 
 	matchStack []string
 
-	// holds map of keyed interpolation symbols
-	dict map[string]slog.Value
+	// holds the dotted key of the attr being matched
+	keyBuf []byte
+
+	// holds map of keyed interpolation symbols, indexing into vals
+	dict map[string]int
+	vals []slog.Value
 
 	// holds ordered list of exported attrs
 	export []Attr
 
+	// holds scoped store and exported attrs, when interpolating `{*}`
+	star []Attr
+
 	// holds number of unkeyed attrs
 	iUnkeyed int
+
+	// when non-nil, records interpolation faults
+	audit *ipolAudit
+
+	// whether [Secret] values are revealed
+	reveal bool
+
+	// when default encoders quote keys and values
+	quote quoteMode
+
+	// whether control characters in values are escaped
+	sanitize bool
+
+	// whether hyperlinks may be written, as with [SourceLink]
+	links bool
+
+	// whether nested groups are written as dotted keys
+	dots bool
+
+	// whether structs in KindAny values are written with field names
+	structs bool
+
+	// fractional-second digits of times written without a verb
+	timePrec int
+
+	// whether byte slices are written in hex without a verb, truncated to hexMax bytes if positive
+	hexBytes bool
+	hexMax   int
+
+	// whether maps are written as groups, with sorted keys if mapSort is set
+	maps    bool
+	mapSort bool
+
+	// depth of collections being written
+	nest int
+
+	// format and precision of floats written without a verb, as with [strconv.AppendFloat],
+	// and whether whole numbers are written without a decimal point
+	floatFmt  byte
+	floatPrec int
+	floatTrim bool
+
+	// highlights interpolated values, restoring the message color after each
+	ipolPen     pen
+	ipolRestore pen
+
+	// if set, the start and end offsets of each interpolated value are appended to spans
+	markSpans bool
+	spans     []int
 }
 
 func newSplicer() *splicer {
+	spoolStats.gets.Add(1)
 	return spool.Get().(*splicer)
 }
 
 var spool = sync.Pool{
 	New: func() any {
+		spoolStats.news.Add(1)
 		return &splicer{
 			text:       make([]byte, 0, 1024),
 			scratch:    make([]byte, 0, 1024),
 			matchStack: make([]string, 0, 16),
-			dict:       make(map[string]slog.Value, 5),
+			keyBuf:     make([]byte, 0, 64),
+			dict:       make(map[string]int, 5),
+			vals:       make([]slog.Value, 0, 5),
 			export:     make([]Attr, 0, 5),
+			timePrec:   timeMillis,
+			floatFmt:   'g',
+			floatPrec:  -1,
 		}
 	},
 }
 
-// contains heuristics for killing splicers that are too large
-// TODO: think through implications
-func (s *splicer) free() {
-	const maxTextSize = 16 << 10
-	const maxAttrSize = 128
-	const maxStackSize = 128
+// POOL LIMITS / STATS
+
+var spoolLimits struct {
+	maxText  atomic.Int64
+	maxAttrs atomic.Int64
+	maxStack atomic.Int64
+}
+
+var spoolStats struct {
+	gets, puts, discards, news atomic.Int64
+}
+
+func init() {
+	SetSplicerLimits(16<<10, 128, 128)
+}
+
+// SetSplicerLimits sets the limits beyond which a splicer is discarded rather than returned to its pool.
+// Splicers are the buffers used to interpolate and format log lines.
+// maxText bounds the byte capacity of text buffers, maxAttrs bounds the number of attributes held, and
+// maxStack bounds the depth of group keys.
+// The defaults are 16KiB, 128, and 128. A non-positive limit leaves the current value unchanged.
+func SetSplicerLimits(maxText, maxAttrs, maxStack int) {
+	if maxText > 0 {
+		spoolLimits.maxText.Store(int64(maxText))
+	}
+	if maxAttrs > 0 {
+		spoolLimits.maxAttrs.Store(int64(maxAttrs))
+	}
+	if maxStack > 0 {
+		spoolLimits.maxStack.Store(int64(maxStack))
+	}
+}
+
+// PoolStats is a snapshot of splicer pool activity, as returned by [SplicerStats].
+type PoolStats struct {
+	// Gets counts splicers taken from the pool
+	Gets int64
+	// Puts counts splicers returned to the pool
+	Puts int64
+	// Discards counts splicers dropped for exceeding limits set by [SetSplicerLimits]
+	Discards int64
+	// News counts splicers allocated by the pool
+	News int64
+}
+
+// SplicerStats returns a snapshot of splicer pool activity.
+func SplicerStats() PoolStats {
+	return PoolStats{
+		Gets:     spoolStats.gets.Load(),
+		Puts:     spoolStats.puts.Load(),
+		Discards: spoolStats.discards.Load(),
+		News:     spoolStats.news.Load(),
+	}
+}
 
-	ok := cap(s.text)+cap(s.scratch) < maxTextSize
-	ok = ok && (len(s.dict)+cap(s.export)) < maxAttrSize
-	ok = ok && (len(s.matchStack)) < maxStackSize
+// discards splicers that exceed limits set by SetSplicerLimits
+func (s *splicer) free() {
+	ok := int64(cap(s.text)+cap(s.scratch)) < spoolLimits.maxText.Load()
+	ok = ok && int64(len(s.dict)+cap(s.export)) < spoolLimits.maxAttrs.Load()
+	ok = ok && int64(len(s.matchStack)) < spoolLimits.maxStack.Load()
 
-	if ok {
-		s.clear()
-		spool.Put(s)
+	if !ok {
+		spoolStats.discards.Add(1)
+		return
 	}
+
+	spoolStats.puts.Add(1)
+	s.clear()
+	spool.Put(s)
 }
 
 // atm, clearing on "free" when cap/length is not over limits
@@ -95,6 +219,7 @@ func (s *splicer) clear() {
 	// clear byte buffers
 	s.text = s.text[:0]
 	s.scratch = s.scratch[:0]
+	s.keyBuf = s.keyBuf[:0]
 
 	// zero out and clear reference-holding components
 	for i := range s.export {
@@ -102,11 +227,40 @@ func (s *splicer) clear() {
 	}
 	s.export = s.export[:0]
 
+	for i := range s.star {
+		s.star[i] = Attr{}
+	}
+	s.star = s.star[:0]
+
 	for k := range s.dict {
 		delete(s.dict, k)
 	}
+	for i := range s.vals {
+		s.vals[i] = slog.Value{}
+	}
+	s.vals = s.vals[:0]
 
 	s.iUnkeyed = 0
+	s.audit = nil
+	s.reveal = false
+	s.quote = quoteAuto
+	s.sanitize = false
+	s.links = false
+	s.dots = false
+	s.structs = false
+	s.timePrec = timeMillis
+	s.hexBytes = false
+	s.hexMax = 0
+	s.maps = false
+	s.mapSort = false
+	s.nest = 0
+	s.floatFmt = 'g'
+	s.floatPrec = -1
+	s.floatTrim = false
+	s.ipolPen = ""
+	s.ipolRestore = ""
+	s.markSpans = false
+	s.spans = s.spans[:0]
 }
 
 // return spliced text
@@ -114,65 +268,209 @@ func (s *splicer) line() string {
 	return string(s.text)
 }
 
+// DICT
+
+// adds a key to the dictionary, initially unmatched
+func (s *splicer) dictAdd(key string) {
+	if _, found := s.dict[key]; !found {
+		s.dict[key] = len(s.vals)
+		s.vals = append(s.vals, missingMatch)
+	}
+}
+
+// looks up a key without allocating a string
+func (s *splicer) dictGet(key []byte) (slog.Value, bool) {
+	i, found := s.dict[string(key)]
+	if !found {
+		return slog.Value{}, false
+	}
+	return s.vals[i], true
+}
+
+// sets the value of a key already in the dictionary
+func (s *splicer) dictSet(key []byte, v slog.Value) {
+	if i, found := s.dict[string(key)]; found {
+		s.vals[i] = v
+	}
+}
+
+func (s *splicer) dictHas(key string) bool {
+	_, found := s.dict[key]
+	return found
+}
+
 // JOIN / MATCH
-func (s *splicer) joinStore(store Store, replace replaceFunc) {
-	store.Attrs(func(scope []string, a Attr) {
-		s.match(scope, a, replace)
-	})
+
+// joinStore looks up the keys in the dictionary among the store's prebuilt values.
+// Only the "*" key, listing every stored attr, traverses the store.
+func (s *splicer) joinStore(store Store) {
+	if s.dictHas("*") {
+		s.joinStoreStar(store)
+		return
+	}
+	if store.dict.len() == 0 {
+		return
+	}
+
+	for key, i := range s.dict {
+		if v, ok := store.dict.lookup(key); ok {
+			s.vals[i] = v
+		}
+	}
+}
+
+func (s *splicer) joinStoreStar(store Store) {
+	for depth := 0; depth < len(store.as) && depth <= len(store.scope); depth++ {
+		scope := store.scope[:depth]
+		s.keyBuf = append(s.keyBuf[:0], store.scopeKey(depth)...)
+
+		for _, a := range store.as[depth] {
+			a = replaceAttr(scope, a, store.replace)
+			s.match(a)
+			s.joinStar(a)
+		}
+	}
 }
 
-func (s *splicer) joinLocal(stack []string, a Attr, replace replaceFunc) {
-	if replace != nil {
-		a = replace(stack, a)
+func (s *splicer) joinLocal(store Store, a Attr, replace replaceFunc) {
+	a = replaceAttr(store.scope, a, replace)
+
+	s.keyBuf = append(s.keyBuf[:0], store.scopeKey(len(store.scope))...)
+
+	if s.dictHas("*") {
+		s.joinStar(a)
 	}
 
 	s.export = append(s.export, a)
-	s.matchLocal(stack, a, replace)
-	s.match(stack, a, replace)
+	s.matchLocal(a)
+	s.match(a)
 }
 
-func (s *splicer) matchLocal(stack []string, a Attr, replace replaceFunc) {
-	if replace != nil {
-		a = replace(stack, a)
+// s.keyBuf holds the scope prefix of a
+func (s *splicer) joinStar(a Attr) {
+	if a.Key == "" {
+		return
 	}
 
-	if _, found := s.dict[a.Key]; found {
-		s.dict[a.Key] = a.Value
+	if len(s.keyBuf) > 0 {
+		a.Key = string(s.keyBuf) + a.Key
 	}
+	s.star = append(s.star, a)
+}
+
+// s.keyBuf holds the scope prefix of a; matchLocal also matches the unscoped key of a
+func (s *splicer) matchLocal(a Attr) {
+	n := len(s.keyBuf)
+	s.keyBuf = append(s.keyBuf, a.Key...)
+
+	s.dictSet(s.keyBuf[n:], a.Value)
+	s.matchIndex(s.keyBuf[n:], a.Value)
 
 	if a.Value.Kind() == slog.KindGroup {
-		stack = append(stack, a.Key)
+		s.keyBuf = append(s.keyBuf, '.')
 
 		for _, a := range a.Value.Group() {
-			s.match(stack, a, replace)
+			s.match(a)
 		}
 	}
+
+	s.keyBuf = s.keyBuf[:n]
 }
 
-func (s *splicer) match(stack []string, a Attr, replace replaceFunc) {
-	if replace != nil {
-		a = replace(stack, a)
+// s.keyBuf holds the scope prefix of a
+func (s *splicer) match(a Attr) {
+	n := len(s.keyBuf)
+	s.keyBuf = append(s.keyBuf, a.Key...)
+
+	// properly scoped
+	s.dictSet(s.keyBuf, a.Value)
+	s.matchIndex(s.keyBuf, a.Value)
+
+	if a.Value.Kind() == slog.KindGroup {
+		s.keyBuf = append(s.keyBuf, '.')
+
+		for _, a := range a.Value.Group() {
+			s.match(a)
+		}
 	}
 
-	var key string
-	if len(stack) > 0 {
-		key = strings.Join(stack, ".")
-		key += "."
+	s.keyBuf = s.keyBuf[:n]
+}
+
+// matches dict keys that index into a slice, array, or map held by a value
+func (s *splicer) matchIndex(key []byte, v slog.Value) {
+	if v.Kind() != slog.KindAny {
+		return
 	}
-	key += a.Key
 
-	// properly scoped
-	if _, found := s.dict[key]; found {
-		s.dict[key] = a.Value
+	for dictKey, i := range s.dict {
+		if len(dictKey) <= len(key)+1 || dictKey[len(key)] != '.' || dictKey[:len(key)] != string(key) {
+			continue
+		}
+
+		if iv, ok := indexValue(v.Any(), dictKey[len(key)+1:]); ok {
+			s.vals[i] = iv
+		}
 	}
+}
 
-	if a.Value.Kind() == slog.KindGroup {
-		stack = append(stack, a.Key)
+// indexValue follows a dotted path of indices and map keys into x
+func indexValue(x any, path string) (slog.Value, bool) {
+	rv := reflect.ValueOf(x)
 
-		for _, a := range a.Value.Group() {
-			s.match(stack, a, replace)
+	for len(path) > 0 {
+		seg := path
+		if n := strings.IndexByte(path, '.'); n >= 0 {
+			seg, path = path[:n], path[n+1:]
+		} else {
+			path = ""
+		}
+
+		for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return slog.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, ok := ipolIndex([]byte(seg))
+			if !ok || i >= rv.Len() {
+				return slog.Value{}, false
+			}
+			rv = rv.Index(i)
+
+		case reflect.Map:
+			kt := rv.Type().Key()
+			var k reflect.Value
+			switch kt.Kind() {
+			case reflect.String:
+				k = reflect.ValueOf(seg).Convert(kt)
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				i, err := strconv.ParseInt(seg, 10, 64)
+				if err != nil || reflect.Zero(kt).OverflowInt(i) {
+					return slog.Value{}, false
+				}
+				k = reflect.ValueOf(i).Convert(kt)
+			default:
+				return slog.Value{}, false
+			}
+
+			rv = rv.MapIndex(k)
+			if !rv.IsValid() {
+				return slog.Value{}, false
+			}
+
+		default:
+			return slog.Value{}, false
 		}
 	}
+
+	if !rv.CanInterface() {
+		return slog.Value{}, false
+	}
+	return slog.AnyValue(rv.Interface()), true
 }
 
 // WRITES
@@ -198,12 +496,64 @@ func (s *splicer) WriteString(m string) (int, error) {
 
 // TYPED WRITES
 
+// resolves a value, revealing a [Secret] if the splicer reveals secrets
+func (s *splicer) resolve(v slog.Value) slog.Value {
+	return resolveSecret(v, s.reveal)
+}
+
 func (s *splicer) WriteValue(v slog.Value, verb []byte) {
+	mark := len(s.text)
 	if len(verb) > 0 {
 		s.writeValueVerb(v, string(verb))
 	} else {
 		s.writeValueNoVerb(v)
 	}
+	s.sanitizeSince(mark)
+}
+
+// writes text, escaping control characters if the splicer sanitizes
+func (s *splicer) writeSanitized(text string) {
+	mark := len(s.text)
+	s.WriteString(text)
+	s.sanitizeSince(mark)
+}
+
+// escapes control characters written since mark, if the splicer sanitizes
+func (s *splicer) sanitizeSince(mark int) {
+	if !s.sanitize {
+		return
+	}
+
+	text := s.text[mark:]
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRune(text[i:])
+		if isUnsafeControl(r) {
+			break
+		}
+		i += size
+	}
+	if i == len(text) {
+		return
+	}
+
+	raw := string(text[i:])
+	s.text = s.text[:mark+i]
+	for len(raw) > 0 {
+		r, size := utf8.DecodeRuneInString(raw)
+		if isUnsafeControl(r) {
+			q := strconv.QuoteRune(r)
+			s.text = append(s.text, q[1:len(q)-1]...)
+		} else {
+			s.text = append(s.text, raw[:size]...)
+		}
+		raw = raw[size:]
+	}
+}
+
+// reports whether r is a C0 (other than tab), DEL, or C1 control character
+func isUnsafeControl(r rune) bool {
+	return (r < 0x20 && r != '\t') || (0x7f <= r && r <= 0x9f)
 }
 
 func (s *splicer) writeValueNoVerb(v slog.Value) {
@@ -213,7 +563,7 @@ func (s *splicer) writeValueNoVerb(v slog.Value) {
 	case slog.KindBool:
 		s.text = strconv.AppendBool(s.text, v.Bool())
 	case slog.KindFloat64:
-		s.text = strconv.AppendFloat(s.text, v.Float64(), 'g', -1, 64)
+		s.writeFloat(v.Float64())
 	case slog.KindInt64:
 		s.text = strconv.AppendInt(s.text, v.Int64(), 10)
 	case slog.KindUint64:
@@ -221,19 +571,122 @@ func (s *splicer) writeValueNoVerb(v slog.Value) {
 	case slog.KindDuration:
 		s.text = appendDuration(s.text, v.Duration())
 	case slog.KindTime:
-		s.text = appendTimeRFC3339Millis(s.text, v.Time())
+		s.text = appendTimeRFC3339(s.text, v.Time(), s.timePrec)
 	case slog.KindGroup:
 		s.writeGroup(v.Group())
 	case slog.KindLogValuer:
-		s.writeValueNoVerb(v.Resolve())
+		s.writeValueNoVerb(s.resolve(v))
 	case slog.KindAny:
-		fmt.Fprintf(s, "%v", v.Any())
+		s.writeAny(v.Any())
 	default:
 		panic(corruptKind)
 	}
 }
 
+// writes a float in the splicer's format and precision,
+// or without a decimal point if it's a whole number and the splicer trims zeros
+func (s *splicer) writeFloat(f float64) {
+	if s.floatTrim && f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		s.text = strconv.AppendFloat(s.text, f, 'f', 0, 64)
+		return
+	}
+	s.text = strconv.AppendFloat(s.text, f, s.floatFmt, s.floatPrec, 64)
+}
+
+// writes x as with %v, appending common types directly
+func (s *splicer) writeAny(x any) {
+	switch x := x.(type) {
+	case fmt.Formatter:
+		// Formatters take precedence over error and Stringer methods in fmt
+	case error:
+		if s.writeMethod(x, x.Error) {
+			return
+		}
+	case fmt.Stringer:
+		if s.writeMethod(x, x.String) {
+			return
+		}
+	case []byte:
+		if s.hexBytes {
+			s.writeHex(x, s.hexMax)
+			return
+		}
+		s.WriteByte('[')
+		for i, c := range x {
+			if i > 0 {
+				s.WriteByte(' ')
+			}
+			s.text = strconv.AppendUint(s.text, uint64(c), 10)
+		}
+		s.WriteByte(']')
+		return
+	case int8:
+		s.text = strconv.AppendInt(s.text, int64(x), 10)
+		return
+	case int16:
+		s.text = strconv.AppendInt(s.text, int64(x), 10)
+		return
+	case int32:
+		s.text = strconv.AppendInt(s.text, int64(x), 10)
+		return
+	case uint8:
+		s.text = strconv.AppendUint(s.text, uint64(x), 10)
+		return
+	case uint16:
+		s.text = strconv.AppendUint(s.text, uint64(x), 10)
+		return
+	case uint32:
+		s.text = strconv.AppendUint(s.text, uint64(x), 10)
+		return
+	case uintptr:
+		s.text = strconv.AppendUint(s.text, uint64(x), 10)
+		return
+	case float32:
+		s.text = strconv.AppendFloat(s.text, float64(x), 'g', -1, 32)
+		return
+	default:
+		if s.structs && s.writeStruct(x) {
+			return
+		}
+		if s.maps && s.writeCollection(x) {
+			return
+		}
+	}
+	fmt.Fprintf(s, "%v", x)
+}
+
+// writes the result of an Error or String method of x.
+// If the method panics, nothing is written and false is returned, leaving fmt to report the panic.
+func (s *splicer) writeMethod(x any, method func() string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	s.WriteString(method())
+	return true
+}
+
 func (s *splicer) writeValueVerb(v slog.Value, verb string) {
+	// verbs applying to any kind
+	switch verb {
+	case "%q":
+		s.writeValueQuoted(v)
+		return
+	case "json":
+		s.writeValueJSON(v)
+		return
+	case "upper", "lower", "title":
+		s.writeValueCase(v, verb)
+		return
+	case "bytes", "si":
+		s.writeValueHuman(v, verb)
+		return
+	case "hex", "b64", "len":
+		s.writeValueBinary(v, verb)
+		return
+	}
+
 	switch v.Kind() {
 	case slog.KindString:
 		fmt.Fprintf(s, verb, v.String())
@@ -252,7 +705,7 @@ func (s *splicer) writeValueVerb(v slog.Value, verb string) {
 	case slog.KindGroup:
 		s.writeGroup(v.Group())
 	case slog.KindLogValuer:
-		s.writeValueVerb(v.Resolve(), verb)
+		s.writeValueVerb(s.resolve(v), verb)
 	case slog.KindAny:
 		fmt.Fprintf(s, verb, v.Any())
 	default:
@@ -260,6 +713,82 @@ func (s *splicer) writeValueVerb(v slog.Value, verb string) {
 	}
 }
 
+// quotes the verb-less rendering of a value
+func (s *splicer) writeValueQuoted(v slog.Value) {
+	v = s.resolve(v)
+	if v.Kind() == slog.KindString {
+		s.text = strconv.AppendQuote(s.text, v.String())
+		return
+	}
+
+	mark := len(s.text)
+	s.writeValueNoVerb(v)
+	raw := string(s.text[mark:])
+	s.text = strconv.AppendQuote(s.text[:mark], raw)
+}
+
+// writes the verb-less rendering of a value, transformed to upper, lower, or title case
+func (s *splicer) writeValueCase(v slog.Value, verb string) {
+	mark := len(s.text)
+	s.writeValueNoVerb(v)
+	text := s.text[mark:]
+
+	var ascii bool
+	for _, c := range text {
+		if ascii = c < utf8.RuneSelf; !ascii {
+			break
+		}
+	}
+
+	// non-ASCII text may change length when case is mapped
+	if !ascii {
+		var mapped []byte
+		switch verb {
+		case "upper":
+			mapped = bytes.ToUpper(text)
+		case "lower":
+			mapped = bytes.ToLower(text)
+		case "title":
+			mapped = toTitle(text)
+		}
+		s.text = append(s.text[:mark], mapped...)
+		return
+	}
+
+	inWord := false
+	for i, c := range text {
+		switch verb {
+		case "upper":
+			if 'a' <= c && c <= 'z' {
+				text[i] = c - 'a' + 'A'
+			}
+		case "lower":
+			if 'A' <= c && c <= 'Z' {
+				text[i] = c - 'A' + 'a'
+			}
+		case "title":
+			if !inWord && 'a' <= c && c <= 'z' {
+				text[i] = c - 'a' + 'A'
+			}
+			inWord = c != ' ' && c != '\t' && c != '\n'
+		}
+	}
+}
+
+// upper-cases the first letter of each space-separated word
+func toTitle(text []byte) []byte {
+	mapped := make([]byte, 0, len(text))
+	inWord := false
+	for _, r := range string(text) {
+		if !inWord {
+			r = unicode.ToTitle(r)
+		}
+		inWord = !unicode.IsSpace(r)
+		mapped = utf8.AppendRune(mapped, r)
+	}
+	return mapped
+}
+
 func (s *splicer) writeTimeVerb(t time.Time, verb string) {
 	switch verb {
 	case "epoch":
@@ -270,6 +799,8 @@ func (s *splicer) writeTimeVerb(t time.Time, verb string) {
 		s.text = t.AppendFormat(s.text, time.Kitchen)
 	case "stamp":
 		s.text = t.AppendFormat(s.text, time.Stamp)
+	case "ago":
+		s.text = appendAgo(s.text, timeNow().Sub(t), 1)
 	default:
 		// TODO: might be slow /shrug
 		s.text = t.AppendFormat(s.text, strings.Replace(verb, ";", ":", -1))
@@ -280,19 +811,258 @@ func (s *splicer) writeDurationVerb(d time.Duration, verb string) {
 	switch verb {
 	case "epoch":
 		s.text = strconv.AppendInt(s.text, int64(d), 10)
+	case "ago":
+		s.text = appendAgo(s.text, d, 2)
 	default:
 		fmt.Fprintf(s, verb, d.String())
 	}
 }
 
+// the clock used by the "ago" verb
+var timeNow = time.Now
+
+var agoUnits = [...]struct {
+	d    time.Duration
+	name string
+}{
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+	{time.Millisecond, "ms"},
+	{time.Microsecond, "µs"},
+	{time.Nanosecond, "ns"},
+}
+
+// appends a delta of time as, e.g., "3m ago" or "in 5s", using at most n of the most significant units.
+// For time.Time values, units smaller than a second are not used, and deltas under a second are "now".
+// The last unit is truncated when n is 1, and rounded otherwise.
+func appendAgo(dst []byte, d time.Duration, n int) []byte {
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	// most significant unit
+	i := 0
+	for i < len(agoUnits)-1 && d < agoUnits[i].d {
+		i++
+	}
+
+	if n == 1 {
+		if d < time.Second {
+			return append(dst, "now"...)
+		}
+	} else if last := i + n - 1; last < len(agoUnits) {
+		d = d.Round(agoUnits[last].d)
+		// rounding may carry into a more significant unit
+		if i > 0 && d >= agoUnits[i-1].d {
+			i--
+		}
+	}
+
+	if future {
+		dst = append(dst, "in "...)
+	}
+
+	for j := i; j < i+n && j < len(agoUnits); j++ {
+		q := d / agoUnits[j].d
+		d -= q * agoUnits[j].d
+		if q == 0 && j > i {
+			continue
+		}
+		dst = strconv.AppendInt(dst, int64(q), 10)
+		dst = append(dst, agoUnits[j].name...)
+	}
+
+	if !future {
+		dst = append(dst, " ago"...)
+	}
+	return dst
+}
+
+// writes a byte slice or a string in lowercase hex ("hex"), in standard base64 ("b64"), or its length in bytes ("len").
+// Other values are written as without a verb.
+func (s *splicer) writeValueBinary(v slog.Value, verb string) {
+	v = s.resolve(v)
+
+	var data []byte
+	switch v.Kind() {
+	case slog.KindString:
+		if verb == "len" {
+			s.text = strconv.AppendInt(s.text, int64(len(v.String())), 10)
+			return
+		}
+		data = []byte(v.String())
+	case slog.KindAny:
+		var ok bool
+		if data, ok = v.Any().([]byte); ok {
+			break
+		}
+		fallthrough
+	default:
+		s.writeValueNoVerb(v)
+		return
+	}
+
+	switch verb {
+	case "hex":
+		s.text = hex.AppendEncode(s.text, data)
+	case "b64":
+		s.text = base64.StdEncoding.AppendEncode(s.text, data)
+	case "len":
+		s.text = strconv.AppendInt(s.text, int64(len(data)), 10)
+	}
+}
+
+// writes a byte slice in lowercase hex, truncated to max bytes if max is positive.
+// A truncated slice is followed by its full length, as in `0001…(64B)`.
+func (s *splicer) writeHex(data []byte, max int) {
+	n := len(data)
+	if max > 0 && n > max {
+		data = data[:max]
+	}
+	s.text = hex.AppendEncode(s.text, data)
+	if len(data) < n {
+		s.WriteString("…(")
+		s.text = strconv.AppendInt(s.text, int64(n), 10)
+		s.WriteString("B)")
+	}
+}
+
+// writes a non-negative number in IEC byte units ("bytes"), or with SI prefixes ("si").
+// Other values are written as without a verb.
+func (s *splicer) writeValueHuman(v slog.Value, verb string) {
+	v = s.resolve(v)
+
+	var n float64
+	switch v.Kind() {
+	case slog.KindInt64:
+		n = float64(v.Int64())
+	case slog.KindUint64:
+		n = float64(v.Uint64())
+	case slog.KindFloat64:
+		n = v.Float64()
+	case slog.KindAny:
+		switch c := v.Any().(type) {
+		case byteCount:
+			n = float64(c)
+		case siCount:
+			n = float64(c)
+		default:
+			n = -1
+		}
+	default:
+		n = -1
+	}
+
+	// (NaN fails the comparison)
+	if !(n >= 0) || math.IsInf(n, 1) {
+		s.writeValueNoVerb(v)
+		return
+	}
+
+	if verb == "bytes" {
+		s.text = appendBytes(s.text, n)
+	} else {
+		s.text = appendSI(s.text, n)
+	}
+}
+
+// appends n in IEC byte units, e.g. "1.2 MiB"
+func appendBytes(dst []byte, n float64) []byte {
+	if !(n >= 1024) {
+		dst = strconv.AppendFloat(dst, n, 'f', -1, 64)
+		return append(dst, " B"...)
+	}
+
+	const units = "KMGTPE"
+	i := -1
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	// a value rounding up to the next unit takes that unit
+	if math.Round(n*10) >= 1024*10 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	dst = strconv.AppendFloat(dst, n, 'f', 1, 64)
+	dst = append(dst, ' ', units[i], 'i', 'B')
+	return dst
+}
+
+// appends n with an SI prefix, e.g. "3.4M"
+func appendSI(dst []byte, n float64) []byte {
+	if !(n >= 1000) {
+		return strconv.AppendFloat(dst, n, 'f', -1, 64)
+	}
+
+	const units = "kMGTPE"
+	i := -1
+	for n >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	if math.Round(n*10) >= 1000*10 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	dst = strconv.AppendFloat(dst, n, 'f', 1, 64)
+	return append(dst, units[i])
+}
+
+// writes attrs in `key=value` form, flattening groups with dotted keys.
+// sep reports whether a space is needed before the next attr.
+func (s *splicer) writeStar(prefix string, as []Attr, verb []byte, sep *bool) {
+	for _, a := range as {
+		if a.Key == "" {
+			continue
+		}
+
+		v := s.resolve(a.Value)
+		if v.Kind() == slog.KindGroup {
+			s.writeStar(prefix+a.Key+".", v.Group(), verb, sep)
+			continue
+		}
+
+		if *sep {
+			s.WriteByte(' ')
+		}
+		*sep = true
+
+		s.WriteString(prefix)
+		s.WriteString(a.Key)
+		s.WriteByte('=')
+		s.WriteValue(v, verb)
+	}
+}
+
 func (s *splicer) writeGroup(as []Attr) {
+	if s.dots {
+		var sep bool
+		s.WriteByte('[')
+		s.writeStar("", as, nil, &sep)
+		s.WriteByte(']')
+		return
+	}
+
+	// as with slog, empty keys and empty groups are elided
 	next := byte('[')
 	for _, a := range as {
+		v := s.resolve(a.Value)
+		if a.Key == "" || v.Kind() == slog.KindGroup && len(v.Group()) == 0 {
+			continue
+		}
+
 		s.WriteByte(next)
 		s.WriteString(a.Key)
 		s.WriteByte('=')
-		s.writeValueNoVerb(a.Value)
+		s.writeValueNoVerb(v)
 		next = ' '
 	}
+	if next == '[' {
+		s.WriteByte(next)
+	}
 	s.WriteByte(']')
 }
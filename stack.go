@@ -0,0 +1,70 @@
+package logf
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"log/slog"
+)
+
+// maximum number of frames captured in a stack, after eliding frames
+const maxStackDepth = 32
+
+// captures a stack, starting skip frames above the caller of callers.
+// The logging call path, through logf and log/slog, is elided, as are runtime frames;
+// logf frames further down the stack, e.g. of [Logger.Go], are kept.
+func callers(skip int) []runtime.Frame {
+	var pcs [4 * maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+
+	var frames []runtime.Frame
+	callPath := true
+	fs := runtime.CallersFrames(pcs[:n])
+	for len(frames) < maxStackDepth {
+		f, more := fs.Next()
+		callPath = callPath && elideFrame(f)
+		if !callPath && framePkg(f.Function) != "runtime" {
+			frames = append(frames, f)
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// reports whether a frame is on the logging call path
+func elideFrame(f runtime.Frame) bool {
+	switch framePkg(f.Function) {
+	case "github.com/AndrewHarrisSPU/logf", "log/slog", "runtime":
+		return true
+	}
+	return false
+}
+
+// extracts the package path from a fully qualified function name
+func framePkg(fn string) string {
+	slash := strings.LastIndexByte(fn, '/') + 1
+	if dot := strings.IndexByte(fn[slash:], '.'); dot >= 0 {
+		return fn[:slash+dot]
+	}
+	return fn
+}
+
+// stackValue returns a group of frames, keyed by index.
+// Each frame is a group with file, line, and func attrs.
+func stackValue(frames []runtime.Frame) Value {
+	as := make([]Attr, 0, len(frames))
+	for i, f := range frames {
+		as = append(as, slog.Attr{
+			Key: strconv.Itoa(i),
+			Value: slog.GroupValue(
+				slog.String("file", f.File),
+				slog.Int("line", f.Line),
+				slog.String("func", f.Function),
+			),
+		})
+	}
+	return slog.GroupValue(as...)
+}
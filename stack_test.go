@@ -0,0 +1,63 @@
+package logf_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AndrewHarrisSPU/logf"
+)
+
+// logs with the given func, returning the functions of the frames of the logged stack
+func stackFuncs(t *testing.T, log func(logf.Logger)) []string {
+	t.Helper()
+
+	var b bytes.Buffer
+	log(logf.New().Writer(&b).AddStack(logf.ERROR).JSON())
+
+	var rec struct {
+		Stack map[string]struct{ Func string }
+	}
+	if err := json.Unmarshal(b.Bytes(), &rec); err != nil {
+		t.Fatalf("%v: %s", err, b.String())
+	}
+
+	funcs := make([]string, len(rec.Stack))
+	for i := range funcs {
+		funcs[i] = rec.Stack[strconv.Itoa(i)].Func
+	}
+	return funcs
+}
+
+func TestStackElision(t *testing.T) {
+	// logf frames below the caller aren't on the logging call path
+	funcs := stackFuncs(t, func(log logf.Logger) {
+		done := make(chan struct{})
+		log.Go(func() {
+			defer close(done)
+			log.Error("in goroutine", nil)
+		})
+		<-done
+	})
+	if len(funcs) != 2 || !strings.Contains(funcs[0], "TestStackElision") || !strings.HasSuffix(funcs[1], "logf.Logger.Go.func1") {
+		t.Errorf("goroutine: got %q", funcs)
+	}
+
+	// the depth is limited after eliding
+	var recurse func(log logf.Logger, n int)
+	recurse = func(log logf.Logger, n int) {
+		if n == 0 {
+			log.Error("deep", nil)
+			return
+		}
+		recurse(log, n-1)
+	}
+	funcs = stackFuncs(t, func(log logf.Logger) {
+		recurse(log, 100)
+	})
+	if len(funcs) != 32 || !strings.Contains(funcs[0], "TestStackElision") {
+		t.Errorf("depth: got %d frames, first %q", len(funcs), funcs[0])
+	}
+}
@@ -0,0 +1,551 @@
+package logf
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"log/slog"
+)
+
+// Struct wraps a struct value. In a list of arguments given to [Attrs], a Struct is expanded into attributes,
+// as with [StructAttrs]. A Struct is also a [slog.LogValuer], resolving to a group of the same attributes.
+type Struct struct {
+	V any
+}
+
+// LogValue returns a group of the attributes given by [StructAttrs].
+func (st Struct) LogValue() Value {
+	return slog.GroupValue(StructAttrs(st.V)...)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	valuerType   = reflect.TypeOf((*slog.LogValuer)(nil)).Elem()
+)
+
+// StructAttrs returns an Attr for each exported field of a struct, or a pointer to a struct.
+// Other values result in no Attrs.
+//
+// Keys are taken from a field's `logf` tag, falling back to its `json` tag, and then to the field's name.
+// A field tagged "-" is skipped, and a field tagged with the "omitempty" option is skipped when empty, as with [encoding/json].
+// Fields of embedded structs without a tag are promoted.
+//
+// Nested structs are groups, unless they are [slog.LogValuer]s.
+// A pointer to a struct already being expanded is the string "<cycle>".
+// [time.Time], [time.Duration], and error fields are time, duration, and error values.
+func StructAttrs(v any) []Attr {
+	var path []uintptr
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() || slices.Contains(path, rv.Pointer()) {
+			return nil
+		}
+		path = append(path, rv.Pointer())
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var as []Attr
+	structAttrs(&as, rv, path)
+	return as
+}
+
+// the placeholder for a pointer to a struct already being expanded
+const structCycle = "<cycle>"
+
+// appends attrs of the fields of rv; path holds the pointers followed to reach rv
+func structAttrs(list *[]Attr, rv reflect.Value, path []uintptr) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		key, omitEmpty, ok := structKey(field)
+		if !ok {
+			continue
+		}
+
+		fv := rv.Field(i)
+
+		// promote fields of untagged embedded structs
+		if field.Anonymous && key == field.Name {
+			ev, evPath := fv, path
+			if ev.Kind() == reflect.Pointer {
+				if ev.IsNil() || slices.Contains(path, ev.Pointer()) {
+					continue
+				}
+				evPath = append(path, ev.Pointer())
+				ev = ev.Elem()
+			}
+			if ev.Kind() == reflect.Struct && !isSpecialStruct(ev.Type()) {
+				structAttrs(list, ev, evPath)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		*list = append(*list, slog.Attr{Key: key, Value: structValue(fv, path)})
+	}
+}
+
+// returns the key for a field, whether it's tagged omitempty, and whether it's kept at all
+func structKey(field reflect.StructField) (key string, omitEmpty bool, ok bool) {
+	tag, found := field.Tag.Lookup("logf")
+	if !found {
+		tag, found = field.Tag.Lookup("json")
+	}
+
+	if tag == "-" {
+		return "", false, false
+	}
+
+	key, opts, _ := strings.Cut(tag, ",")
+	if key == "" {
+		key = field.Name
+	}
+
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return key, omitEmpty, true
+}
+
+func structValue(fv reflect.Value, path []uintptr) Value {
+	ft := fv.Type()
+
+	switch {
+	case ft == timeType:
+		return slog.TimeValue(fv.Interface().(time.Time))
+	case ft == durationType:
+		return slog.DurationValue(time.Duration(fv.Int()))
+	case ft.Implements(valuerType), ft.Implements(errorType):
+		if isNil(fv) {
+			return slog.AnyValue(nil)
+		}
+		return slog.AnyValue(fv.Interface())
+	}
+
+	switch fv.Kind() {
+	case reflect.Pointer:
+		if fv.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		if fv.Elem().Kind() == reflect.Struct {
+			if slices.Contains(path, fv.Pointer()) {
+				return slog.StringValue(structCycle)
+			}
+			return structValue(fv.Elem(), append(path, fv.Pointer()))
+		}
+		return slog.AnyValue(fv.Elem().Interface())
+	case reflect.Interface:
+		if fv.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		return structValue(fv.Elem(), path)
+	case reflect.Struct:
+		var as []Attr
+		structAttrs(&as, fv, path)
+		return slog.GroupValue(as...)
+	}
+
+	return slog.AnyValue(fv.Interface())
+}
+
+// structs that aren't expanded into groups
+func isSpecialStruct(t reflect.Type) bool {
+	return t == timeType || t.Implements(valuerType) || t.Implements(errorType)
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// as in encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// limits on values rendered by [Logger.Dump]
+const (
+	dumpMaxDepth = 8
+	dumpMaxElems = 64
+)
+
+// dumpValue renders a value as nested groups: struct fields keyed as with [StructAttrs],
+// slices and arrays keyed by index, and maps keyed by sorted keys.
+// Past the depth limit, or at a pointer already being rendered, "..." is rendered.
+// Elements past the element limit are counted under a "more" key.
+func dumpValue(rv reflect.Value, depth int, seen map[uintptr]bool) Value {
+	if !rv.IsValid() {
+		return slog.AnyValue(nil)
+	}
+
+	rt := rv.Type()
+	switch {
+	case rt == timeType, rt == durationType:
+		return structValue(rv, nil)
+	case rt.Implements(valuerType), rt.Implements(errorType):
+		if isNil(rv) {
+			return slog.AnyValue(nil)
+		}
+		return slog.AnyValue(rv.Interface())
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		ptr := rv.Pointer()
+		if seen[ptr] {
+			return slog.StringValue("...")
+		}
+		seen[ptr] = true
+		defer delete(seen, ptr)
+		return dumpValue(rv.Elem(), depth, seen)
+
+	case reflect.Interface:
+		if rv.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		return dumpValue(rv.Elem(), depth, seen)
+
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		if rv.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if depth >= dumpMaxDepth {
+			return slog.StringValue("...")
+		}
+		var as []Attr
+		switch rv.Kind() {
+		case reflect.Struct:
+			dumpStruct(&as, rv, depth, seen)
+		case reflect.Map:
+			dumpMap(&as, rv, depth, seen)
+		default:
+			dumpList(&as, rv, depth, seen)
+		}
+		return slog.GroupValue(as...)
+	}
+
+	if !rv.CanInterface() {
+		return slog.StringValue(rv.String())
+	}
+	return slog.AnyValue(rv.Interface())
+}
+
+func dumpStruct(list *[]Attr, rv reflect.Value, depth int, seen map[uintptr]bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		key, omitEmpty, ok := structKey(field)
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		*list = append(*list, slog.Attr{Key: key, Value: dumpValue(fv, depth+1, seen)})
+	}
+}
+
+func dumpList(list *[]Attr, rv reflect.Value, depth int, seen map[uintptr]bool) {
+	n := rv.Len()
+	for i := 0; i < n && i < dumpMaxElems; i++ {
+		*list = append(*list, slog.Attr{Key: strconv.Itoa(i), Value: dumpValue(rv.Index(i), depth+1, seen)})
+	}
+	if n > dumpMaxElems {
+		*list = append(*list, slog.Int("more", n-dumpMaxElems))
+	}
+}
+
+func dumpMap(list *[]Attr, rv reflect.Value, depth int, seen map[uintptr]bool) {
+	type entry struct {
+		key string
+		v   reflect.Value
+	}
+
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		entries = append(entries, entry{fmt.Sprint(iter.Key()), iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	for i, e := range entries {
+		if i == dumpMaxElems {
+			*list = append(*list, slog.Int("more", len(entries)-dumpMaxElems))
+			break
+		}
+		*list = append(*list, slog.Attr{Key: e.key, Value: dumpValue(e.v, depth+1, seen)})
+	}
+}
+
+// a field of a struct written by [splicer.writeStruct]
+type structField struct {
+	index     []int
+	key       string
+	omitEmpty bool
+}
+
+// caches the exported fields of struct types, keyed by reflect.Type
+var structFieldCache sync.Map
+
+func cachedStructFields(rt reflect.Type) []structField {
+	if fs, ok := structFieldCache.Load(rt); ok {
+		return fs.([]structField)
+	}
+
+	var fs []structField
+	appendStructFields(&fs, rt, nil, []reflect.Type{rt})
+
+	cached, _ := structFieldCache.LoadOrStore(rt, fs)
+	return cached.([]structField)
+}
+
+// appends the fields of rt, promoting fields of untagged embedded structs as [structAttrs] does;
+// index is the path of rt's fields, and types holds the struct types on that path
+func appendStructFields(fs *[]structField, rt reflect.Type, index []int, types []reflect.Type) {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		key, omitEmpty, ok := structKey(field)
+		if !ok {
+			continue
+		}
+
+		fi := append(slices.Clip(index), i)
+
+		if field.Anonymous && key == field.Name {
+			et := field.Type
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct && !isSpecialStruct(et) {
+				if !slices.ContainsFunc(types, func(t reflect.Type) bool { return t == et }) {
+					appendStructFields(fs, et, fi, append(types, et))
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		*fs = append(*fs, structField{fi, key, omitEmpty})
+	}
+}
+
+// writes a struct, or a pointer to one, with field names keyed as with [StructAttrs], as in `{first:Fox last:Mulder}`.
+// Returns false, writing nothing, given a [fmt.Stringer], an error, any other non-struct,
+// or a struct without exported fields.
+func (s *splicer) writeStruct(x any) bool {
+	switch x.(type) {
+	case nil, fmt.Stringer, error:
+		return false
+	}
+
+	rv := reflect.ValueOf(x)
+	ptr := rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct
+	if ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return false
+	}
+
+	fs := cachedStructFields(rv.Type())
+	if len(fs) == 0 {
+		return false
+	}
+
+	if ptr {
+		s.WriteByte('&')
+	}
+	s.writeStructFields(rv, fs)
+	return true
+}
+
+func (s *splicer) writeStructFields(rv reflect.Value, fs []structField) {
+	s.WriteByte('{')
+	var sep bool
+	for _, f := range fs {
+		// fields of nil embedded pointers are skipped
+		fv, err := rv.FieldByIndexErr(f.index)
+		if err != nil || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		if sep {
+			s.WriteByte(' ')
+		}
+		sep = true
+
+		s.WriteString(f.key)
+		s.WriteByte(':')
+
+		// as with %v, nested pointers aren't followed
+		x := fv.Interface()
+		if fv.Kind() == reflect.Pointer || reflect.ValueOf(x).Kind() == reflect.Pointer {
+			fmt.Fprintf(s, "%v", x)
+			continue
+		}
+		s.writeValueNoVerb(slog.AnyValue(x))
+	}
+	s.WriteByte('}')
+}
+
+// limit on the entries of a map, or elements of a slice, written by [splicer.writeCollection]
+const collectionMaxElems = 64
+
+// writes a map as a group, as in `{a:1 b:2}`, with keys sorted if the splicer sorts maps.
+// Slices and arrays of maps or slices are written as lists, as in `[{a:1} {b:2}]`.
+// Entries and elements past a limit are counted, as in `…+10`, and collections nested past a depth limit are elided.
+// Returns false, writing nothing, given anything else.
+func (s *splicer) writeCollection(x any) bool {
+	rv := reflect.ValueOf(x)
+	switch rv.Kind() {
+	case reflect.Map:
+		s.writeMap(rv)
+		return true
+	case reflect.Slice, reflect.Array:
+		switch rv.Type().Elem().Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+			s.writeList(rv)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *splicer) writeMap(rv reflect.Value) {
+	if s.nest == dumpMaxDepth {
+		s.WriteString("{...}")
+		return
+	}
+	s.nest++
+	defer func() { s.nest-- }()
+
+	keys := rv.MapKeys()
+	if s.mapSort {
+		slices.SortFunc(keys, compareKeys)
+	}
+
+	s.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			s.WriteByte(' ')
+		}
+		if i == collectionMaxElems {
+			s.writeMore(len(keys) - i)
+			break
+		}
+		s.writeElem(k)
+		s.WriteByte(':')
+		s.writeElem(rv.MapIndex(k))
+	}
+	s.WriteByte('}')
+}
+
+func (s *splicer) writeList(rv reflect.Value) {
+	if s.nest == dumpMaxDepth {
+		s.WriteString("[...]")
+		return
+	}
+	s.nest++
+	defer func() { s.nest-- }()
+
+	s.WriteByte('[')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			s.WriteByte(' ')
+		}
+		if i == collectionMaxElems {
+			s.writeMore(rv.Len() - i)
+			break
+		}
+		s.writeElem(rv.Index(i))
+	}
+	s.WriteByte(']')
+}
+
+func (s *splicer) writeMore(n int) {
+	s.WriteString("…+")
+	s.text = strconv.AppendInt(s.text, int64(n), 10)
+}
+
+func (s *splicer) writeElem(rv reflect.Value) {
+	if !rv.IsValid() || !rv.CanInterface() {
+		fmt.Fprintf(s, "%v", rv)
+		return
+	}
+	s.writeValueNoVerb(slog.AnyValue(rv.Interface()))
+}
+
+// orders map keys as fmt does for basic kinds; other keys are ordered by their %v rendering
+func compareKeys(a, b reflect.Value) int {
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.String:
+			return strings.Compare(a.String(), b.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(a.Uint(), b.Uint())
+		case reflect.Float32, reflect.Float64:
+			return cmp.Compare(a.Float(), b.Float())
+		case reflect.Bool:
+			switch {
+			case a.Bool() == b.Bool():
+				return 0
+			case !a.Bool():
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
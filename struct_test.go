@@ -0,0 +1,200 @@
+package logf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID int `logf:"id"`
+}
+
+type testAddr struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testUser struct {
+	testBase
+	Name    string        `logf:"name"`
+	Email   string        `json:"email,omitempty"`
+	Pass    string        `logf:"-"`
+	Addr    testAddr      `logf:"addr"`
+	Backup  *testAddr     `logf:"backup"`
+	Spare   *testAddr     `logf:"spare,omitempty"`
+	Since   time.Time     `logf:"since"`
+	Timeout time.Duration `logf:"timeout"`
+	Err     error         `logf:"err"`
+	Count   *int          `logf:"count"`
+	Plain   bool
+	private string
+}
+
+func TestStructAttrs(t *testing.T) {
+	want := func(ok string, got string) {
+		t.Helper()
+		if ok != got {
+			t.Errorf("\nwant: %s\ngot:  %s", ok, got)
+		}
+	}
+
+	n := 3
+	u := testUser{
+		testBase: testBase{7},
+		Name:     "gopher",
+		Pass:     "secret",
+		Addr:     testAddr{City: "Roswell"},
+		Backup:   &testAddr{City: "Area 51", Zip: "89001"},
+		Since:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Timeout:  time.Second,
+		Err:      errors.New("lost"),
+		Count:    &n,
+		private:  "x",
+	}
+
+	as := StructAttrs(&u)
+	want("[id=7 name=gopher addr=[city=Roswell] backup=[city=Area 51 zip=89001] since=2023-01-02 03:04:05 +0000 UTC timeout=1s err=lost count=3 Plain=false]", fmt.Sprint(as))
+
+	kinds := map[string]string{
+		"since":   "Time",
+		"timeout": "Duration",
+		"addr":    "Group",
+		"count":   "Int64",
+	}
+	for _, a := range as {
+		if kind, ok := kinds[a.Key]; ok && a.Value.Kind().String() != kind {
+			t.Errorf("%s: want %s, got %s", a.Key, kind, a.Value.Kind())
+		}
+	}
+
+	// nil pointers
+	var nilUser *testUser
+	if as := StructAttrs(nilUser); as != nil {
+		t.Errorf("nil: got %v", as)
+	}
+	u.Backup = nil
+	u.Err = nil
+	want("[id=7 name=gopher addr=[city=Roswell] backup=<nil> since=2023-01-02 03:04:05 +0000 UTC timeout=1s err=<nil> count=3 Plain=false]", fmt.Sprint(StructAttrs(u)))
+
+	// non-structs
+	if as := StructAttrs(1); as != nil {
+		t.Errorf("non-struct: got %v", as)
+	}
+
+	// in Attrs, and interpolation
+	want("[k=v city=Roswell]", fmt.Sprint(Attrs("k", "v", Struct{testAddr{City: "Roswell"}})))
+	want("Roswell", Fmt("{addr.city}", Struct{u}))
+}
+
+type testLink struct {
+	Name string
+	Next *testLink
+}
+
+type testEmbedLink struct {
+	*testEmbedLink
+	Name string
+}
+
+func TestStructCycle(t *testing.T) {
+	n := &testLink{Name: "a"}
+	n.Next = &testLink{Name: "b", Next: n}
+
+	if got := fmt.Sprint(StructAttrs(n)); got != "[Name=a Next=[Name=b Next=<cycle>]]" {
+		t.Errorf("cycle: got %s", got)
+	}
+
+	self := &testLink{Name: "self"}
+	self.Next = self
+	if got := fmt.Sprint(StructAttrs(self)); got != "[Name=self Next=<cycle>]" {
+		t.Errorf("self: got %s", got)
+	}
+
+	e := &testEmbedLink{Name: "e"}
+	e.testEmbedLink = e
+	if got := fmt.Sprint(StructAttrs(e)); got != "[Name=e]" {
+		t.Errorf("embedded: got %s", got)
+	}
+
+	// shared, acyclic pointers are expanded each time
+	shared := &testLink{Name: "s"}
+	pair := struct{ A, B *testLink }{shared, shared}
+	if got := fmt.Sprint(StructAttrs(pair)); got != "[A=[Name=s Next=<nil>] B=[Name=s Next=<nil>]]" {
+		t.Errorf("shared: got %s", got)
+	}
+
+	var b bytes.Buffer
+	New().Writer(&b).JSON().Info("x", "n", Struct{self})
+	if !strings.Contains(b.String(), `"n":{"Name":"self","Next":"<cycle>"}`) {
+		t.Errorf("logged: got %s", b.String())
+	}
+}
+
+type testNode struct {
+	Name string
+	Next *testNode
+	Tags map[string]int
+	List []int
+	note string
+}
+
+func TestDump(t *testing.T) {
+	var b bytes.Buffer
+	var ref slog.LevelVar
+	ref.Set(DEBUG)
+
+	log := New().
+		Writer(&b).
+		Ref(&ref).
+		ShowLayout("message", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	n := &testNode{
+		Name: "a",
+		Tags: map[string]int{"z": 1, "y": 2},
+		List: []int{1, 2},
+		note: "hidden",
+	}
+	n.Next = n
+
+	log.Dump("n", n)
+	want := "dump n:{Name:a Next:... Tags:{y:2 z:1} List:{0:1 1:2}}\n"
+	if got := b.String(); got != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+	b.Reset()
+
+	// element limit
+	log.Dump("", make([]int, dumpMaxElems+3))
+	if got := b.String(); !strings.HasSuffix(got, fmt.Sprintf("%d:0 more:3}\n", dumpMaxElems-1)) {
+		t.Errorf("elements: got %q", got)
+	}
+	b.Reset()
+
+	// depth limit
+	var deep any = 1
+	for i := 0; i < dumpMaxDepth+2; i++ {
+		deep = []any{deep}
+	}
+	log.Dump("deep", deep)
+	if got := b.String(); strings.Count(got, "{") != dumpMaxDepth || !strings.Contains(got, "0:...") {
+		t.Errorf("depth: got %q", got)
+	}
+	b.Reset()
+
+	// disabled
+	log = New().Writer(&b).ForceTTY(true).Logger()
+	wantAllocs(t, "disabled dump", 0, func() {
+		log.Dump("n", n)
+	})
+	if b.Len() > 0 {
+		t.Errorf("disabled: got %q", b.String())
+	}
+}
@@ -1,10 +1,15 @@
 package logf
 
 import (
+	"net/url"
 	"path/filepath"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"log/slog"
 )
@@ -122,6 +127,8 @@ func newPen(s string) pen {
 
 func (tty *TTY) levelPen(level slog.Level) (p pen) {
 	switch {
+	case level < DEBUG:
+		p = tty.dev.fmtr.tracePen
 	case level < INFO:
 		p = tty.dev.fmtr.debugPen
 	case level < WARN:
@@ -140,11 +147,20 @@ func init() {
 	LevelBar = EncodeFunc(encLevelBar)
 	LevelBullet = EncodeFunc(encLevelBullet)
 	LevelText = EncodeFunc(encLevelText)
+	LevelEmoji = EncodeFunc(encLevelEmoji)
+	LevelNerd = EncodeFunc(encLevelNerd)
 	TimeShort = EncodeFunc(encTimeShort)
+	TimeShortMillis = EncodeFunc(encTimeShortMillis)
+	TimeShortMicro = EncodeFunc(encTimeShortMicro)
+	TimeDelta = timeDelta{}
 	TimeRFC3339Nano = EncodeFunc(encTimeRFC3339Nano)
 	SourceAbs = EncodeFunc(encSourceAbs)
 	SourceShort = EncodeFunc(encSourceShort)
 	SourcePkg = EncodeFunc(encSourcePkg)
+	SourceRel = EncodeFunc(encSourceRel)
+	SourceFunc = EncodeFunc(encSourceFunc)
+	AttrsJSON = EncodeFunc(encAttrsJSON)
+	AttrsLogfmt = EncodeFunc(encAttrsLogfmt)
 }
 
 var (
@@ -157,9 +173,24 @@ var (
 	// [slog.Level.String] text
 	LevelText Encoder[slog.Level]
 
+	// an emoji depiction of log level: 🐛, ℹ️, ⚠️, or ❌
+	LevelEmoji Encoder[slog.Level]
+
+	// a Nerd Font depiction of log level, requiring a patched font
+	LevelNerd Encoder[slog.Level]
+
 	// with time format "15:04:05"
 	TimeShort Encoder[time.Time]
 
+	// with time format "15:04:05.000"
+	TimeShortMillis Encoder[time.Time]
+
+	// with time format "15:04:05.000000"
+	TimeShortMicro Encoder[time.Time]
+
+	// time since the previous record handled by the TTY, e.g. "+0.250s"
+	TimeDelta Encoder[time.Time]
+
 	// with time format "15:04:05"
 	TimeRFC3339Nano Encoder[time.Time]
 
@@ -171,8 +202,76 @@ var (
 
 	// just the package
 	SourcePkg Encoder[*slog.Source]
+
+	// source file path relative to the main module root, plus line number
+	// Files outside the main module are qualified by package import path.
+	SourceRel Encoder[*slog.Source]
+
+	// last element of the package path, function name, and line number
+	SourceFunc Encoder[*slog.Source]
+
+	// a compact JSON object, with groups as nested objects
+	AttrsJSON Encoder[[]Attr]
+
+	// logfmt key=value pairs, with dotted keys in groups
+	AttrsLogfmt Encoder[[]Attr]
 )
 
+func encAttrsJSON(b *Buffer, as []Attr) {
+	b.text = appendJSONGroup(b.text, as, b.reveal, b.timePrec)
+}
+
+func encAttrsLogfmt(b *Buffer, as []Attr) {
+	appendLogfmt(b, len(b.text), "", as)
+}
+
+// appends key=value for each attr, separated by spaces from text written since start;
+// keys in groups are prefixed with the group key, and the attrs of a group with an empty key are inlined
+func appendLogfmt(b *Buffer, start int, prefix string, as []Attr) {
+	for _, a := range as {
+		v := b.resolve(a.Value)
+		if v.Kind() == slog.KindGroup {
+			if a.Key != "" {
+				appendLogfmt(b, start, prefix+a.Key+".", v.Group())
+			} else {
+				appendLogfmt(b, start, prefix, v.Group())
+			}
+			continue
+		}
+		if a.Key == "" {
+			continue
+		}
+
+		if len(b.text) > start {
+			b.WriteByte(' ')
+		}
+		mark := len(b.text)
+		b.WriteString(prefix)
+		b.WriteString(a.Key)
+		logfmtQuoteSince(b, mark)
+
+		b.WriteByte('=')
+		mark = len(b.text)
+		b.WriteValue(v, nil)
+		logfmtQuoteSince(b, mark)
+	}
+}
+
+// quotes text written since mark, if it is empty or holds whitespace, '=', '"', or control characters
+func logfmtQuoteSince(b *Buffer, mark int) {
+	text := b.text[mark:]
+	quote := len(text) == 0 || !utf8.Valid(text)
+	for _, r := range string(text) {
+		if quote {
+			break
+		}
+		quote = r == '=' || r == '"' || unicode.IsSpace(r) || unicode.IsControl(r)
+	}
+	if quote {
+		b.text = strconv.AppendQuote(b.text[:mark], string(text))
+	}
+}
+
 func encGroupOpen(b *Buffer, count int) {
 	b.WriteString("{")
 }
@@ -183,33 +282,92 @@ func encGroupClose(b *Buffer, count int) {
 	}
 }
 
+// QUOTING
+
+// quoteMode determines when keys and values are quoted by default encoders
+type quoteMode uint8
+
+const (
+	quoteAuto quoteMode = iota
+	quoteAlways
+	quoteNever
+)
+
+// quotes text written since mark, according to the splicer's quoting mode
+func (b *Buffer) quoteSince(mark int) {
+	switch b.quote {
+	case quoteNever:
+		return
+	case quoteAuto:
+		if !needsQuote(b.text[mark:]) {
+			return
+		}
+	}
+
+	raw := string(b.text[mark:])
+	b.text = strconv.AppendQuote(b.text[:mark], raw)
+}
+
+// reports whether text is ambiguous in a TTY line:
+// whitespace, ':', '{', '}', control characters, or invalid UTF-8
+func needsQuote(text []byte) bool {
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return true
+		case r == ':', r == '{', r == '}':
+			return true
+		case unicode.IsSpace(r), unicode.IsControl(r):
+			return true
+		}
+		text = text[size:]
+	}
+	return false
+}
+
 func encKey(b *Buffer, key string) {
+	mark := len(b.text)
 	b.WriteString(key)
+	b.quoteSince(mark)
 	b.WriteString(":")
 }
 
 func encValue(b *Buffer, v Value) {
+	mark := len(b.text)
 	b.WriteValue(v, nil)
+	b.quoteSince(mark)
 }
 
 func encTag(b *Buffer, a Attr) {
 	b.WriteValue(a.Value, nil)
 }
 
-func encLevelText(b *Buffer, level slog.Level) {
-	// compute padding
-	width := len(level.String())
-
-	pad := (12 - width) / 2
-	pad1 := width % 2
+// returns the name of a level, as written by a TTY and in JSON.
+// Levels below DEBUG are named relative to TRACE, e.g. "TRACE+1", rather than as slog names them.
+func levelName(level slog.Level) string {
+	if level >= DEBUG {
+		return level.String()
+	}
+	switch d := int(level - TRACE); {
+	case d == 0:
+		return "TRACE"
+	case d > 0:
+		return "TRACE+" + strconv.Itoa(d)
+	default:
+		return "TRACE" + strconv.Itoa(d)
+	}
+}
 
-	b.WriteString("      "[:pad+pad1-1])
-	b.WriteString(level.String())
-	b.WriteString("      "[:pad])
+func encLevelText(b *Buffer, level slog.Level) {
+	b.WriteString(levelName(level))
+	b.Pad(11, '^')
 }
 
 func encLevelBullet(b *Buffer, level slog.Level) {
 	switch {
+	case level < DEBUG:
+		b.WriteString(" · ")
 	case level < INFO:
 		b.WriteString(" ╴ ")
 	case level < WARN:
@@ -221,8 +379,50 @@ func encLevelBullet(b *Buffer, level slog.Level) {
 	}
 }
 
+// LevelBarStyle configures a level [Encoder] returned by [LevelBarWith].
+type LevelBarStyle struct {
+	// Text written for levels below INFO, below WARN, below ERROR, and at or above ERROR
+	Debug, Info, Warn, Error string
+
+	// Text written for levels below DEBUG. If empty, Debug text is written.
+	Trace string
+
+	// Width, in terminal cells, to which text is padded or truncated.
+	// As no space follows the level field, the width includes any spacing; LevelBar is three cells wide.
+	// If Width is not positive, text is written as is.
+	Width int
+}
+
+// LevelBarWith returns a level [Encoder] writing text as configured by the style.
+// For example, ASCII-only bars:
+//
+//	logf.LevelBarWith(logf.LevelBarStyle{Debug: " .", Info: " |", Warn: "||", Error: "##", Width: 3})
+func LevelBarWith(style LevelBarStyle) Encoder[slog.Level] {
+	return EncodeFunc(func(b *Buffer, level slog.Level) {
+		switch {
+		case level < DEBUG && style.Trace != "":
+			b.WriteString(style.Trace)
+		case level < INFO:
+			b.WriteString(style.Debug)
+		case level < WARN:
+			b.WriteString(style.Info)
+		case level < ERROR:
+			b.WriteString(style.Warn)
+		default:
+			b.WriteString(style.Error)
+		}
+
+		if style.Width > 0 {
+			b.text = b.text[:b.mark+cellPrefix(b.text[b.mark:], style.Width)]
+			b.Pad(style.Width, '<')
+		}
+	})
+}
+
 func encLevelBar(b *Buffer, level slog.Level) {
 	switch {
+	case level < DEBUG:
+		b.WriteString(" ╎ ")
 	case level < INFO:
 		b.WriteString(" ▏ ")
 	case level < WARN:
@@ -234,8 +434,84 @@ func encLevelBar(b *Buffer, level slog.Level) {
 	}
 }
 
+// padded to three cells, the width of LevelBar
+func encLevelEmoji(b *Buffer, level slog.Level) {
+	switch {
+	case level < DEBUG:
+		b.WriteString("👣")
+	case level < INFO:
+		b.WriteString("🐛")
+	case level < WARN:
+		b.WriteString("ℹ️")
+	case level < ERROR:
+		b.WriteString("⚠️")
+	default:
+		b.WriteString("❌")
+	}
+	b.Pad(3, '<')
+}
+
+func encLevelNerd(b *Buffer, level slog.Level) {
+	switch {
+	case level < DEBUG:
+		b.WriteString(" \uea6d ") // nf-cod-search
+	case level < INFO:
+		b.WriteString(" \uead8 ") // nf-cod-debug
+	case level < WARN:
+		b.WriteString(" \uf05a ") // nf-fa-info_circle
+	case level < ERROR:
+		b.WriteString(" \uf071 ") // nf-fa-warning
+	default:
+		b.WriteString(" \uf057 ") // nf-fa-times_circle
+	}
+}
+
 func encTimeShort(b *Buffer, t time.Time) {
-	b.WriteString(t.Format("15:04:05"))
+	appendClock(b, t)
+}
+
+func encTimeShortMillis(b *Buffer, t time.Time) {
+	appendClock(b, t)
+	b.WriteByte('.')
+	itoa(&b.text, t.Nanosecond()/1e6, 3)
+}
+
+func encTimeShortMicro(b *Buffer, t time.Time) {
+	appendClock(b, t)
+	b.WriteByte('.')
+	itoa(&b.text, t.Nanosecond()/1e3, 6)
+}
+
+// appends "15:04:05"
+func appendClock(b *Buffer, t time.Time) {
+	hour, minute, sec := t.Clock()
+	itoa(&b.text, hour, 2)
+	b.WriteByte(':')
+	itoa(&b.text, minute, 2)
+	b.WriteByte(':')
+	itoa(&b.text, sec, 2)
+}
+
+// timeDelta encodes the time since the previous record handled by a TTY.
+// A TTY recognizes it, and keeps the time of the previous record only when using it.
+type timeDelta struct{}
+
+func (timeDelta) Encode(b *Buffer, t time.Time) {
+	var d time.Duration
+	if !b.prevTime.IsZero() {
+		d = t.Sub(b.prevTime)
+	}
+
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	} else {
+		b.WriteByte('+')
+	}
+	itoa(&b.text, int(d/time.Second), 1)
+	b.WriteByte('.')
+	itoa(&b.text, int(d%time.Second/time.Millisecond), 3)
+	b.WriteByte('s')
 }
 
 func encTimeRFC3339Nano(b *Buffer, t time.Time) {
@@ -248,12 +524,125 @@ func encSourcePkg(b *Buffer, src *slog.Source) {
 
 func encSourceShort(b *Buffer, src *slog.Source) {
 	b.WriteString(filepath.Base(src.File))
-	b.WriteString(":")
-	b.WriteString(strconv.Itoa(src.Line))
+	b.WriteByte(':')
+	b.AppendInt(int64(src.Line))
 }
 
 func encSourceAbs(b *Buffer, src *slog.Source) {
 	b.WriteString(src.File)
-	b.WriteString(":")
-	b.WriteString(strconv.Itoa(src.Line))
+	b.WriteByte(':')
+	b.AppendInt(int64(src.Line))
+}
+
+func encSourceRel(b *Buffer, src *slog.Source) {
+	b.WriteString(sourceRelPath(src))
+	b.WriteByte(':')
+	b.AppendInt(int64(src.Line))
+}
+
+func encSourceFunc(b *Buffer, src *slog.Source) {
+	fn := src.Function
+	if fn == "" {
+		fn = filepath.Base(src.File)
+	}
+	b.WriteString(fn[strings.LastIndexByte(fn, '/')+1:])
+	b.WriteByte(':')
+	b.AppendInt(int64(src.Line))
+}
+
+// SourceLink returns an [Encoder] wrapping the text written by enc in an OSC 8 hyperlink, which many terminals render as clickable.
+// If enc is nil, [SourceAbs] is used.
+//
+// The link target is given by expanding "{file}" and "{line}" in the template.
+// The file is absolute, slash-separated, percent-escaped, and begins with "/".
+// Some templates have short names:
+//   - "file": "file://{file}"
+//   - "vscode": "vscode://file{file}:{line}"
+//
+// Links are only written by a [TTY] that uses colors and writes to a terminal. Otherwise, just the text of enc is written.
+func SourceLink(template string, enc Encoder[*slog.Source]) Encoder[*slog.Source] {
+	if enc == nil {
+		enc = SourceAbs
+	}
+
+	switch template {
+	case "file":
+		template = "file://{file}"
+	case "vscode":
+		template = "vscode://file{file}:{line}"
+	}
+
+	return EncodeFunc(func(b *Buffer, src *slog.Source) {
+		if !b.links || src == nil || src.File == "" {
+			enc.Encode(b, src)
+			return
+		}
+
+		b.WriteString("\x1b]8;;")
+		b.WriteString(sourceURL(template, src))
+		b.WriteString("\x1b\\")
+		enc.Encode(b, src)
+		b.WriteString("\x1b]8;;\x1b\\")
+	})
+}
+
+// expands a SourceLink template
+func sourceURL(template string, src *slog.Source) string {
+	file := filepath.ToSlash(src.File)
+	if !strings.HasPrefix(file, "/") {
+		file = "/" + file
+	}
+	file = (&url.URL{Path: file}).EscapedPath()
+
+	return strings.NewReplacer(
+		"{file}", file,
+		"{line}", strconv.Itoa(src.Line),
+	).Replace(template)
+}
+
+var mainModule struct {
+	once sync.Once
+	path string // main module path
+	pkg  string // main package path
+}
+
+// returns the main module and package paths, as reported by [debug.ReadBuildInfo]
+func mainModulePaths() (mod, pkg string) {
+	mainModule.once.Do(func() {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			mainModule.path = bi.Main.Path
+			mainModule.pkg = bi.Path
+		}
+	})
+	return mainModule.path, mainModule.pkg
+}
+
+// returns the file path of src relative to the main module root.
+// Package main is located by the main package path.
+// Files in other modules are qualified by their package's import path.
+// Lacking a function name, only the base of the file name is returned.
+func sourceRelPath(src *slog.Source) string {
+	file := filepath.Base(src.File)
+	if src.Function == "" {
+		return file
+	}
+
+	mod, mainPkg := mainModulePaths()
+
+	pkg := framePkg(src.Function)
+	if pkg == "main" {
+		pkg = mainPkg
+	}
+
+	switch {
+	case mod == "":
+		return file
+	case pkg == mod:
+		return file
+	case strings.HasPrefix(pkg, mod+"/"):
+		return pkg[len(mod)+1:] + "/" + file
+	case pkg == "main" || pkg == "command-line-arguments":
+		return file
+	}
+	return pkg + "/" + file
 }
@@ -0,0 +1,135 @@
+package testlog
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// buffer holds the output of a handler under test.
+// Its assertions are shared by [TB] and the [Substrings]-style helpers; they report failures with t, and don't clear the buffer.
+//
+// A buffer is safe for concurrent use: handlers may write to it from any goroutine while a test asserts.
+type buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffer.
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// String returns a copy of buffered output.
+func (b *buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Reset clears buffered output.
+func (b *buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+// reports whether the buffer contains want
+func (b *buffer) wantSubstring(t testing.TB, want string) bool {
+	t.Helper()
+	text := b.String()
+	if strings.Contains(text, want) {
+		return true
+	}
+
+	t.Errorf("\nwant: %s\nin:\n%s", want, listing(text))
+	return false
+}
+
+// reports whether the buffer doesn't contain unwanted
+func (b *buffer) notWantSubstring(t testing.TB, unwanted string) bool {
+	t.Helper()
+	text := b.String()
+	if !strings.Contains(text, unwanted) {
+		return true
+	}
+
+	t.Errorf("\nnot want: %s\nin:\n%s", unwanted, listing(text))
+	return false
+}
+
+// reports whether the buffer holds n newline-terminated records
+func (b *buffer) wantCount(t testing.TB, n int) bool {
+	t.Helper()
+	text := b.String()
+	got := strings.Count(text, "\n")
+	if got == n {
+		return true
+	}
+
+	t.Errorf("\nwant %d records, got %d\nin:\n%s", n, got, listing(text))
+	return false
+}
+
+// reports whether the buffer matches pattern
+func (b *buffer) wantPattern(t testing.TB, pattern string) bool {
+	t.Helper()
+	re, ok := compile(t, pattern)
+	if !ok {
+		return false
+	}
+	text := b.String()
+	if re.MatchString(text) {
+		return true
+	}
+
+	t.Errorf("\nwant match: %s\nin:\n%s", pattern, listing(text))
+	return false
+}
+
+// reports whether the buffer holds n non-overlapping matches of pattern
+func (b *buffer) wantPatternN(t testing.TB, pattern string, n int) bool {
+	t.Helper()
+	re, ok := compile(t, pattern)
+	if !ok {
+		return false
+	}
+	text := b.String()
+	got := len(re.FindAllStringIndex(text, -1))
+	if got == n {
+		return true
+	}
+
+	t.Errorf("\nwant %d matches, got %d: %s\nin:\n%s", n, got, pattern, listing(text))
+	return false
+}
+
+// returns buffered output with numbered lines, for failure reports
+func listing(text string) string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return "\t(no output)"
+	}
+
+	var list strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(&list, "\t%3d | %s\n", i+1, line)
+	}
+	return strings.TrimSuffix(list.String(), "\n")
+}
+
+// compiles pattern, or reports why it can't be
+func compile(t testing.TB, pattern string) (*regexp.Regexp, bool) {
+	t.Helper()
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Errorf("bad pattern: %v", err)
+		return nil, false
+	}
+	return re, true
+}
@@ -0,0 +1,240 @@
+package testlog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Update, if set, makes [GoldenFile.Check] write golden files rather than compare against them.
+// It is initially set if the TESTLOG_UPDATE environment variable is non-empty.
+// No flag is registered; a test package wanting one may set Update from its own, e.g. in TestMain:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	func TestMain(m *testing.M) {
+//		flag.Parse()
+//		testlog.Update = *update
+//		os.Exit(m.Run())
+//	}
+var Update = os.Getenv("TESTLOG_UPDATE") != ""
+
+// GoldenFile is an [io.Writer] capturing output to compare against a golden file, returned by [Golden].
+type GoldenFile struct {
+	// ReplaceFunc, if set, is applied to each line of captured output before comparison,
+	// e.g. to normalize or strip timestamps.
+	ReplaceFunc func(line string) string
+
+	// If StripANSI is set, ANSI escape sequences are removed from captured output before comparison.
+	StripANSI bool
+
+	t    testing.TB
+	path string
+	buf  buffer
+}
+
+// Golden returns a [GoldenFile] comparing captured output against "testdata/<name>.golden".
+// Pass the GoldenFile as a writer, e.g. to logf's Config.Writer, and then call [GoldenFile.Check].
+//
+// If [Update] is set, Check writes the golden file rather than comparing against it.
+func Golden(t testing.TB, name string) *GoldenFile {
+	return &GoldenFile{
+		t:    t,
+		path: filepath.Join("testdata", name+".golden"),
+	}
+}
+
+// Write appends p to captured output.
+func (g *GoldenFile) Write(p []byte) (int, error) {
+	return g.buf.Write(p)
+}
+
+// Check compares captured output against the golden file.
+// On a mismatch, t.Errorf is called with a unified diff.
+// Calling Check clears captured output.
+func (g *GoldenFile) Check() bool {
+	g.t.Helper()
+	defer g.buf.Reset()
+
+	got := g.normalize(g.buf.String())
+
+	if Update {
+		if err := os.MkdirAll(filepath.Dir(g.path), 0o755); err != nil {
+			g.t.Errorf("golden: %v", err)
+			return false
+		}
+		if err := os.WriteFile(g.path, []byte(got), 0o644); err != nil {
+			g.t.Errorf("golden: %v", err)
+			return false
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(g.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		g.t.Errorf("golden: %s doesn't exist; set TESTLOG_UPDATE=1 to create it", g.path)
+		return false
+	}
+	if err != nil {
+		g.t.Errorf("golden: %v", err)
+		return false
+	}
+
+	if string(want) == got {
+		return true
+	}
+	g.t.Errorf("golden: output differs from %s:\n%s", g.path, unifiedDiff(g.path, "got", string(want), got))
+	return false
+}
+
+var ansiSeq = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b\][^\x1b\a]*(?:\x1b\\|\a)`)
+
+func (g *GoldenFile) normalize(text string) string {
+	if g.StripANSI {
+		text = ansiSeq.ReplaceAllString(text, "")
+	}
+	if g.ReplaceFunc == nil {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		body, nl := strings.CutSuffix(line, "\n")
+		if nl {
+			lines[i] = g.ReplaceFunc(body) + "\n"
+		} else if body != "" {
+			lines[i] = g.ReplaceFunc(body)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// DIFF
+
+// lines of context surrounding changes in a hunk
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-', or '+'
+	line string
+}
+
+// returns a unified diff of lines, from a to b
+func unifiedDiff(nameA, nameB, a, b string) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for i := 0; i < len(ops); {
+		// find the next change
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// extend the hunk through changes separated by no more than twice the context
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		// line numbers of the hunk
+		lineA, lineB := 1, 1
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				lineA++
+			}
+			if op.kind != '-' {
+				lineB++
+			}
+		}
+		var countA, countB int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&sb, "%c%s\n", op.kind, op.line)
+		}
+		i = end
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines finds a shortest edit from a to b, by way of a longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
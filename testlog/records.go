@@ -0,0 +1,234 @@
+package testlog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"log/slog"
+)
+
+// Recorder is a [slog.Handler] retaining every record it handles, with assertions about the most recent one.
+// A Recorder derived with WithAttrs or WithGroup captures into the same records.
+// Captured records hold the attrs and groups of the derived handler, as if they were given with the record.
+//
+// Assertions call t.Errorf, with a rendering of the record, when they fail.
+type Recorder struct {
+	t testing.TB
+	*recorderState
+
+	// attrs and groups, outermost first; attrs of a frame precede the group it opens
+	frames []recorderFrame
+}
+
+type recorderState struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+type recorderFrame struct {
+	attrs []slog.Attr
+	group string
+}
+
+// Records returns a [Recorder] reporting failed assertions with t.
+func Records(t testing.TB) *Recorder {
+	return &Recorder{
+		t:             t,
+		recorderState: new(recorderState),
+		frames:        []recorderFrame{{}},
+	}
+}
+
+// slog.Handler methods
+
+func (rec *Recorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+func (rec *Recorder) Handle(ctx context.Context, r slog.Record) error {
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(rec.nest(r)...)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.records = append(rec.records, r2)
+	return nil
+}
+
+func (rec *Recorder) WithAttrs(as []slog.Attr) slog.Handler {
+	if len(as) == 0 {
+		return rec
+	}
+
+	rec2 := *rec
+	rec2.frames = append([]recorderFrame{}, rec.frames...)
+	last := &rec2.frames[len(rec2.frames)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], as...)
+	return &rec2
+}
+
+func (rec *Recorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return rec
+	}
+
+	rec2 := *rec
+	rec2.frames = append([]recorderFrame{}, rec.frames...)
+	rec2.frames[len(rec2.frames)-1].group = name
+	rec2.frames = append(rec2.frames, recorderFrame{})
+	return &rec2
+}
+
+// returns the attrs of the record, nested in the handler's frames
+func (rec *Recorder) nest(r slog.Record) []slog.Attr {
+	var as []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		as = append(as, a)
+		return true
+	})
+
+	for i := len(rec.frames) - 1; i >= 0; i-- {
+		f := rec.frames[i]
+		// (as with slog handlers, an empty group is elided)
+		if f.group != "" && len(as) > 0 {
+			as = []slog.Attr{{Key: f.group, Value: slog.GroupValue(as...)}}
+		}
+		as = append(append([]slog.Attr{}, f.attrs...), as...)
+	}
+	return as
+}
+
+// Records
+
+// Records returns a copy of the records captured so far.
+func (rec *Recorder) Records() []slog.Record {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rs := make([]slog.Record, len(rec.records))
+	for i, r := range rec.records {
+		rs[i] = r.Clone()
+	}
+	return rs
+}
+
+// returns the most recent record, or reports that there is none
+func (rec *Recorder) last() (slog.Record, bool) {
+	rec.t.Helper()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.records) == 0 {
+		rec.t.Errorf("no records")
+		return slog.Record{}, false
+	}
+	return rec.records[len(rec.records)-1].Clone(), true
+}
+
+// Asserts
+
+// WantMessage tests whether the most recent record has the given message.
+func (rec *Recorder) WantMessage(msg string) bool {
+	rec.t.Helper()
+	r, ok := rec.last()
+	if !ok {
+		return false
+	}
+	if r.Message != msg {
+		rec.t.Errorf("\nwant message: %q\nin: %s", msg, render(r))
+		return false
+	}
+	return true
+}
+
+// WantLevel tests whether the most recent record has the given level.
+func (rec *Recorder) WantLevel(level slog.Level) bool {
+	rec.t.Helper()
+	r, ok := rec.last()
+	if !ok {
+		return false
+	}
+	if r.Level != level {
+		rec.t.Errorf("\nwant level: %s\nin: %s", level, render(r))
+		return false
+	}
+	return true
+}
+
+// WantAttr tests whether the most recent record has a top-level attr with the given key and value.
+// Values are compared as by [slog.Value.Equal], after resolving, with the given value converted by [slog.AnyValue].
+func (rec *Recorder) WantAttr(key string, value any) bool {
+	rec.t.Helper()
+	r, ok := rec.last()
+	if !ok {
+		return false
+	}
+
+	want := slog.AnyValue(value).Resolve()
+	if a, found := findAttr(r, []string{key}); found && a.Value.Resolve().Equal(want) {
+		return true
+	}
+	rec.t.Errorf("\nwant attr: %s=%s\nin: %s", key, want, render(r))
+	return false
+}
+
+// WantGroupAttr tests whether the most recent record has an attr at the given path of keys,
+// the last key naming an attr and any earlier keys naming the groups holding it.
+func (rec *Recorder) WantGroupAttr(path ...string) bool {
+	rec.t.Helper()
+	r, ok := rec.last()
+	if !ok {
+		return false
+	}
+	if _, found := findAttr(r, path); found {
+		return true
+	}
+	rec.t.Errorf("\nwant attr: %s\nin: %s", strings.Join(path, "."), render(r))
+	return false
+}
+
+// finds the attr at path, where each key but the last names a group; of attrs with the same key, the last wins
+func findAttr(r slog.Record, path []string) (a slog.Attr, found bool) {
+	if len(path) == 0 {
+		return
+	}
+
+	r.Attrs(func(ra slog.Attr) bool {
+		if ra.Key == path[0] {
+			a, found = ra, true
+		}
+		return true
+	})
+
+	for _, key := range path[1:] {
+		if !found || a.Value.Resolve().Kind() != slog.KindGroup {
+			return slog.Attr{}, false
+		}
+
+		group := a.Value.Resolve().Group()
+		found = false
+		for _, ga := range group {
+			if ga.Key == key {
+				a, found = ga, true
+			}
+		}
+	}
+	return
+}
+
+// renders a record as text, for failure reports
+func render(r slog.Record) string {
+	var b strings.Builder
+	h := slog.NewTextHandler(&b, &slog.HandlerOptions{
+		ReplaceAttr: func(scope []string, a slog.Attr) slog.Attr {
+			if len(scope) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	h.Handle(context.Background(), r)
+	return strings.TrimSuffix(b.String(), "\n")
+}
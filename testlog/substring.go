@@ -1,13 +1,155 @@
 package testlog
 
 import (
-	"bytes"
-	"strings"
+	"context"
+	"math"
 	"testing"
 
 	"log/slog"
+
+	"github.com/AndrewHarrisSPU/logf"
+)
+
+// Capture is a [slog.Handler] writing to a buffer, returned by [Substrings] and [Patterns].
+// Beyond their "want" functions, a Capture offers assertions about what was, or wasn't, logged.
+// As with "want" functions, each assertion clears the buffer.
+//
+// A Capture is also an [io.Writer], writing to the same buffer.
+// Another handler under test, e.g. a logf TTY, may write to it as well.
+// Writing and logging are safe from any goroutine, concurrently with assertions.
+type Capture struct {
+	slog.Handler
+	t   testing.TB
+	buf *buffer
+}
+
+// Format selects the encoding of a [Capture].
+type Format int
+
+const (
+	// JSON encodes with a [slog.JSONHandler].
+	JSON Format = iota
+	// Text encodes with a [slog.TextHandler].
+	Text
+	// TTY encodes with a logf TTY, with colors off and TTY output forced.
+	TTY
 )
 
+// Options configure the handler returned by [SubstringsWith].
+// The zero Options configure the handler returned by [Substrings]: JSON, with source/line information.
+//
+// In any format, time is omitted from output.
+type Options struct {
+	// Format selects the encoding.
+	Format Format
+
+	// OmitSource omits source/line information.
+	OmitSource bool
+
+	// Level is the minimum level of records handled. If nil, the minimum is [slog.LevelInfo].
+	Level slog.Leveler
+
+	// Replace, if set, replaces attrs as [slog.HandlerOptions.ReplaceAttr] does.
+	// It is not given the time field.
+	Replace func(groups []string, a slog.Attr) slog.Attr
+}
+
+// returns a Capture of a handler configured by opts
+func newCapture(t testing.TB, opts Options) *Capture {
+	buf := new(buffer)
+
+	replace := noTime
+	if opts.Replace != nil {
+		replace = func(groups []string, a slog.Attr) slog.Attr {
+			if a = noTime(groups, a); a.Key == "" {
+				return a
+			}
+			return opts.Replace(groups, a)
+		}
+	}
+
+	hopts := &slog.HandlerOptions{
+		AddSource:   !opts.OmitSource,
+		Level:       opts.Level,
+		ReplaceAttr: replace,
+	}
+
+	var h slog.Handler
+	switch opts.Format {
+	case Text:
+		h = slog.NewTextHandler(buf, hopts)
+	case TTY:
+		// the TTY handles any level; leveled decides
+		var all slog.LevelVar
+		all.Set(math.MinInt)
+
+		level := opts.Level
+		if level == nil {
+			level = slog.LevelInfo
+		}
+
+		h = leveled{
+			Handler: logf.New().
+				Writer(buf).
+				Ref(&all).
+				ShowColor(false).
+				ForceTTY(true).
+				AddSource(hopts.AddSource).
+				ReplaceFunc(replace).
+				TTY(),
+			level: level,
+		}
+	default:
+		h = slog.NewJSONHandler(buf, hopts)
+	}
+
+	return &Capture{
+		Handler: h,
+		t:       t,
+		buf:     buf,
+	}
+}
+
+// leveled wraps a handler, enabling records at or above level
+type leveled struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h leveled) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h leveled) WithAttrs(as []slog.Attr) slog.Handler {
+	return leveled{h.Handler.WithAttrs(as), h.level}
+}
+
+func (h leveled) WithGroup(name string) slog.Handler {
+	return leveled{h.Handler.WithGroup(name), h.level}
+}
+
+// Write appends p to the buffer.
+func (c *Capture) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+// NotWant tests whether the buffer doesn't contain the given string.
+// If it does, t.Errorf is called.
+// Verifying that a filtered or disabled record was not logged, NotWant is complemented by [Capture.WantCount].
+func (c *Capture) NotWant(unwanted string) {
+	c.t.Helper()
+	c.buf.notWantSubstring(c.t, unwanted)
+	c.buf.Reset()
+}
+
+// WantCount tests whether exactly n newline-terminated records were written to the buffer.
+// If not, t.Errorf is called.
+func (c *Capture) WantCount(n int) {
+	c.t.Helper()
+	c.buf.wantCount(c.t, n)
+	c.buf.Reset()
+}
+
 // Substrings returns a [slog.Handler] and a "want" function.
 //
 // When a logging call is made using the handler, log lines are written to a buffer.
@@ -16,25 +158,53 @@ import (
 // Calling want clears the buffer.
 //
 // The handler encodes to JSON, and adds source/line information.
-func Substrings(t *testing.T) (h slog.Handler, want func(string)) {
-	var b bytes.Buffer
+// It is a [Capture], also offering [Capture.NotWant] and [Capture.WantCount].
+func Substrings(t *testing.T) (h *Capture, want func(string)) {
+	return SubstringsWith(t, Options{})
+}
+
+// SubstringsWith is as [Substrings], returning a handler configured by opts.
+// For example, tests of TTY encoding may use:
+//
+//	h, want := testlog.SubstringsWith(t, testlog.Options{Format: testlog.TTY})
+func SubstringsWith(t *testing.T, opts Options) (h *Capture, want func(string)) {
+	h = newCapture(t, opts)
 
 	want = func(wantString string) {
 		t.Helper()
-		if !strings.Contains(b.String(), wantString) {
-			t.Errorf("\n\texpected %s\n\tin %s", wantString, b.String())
-		}
-		b.Reset()
+		h.buf.wantSubstring(t, wantString)
+		h.buf.Reset()
 	}
 
-	h = slog.HandlerOptions{
-		ReplaceAttr: noTime,
-		AddSource:   true,
-	}.NewJSONHandler(&b)
-
 	return h, want
 }
 
+// Patterns returns a [slog.Handler], a "want" function, and a "wantN" function.
+// The handler is as given by [Substrings].
+//
+// Calling "want" tests whether the buffered output matches the given regular expression,
+// as with [regexp.Match]. Calling "wantN" tests whether the buffered output holds exactly
+// the given count of non-overlapping matches, e.g. counting records across several logging calls.
+// If not, or if the pattern doesn't compile, t.Errorf is called.
+// Calling want or wantN clears the buffer.
+func Patterns(t *testing.T) (h *Capture, want func(pattern string), wantN func(pattern string, count int)) {
+	h = newCapture(t, Options{})
+
+	want = func(pattern string) {
+		t.Helper()
+		h.buf.wantPattern(t, pattern)
+		h.buf.Reset()
+	}
+
+	wantN = func(pattern string, count int) {
+		t.Helper()
+		h.buf.wantPatternN(t, pattern, count)
+		h.buf.Reset()
+	}
+
+	return h, want, wantN
+}
+
 func noTime(scope []string, a slog.Attr) slog.Attr {
 	if a.Key == "time" {
 		a.Key = ""
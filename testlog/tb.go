@@ -1,21 +1,26 @@
 package testlog
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"log/slog"
-	// "github.com/AndrewHarrisSPU/logf"
 )
 
 // type TB embeds [testing.TB], and has the following utility:
 //   - TB overrides some [testing.TB] methods, and embeds others - it satisifes the [testing.TB] interface
 //   - TB also satisfies the [slog.Handler] interface
-//   - TB provides a method Want, for substring matching of logged output
+//   - TB provides a method Want, for substring matching of logged output, and NotWant and WantCount complementing it
+//   - TB provides methods WantPattern and WantPatternN, for regular expression matching of logged output
 //   - TB provides a method WantBuffer, for string matching of logged output
+//   - TB provides a method LastRecord, and methods WantLevel and WantSourceSuffix, for assertions about the last record
+//
+// Records may be handled by a TB from any goroutine, concurrently with its assertions.
 type TB struct {
 	// turns time on / off in logs
 	Time bool
@@ -27,9 +32,10 @@ type TB struct {
 	testing.TB
 
 	// encoded output writes to buf
-	buf bytes.Buffer
+	buf buffer
 
-	// last record held
+	// last record held, guarded by mu
+	mu   sync.Mutex
 	last slog.Record
 
 	// encoder
@@ -45,22 +51,22 @@ func UsingTB(ttb testing.TB) *TB {
 		tb.Clear()
 	})
 
-	tb.enc = slog.HandlerOptions{
+	tb.enc = slog.NewJSONHandler(&tb.buf, &slog.HandlerOptions{
 		AddSource: true,
-	}.NewJSONHandler(&tb.buf)
+	})
 
 	return tb
 }
 
 // slog.Handler methods
 
-func (tb *TB) Enabled(level slog.Level) bool {
+func (tb *TB) Enabled(ctx context.Context, level slog.Level) bool {
 	return true
 }
 
-func (tb *TB) Handle(r slog.Record) error {
-	tb.last = r
-	return tb.enc.Handle(r)
+func (tb *TB) Handle(ctx context.Context, r slog.Record) error {
+	tb.setLast(r)
+	return tb.enc.Handle(ctx, r)
 }
 
 func (tb *TB) WithAttrs(as []slog.Attr) slog.Handler {
@@ -144,24 +150,36 @@ func (tb *TB) addDepth(depth int) int {
 	return 0
 }
 
+// returns the program counter of the caller at depth, or 0 for no source
+func (tb *TB) pc(depth int) uintptr {
+	depth = tb.addDepth(depth)
+	if depth == 0 {
+		return 0
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(depth, pcs[:])
+	return pcs[0]
+}
+
 func (tb *TB) record(depth int, args ...any) {
 	msg := fmt.Sprint(args...)
-	r := slog.NewRecord(tb.time(), slog.InfoLevel, msg, tb.addDepth(depth), nil)
-	tb.last = r
-	tb.enc.Handle(r)
+	r := slog.NewRecord(tb.time(), slog.LevelInfo, msg, tb.pc(depth))
+	tb.setLast(r)
+	tb.enc.Handle(context.Background(), r)
 }
 
 func (tb *TB) recordf(depth int, f string, args ...any) {
 	msg := fmt.Sprintf(f, args...)
-	r := slog.NewRecord(tb.time(), slog.InfoLevel, msg, tb.addDepth(depth), nil)
-	tb.last = r
-	tb.enc.Handle(r)
+	r := slog.NewRecord(tb.time(), slog.LevelInfo, msg, tb.pc(depth))
+	tb.setLast(r)
+	tb.enc.Handle(context.Background(), r)
 }
 
 func (tb *TB) show(msg string) {
 	tb.TB.Helper()
 	tb.TB.Logf("%s:\n%s\n", msg, tb.buf.String())
-	tb.Clear()
+	tb.buf.Reset()
 }
 
 func (tb *TB) dump() {
@@ -169,43 +187,118 @@ func (tb *TB) dump() {
 	if tb.Failed() && !tb.Skipped() {
 		tb.TB.Logf("%s:\n%s\n", tb.TB.Name(), tb.buf.String())
 	}
-	tb.Clear()
+	tb.buf.Reset()
+}
+
+func (tb *TB) setLast(r slog.Record) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.last = r
 }
 
 // Utility
 
+// Clear clears logged output and the last record.
 func (tb *TB) Clear() {
 	tb.buf.Reset()
-	tb.last = slog.NewRecord(time.Time{}, slog.ErrorLevel, "", 0, nil)
+	tb.setLast(slog.Record{})
+}
+
+// LastRecord returns a clone of the last record, whether handled by the TB or produced by a method like [TB.Log] or [TB.Errorf].
+// The last record is kept until the next record, or [TB.Clear]; it is the zero Record if there is none.
+func (tb *TB) LastRecord() slog.Record {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.last.Clone()
 }
 
 // Asserts
 
 func (tb *TB) Want(want string) (found bool) {
 	tb.TB.Helper()
-	defer tb.Clear()
+	defer tb.buf.Reset()
 
-	if strings.Contains(tb.buf.String(), want) {
-		found = true
+	return tb.buf.wantSubstring(tb.TB, want)
+}
+
+// NotWant tests whether logged output doesn't contain the given string, and then clears it.
+func (tb *TB) NotWant(unwanted string) (absent bool) {
+	tb.TB.Helper()
+	defer tb.buf.Reset()
+
+	return tb.buf.notWantSubstring(tb.TB, unwanted)
+}
+
+// WantCount tests whether exactly n newline-terminated records were logged, and then clears logged output.
+func (tb *TB) WantCount(n int) (found bool) {
+	tb.TB.Helper()
+	defer tb.buf.Reset()
+
+	return tb.buf.wantCount(tb.TB, n)
+}
+
+// WantPattern tests whether logged output matches the given regular expression, and then clears it.
+func (tb *TB) WantPattern(pattern string) (found bool) {
+	tb.TB.Helper()
+	defer tb.buf.Reset()
+
+	return tb.buf.wantPattern(tb.TB, pattern)
+}
+
+// WantPatternN tests whether logged output holds exactly count non-overlapping matches
+// of the given regular expression, and then clears it.
+func (tb *TB) WantPatternN(pattern string, count int) (found bool) {
+	tb.TB.Helper()
+	defer tb.buf.Reset()
+
+	return tb.buf.wantPatternN(tb.TB, pattern, count)
+}
+
+// WantLevel tests whether the last record has the given level.
+// Unlike assertions of logged output, it doesn't clear anything.
+func (tb *TB) WantLevel(level slog.Level) (found bool) {
+	tb.TB.Helper()
+	r := tb.LastRecord()
+	if r.Level == level {
+		return true
 	}
 
-	if !found {
-		tb.TB.Errorf("\nwant: %s\nin:   %s", want, tb.buf.String())
+	tb.TB.Errorf("\nwant level: %s\nin:   %s %q", level, r.Level, r.Message)
+	return false
+}
+
+// WantSourceSuffix tests whether the "file:line" source of the last record ends with the given suffix,
+// e.g. to verify the effect of [TB.Depth]. Records produced by [TB.Log] and [TB.Logf] have no source.
+// Unlike assertions of logged output, it doesn't clear anything.
+func (tb *TB) WantSourceSuffix(suffix string) (found bool) {
+	tb.TB.Helper()
+	r := tb.LastRecord()
+	if r.PC == 0 {
+		tb.TB.Errorf("\nwant source: ...%s\nin:   no source, %q", suffix, r.Message)
+		return false
 	}
 
-	return
+	f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+	src := fmt.Sprintf("%s:%d", f.File, f.Line)
+	if strings.HasSuffix(src, suffix) {
+		return true
+	}
+
+	tb.TB.Errorf("\nwant source: ...%s\nin:   %s", suffix, src)
+	return false
 }
 
 func (tb *TB) WantBuffer(want string) (found bool) {
 	tb.TB.Helper()
-	defer tb.Clear()
+	defer tb.buf.Reset()
 
-	if want == tb.buf.String() {
+	got := tb.buf.String()
+	if want == got {
 		found = true
 	}
 
 	if !found {
-		tb.TB.Errorf("\nwant: %s\nin:   %s", want, tb.buf.String())
+		tb.TB.Errorf("\nwant: %s\nin:   %s", want, got)
 	}
 
 	return
@@ -1,7 +1,19 @@
 package testlog
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"log/slog"
+
+	"github.com/AndrewHarrisSPU/logf"
 )
 
 func Test_Ok(t *testing.T) {
@@ -13,5 +25,362 @@ func Test_Ok(t *testing.T) {
 	tb.Logf("a number: %d", 42)
 	tb.Want("a number: 42")
 
+	// Error records the error and fails the wrapped test; a recorder keeps Test_Ok itself passing
+	tb = UsingTB(&recorder{TB: t})
 	tb.Error("a test error")
+	if msg := tb.LastRecord().Message; msg != "a test error" {
+		t.Errorf("Error: last record %q", msg)
+	}
+}
+
+func Test_Patterns(t *testing.T) {
+	h, want, wantN := Patterns(t)
+	log := slog.New(h)
+
+	log.Info("request", "status", 200)
+	want(`"msg":"request","status":2\d\d`)
+
+	log.Info("one")
+	log.Info("two")
+	log.Warn("three")
+	wantN(`"level":"INFO"`, 2)
+
+	tb := UsingTB(t)
+	tb.Logf("elapsed: %s", 1500*time.Millisecond)
+	tb.WantPattern(`elapsed: \d+\.\d+s`)
+
+	tb.Log("a")
+	tb.Log("b")
+	tb.WantPatternN(`"msg":"[ab]"`, 2)
+}
+
+// recorder is a testing.TB recording failures rather than reporting them
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fail() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func Test_PatternFailures(t *testing.T) {
+	rec := &recorder{TB: t}
+	h := newCapture(rec, Options{})
+	c := h.buf
+	log := slog.New(h)
+
+	log.Info("one")
+	log.Info("two")
+
+	for _, f := range []func() bool{
+		func() bool { return c.wantPattern(rec, `"msg":"three"`) },
+		func() bool { return c.wantPatternN(rec, `"msg"`, 3) },
+		func() bool { return c.wantPattern(rec, `(`) },
+	} {
+		if f() {
+			t.Errorf("unexpected match")
+		}
+	}
+
+	if len(rec.errs) != 3 {
+		t.Fatalf("want 3 failures, got %q", rec.errs)
+	}
+	if !strings.Contains(rec.errs[0], "  2 | {") {
+		t.Errorf("listing: %s", rec.errs[0])
+	}
+	if !strings.Contains(rec.errs[1], "want 3 matches, got 2") {
+		t.Errorf("count: %s", rec.errs[1])
+	}
+	if !strings.HasPrefix(rec.errs[2], "bad pattern") {
+		t.Errorf("compile: %s", rec.errs[2])
+	}
+}
+
+func Test_Records(t *testing.T) {
+	rec := Records(t)
+	log := slog.New(rec)
+
+	log.Warn("request", "status", 200, "elapsed", time.Second)
+	rec.WantMessage("request")
+	rec.WantLevel(slog.LevelWarn)
+	rec.WantAttr("status", 200)
+	rec.WantAttr("elapsed", time.Second)
+
+	// derived loggers capture into the same records
+	log.With("user", "gopher").WithGroup("req").With("path", "/").Info("get", "status", 404)
+	rec.WantAttr("user", "gopher")
+	rec.WantGroupAttr("req", "path")
+	rec.WantGroupAttr("req", "status")
+
+	if rs := rec.Records(); len(rs) != 2 {
+		t.Errorf("want 2 records, got %d", len(rs))
+	}
+
+	// failures
+	r := &recorder{TB: t}
+	rec = Records(r)
+	rec.WantMessage("none")
+	slog.New(rec).Info("msg", "a", 1)
+	rec.WantAttr("a", 2)
+	rec.WantGroupAttr("a", "b")
+	rec.WantLevel(slog.LevelError)
+
+	if len(r.errs) != 4 {
+		t.Fatalf("want 4 failures, got %q", r.errs)
+	}
+	if !strings.Contains(r.errs[1], "in: level=INFO msg=msg a=1") {
+		t.Errorf("render: %s", r.errs[1])
+	}
+}
+
+func Test_NotWant(t *testing.T) {
+	h, want := Substrings(t)
+	log := slog.New(h)
+
+	log.Info("one")
+	log.Debug("disabled")
+	h.NotWant("disabled")
+
+	log.Info("one")
+	log.Info("two")
+	h.WantCount(2)
+
+	log.Info("three")
+	want("three")
+	h.WantCount(0)
+
+	tb := UsingTB(t)
+	tb.Log("one")
+	tb.NotWant("two")
+	tb.WantCount(0)
+
+	// other handlers may write to a Capture
+	tty := logf.New().
+		Writer(h).
+		ShowColor(false).
+		ForceTTY(true).
+		TTY()
+	tty.Filter("db")
+	ttyLog := tty.Logger()
+	ttyLog.Info("query", "#", "db")
+	ttyLog.Info("request", "#", "http")
+	h.NotWant("request")
+
+	jsonLog := logf.New().Writer(h).JSON()
+	jsonLog.Info("one")
+	jsonLog.Debug("two")
+	h.WantCount(1)
+
+	// failures
+	rec := &recorder{TB: t}
+	c := newCapture(rec, Options{})
+	slog.New(c).Info("one")
+	c.NotWant("one")
+	slog.New(c).Info("one")
+	c.WantCount(2)
+
+	if len(rec.errs) != 2 {
+		t.Fatalf("want 2 failures, got %q", rec.errs)
+	}
+	if !strings.Contains(rec.errs[1], "want 2 records, got 1") {
+		t.Errorf("count: %s", rec.errs[1])
+	}
+}
+
+func Test_Golden(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rec := &recorder{TB: t}
+
+	logTo := func(g *GoldenFile, colors bool) {
+		log := logf.New().
+			Writer(g).
+			ShowColor(colors).
+			ForceTTY(true).
+			Logger()
+		log.Info("one", "n", 1)
+		log.Warn("two", "n", 2)
+	}
+	stamp := regexp.MustCompile(`\d\d:\d\d:\d\d`)
+	golden := func(r testing.TB) *GoldenFile {
+		g := Golden(r, "tty")
+		g.StripANSI = true
+		g.ReplaceFunc = func(line string) string {
+			return stamp.ReplaceAllString(line, "TIME")
+		}
+		return g
+	}
+
+	// missing
+	g := golden(rec)
+	logTo(g, false)
+	if g.Check() || len(rec.errs) != 1 || !strings.Contains(rec.errs[0], "TESTLOG_UPDATE") {
+		t.Fatalf("missing golden file: %q", rec.errs)
+	}
+
+	// update
+	Update = true
+	logTo(g, false)
+	g.Check()
+	Update = false
+
+	text, err := os.ReadFile(filepath.Join("testdata", "tty.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(text), "TIME") != 2 {
+		t.Errorf("not normalized: %q", text)
+	}
+
+	// colors are stripped
+	g = golden(t)
+	logTo(g, true)
+	g.Check()
+
+	// mismatch
+	rec.errs = nil
+	g = golden(rec)
+	logf.New().Writer(g).ShowColor(false).ForceTTY(true).Logger().Info("one", "n", 3)
+	if g.Check() || len(rec.errs) != 1 {
+		t.Fatalf("mismatch: %q", rec.errs)
+	}
+
+	for _, line := range []string{
+		"--- testdata/tty.golden",
+		"+++ got",
+		"@@ -1,2 +1,1 @@",
+		"- ▏ TIME one\tn:1",
+		"-▕▎ TIME two\tn:2",
+		"+ ▏ TIME one\tn:3",
+	} {
+		if !strings.Contains(rec.errs[0], "\n"+line) {
+			t.Errorf("diff: missing %q in\n%s", line, rec.errs[0])
+		}
+	}
+}
+
+// run with -race
+func Test_Concurrent(t *testing.T) {
+	h, want := Substrings(t)
+	tb := UsingTB(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			log := slog.New(h)
+			tlog := slog.New(tb)
+			for j := 0; j < 100; j++ {
+				log.Info("worker", "id", i)
+				tlog.Info("worker", "id", i)
+				tb.Logf("worker %d", i)
+			}
+		}(i)
+	}
+
+	// assert while workers log
+	for i := 0; i < 100; i++ {
+		h.NotWant(`"msg":"idle"`)
+		tb.NotWant(`"msg":"idle"`)
+	}
+	wg.Wait()
+
+	slog.New(h).Info("worker", "id", 10)
+	want(`"msg":"worker","id":10`)
+
+	tb.Logf("worker %d", 10)
+	tb.WantPattern(`"msg":"worker 10"`)
+}
+
+func Test_SubstringsWith(t *testing.T) {
+	// the zero Options are Substrings
+	h, want := SubstringsWith(t, Options{})
+	slog.New(h).Info("zero", "n", 1)
+	want(`"level":"INFO","source":{`)
+	slog.New(h).Debug("debug")
+	h.WantCount(0)
+
+	h, want = SubstringsWith(t, Options{
+		Format:     Text,
+		OmitSource: true,
+		Level:      slog.LevelDebug,
+		Replace: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "secret" {
+				a.Value = slog.StringValue("***")
+			}
+			return a
+		},
+	})
+	slog.New(h).Debug("text", "secret", "swordfish")
+	want(`level=DEBUG msg=text secret=***`)
+	h.NotWant("time=")
+
+	var level slog.LevelVar
+	h, want = SubstringsWith(t, Options{
+		Format: TTY,
+		Level:  &level,
+	})
+	log := slog.New(h).With("a", 1).WithGroup("g")
+	log.Info("tty", "b", 2)
+	want("tty\ta:1 g:{b:2}")
+
+	log.Debug("hidden")
+	h.NotWant("hidden")
+	level.Set(slog.LevelDebug)
+	log.Debug("shown")
+	want("shown")
+
+	slog.New(h).Info("source")
+	want("tb_test.go:")
+}
+
+func Test_LastRecord(t *testing.T) {
+	rec := &recorder{TB: t}
+	tb := UsingTB(rec)
+
+	// driven as a handler
+	log := slog.New(tb)
+	log.Warn("handled")
+	_, _, line, _ := runtime.Caller(0)
+	tb.Want("handled")
+
+	if r := tb.LastRecord(); r.Message != "handled" {
+		t.Errorf("last record: %q", r.Message)
+	}
+	tb.WantLevel(slog.LevelWarn)
+	tb.WantSourceSuffix(fmt.Sprintf("tb_test.go:%d", line-1))
+
+	// produced by an override
+	tb.Errorf("failed: %d", 1)
+	_, _, line, _ = runtime.Caller(0)
+	tb.WantLevel(slog.LevelInfo)
+	tb.WantSourceSuffix(fmt.Sprintf("tb_test.go:%d", line-1))
+
+	if len(rec.errs) != 0 {
+		t.Fatalf("unexpected failures: %q", rec.errs)
+	}
+
+	// failures
+	tb.WantLevel(slog.LevelError)
+	tb.WantSourceSuffix("other.go:1")
+	tb.Log("no source")
+	tb.WantSourceSuffix("tb_test.go")
+	tb.Clear()
+	if r := tb.LastRecord(); r.Message != "" || r.PC != 0 {
+		t.Errorf("cleared: %v", r)
+	}
+
+	if len(rec.errs) != 3 {
+		t.Fatalf("want 3 failures, got %q", rec.errs)
+	}
+	for i, want := range []string{"want level: ERROR", "want source: ...other.go:1", "no source"} {
+		if !strings.Contains(rec.errs[i], want) {
+			t.Errorf("failure %d: want %q in %q", i, want, rec.errs[i])
+		}
+	}
 }
@@ -5,7 +5,11 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"log/slog"
 )
@@ -26,13 +30,47 @@ type TTY struct {
 	dev *ttyDevice
 	aux slog.Handler
 
+	// the TTY before any WithAttrs or WithGroup calls
+	root *TTY
+
 	// unformatted
-	store Store
-	label Attr
+	store  Store
+	labels []Attr
+	err    error
+
+	// attrs given to WithAttrs, preformatted
+	pre *ttyPreformat
+}
+
+// ttyPreformat holds the attrs of a [TTY.WithAttrs] call, following those of earlier calls.
+// Text is rendered for the formatter in use, and cached.
+type ttyPreformat struct {
+	parent *ttyPreformat
+	store  Store  // as of the call
+	as     []Attr // replaced
+
+	text atomic.Pointer[ttyPreText]
+}
+
+// chain returns the preformatted attrs of each WithAttrs call leading to pre, earliest first
+func (pre *ttyPreformat) chain() []*ttyPreformat {
+	var pres []*ttyPreformat
+	for ; pre != nil; pre = pre.parent {
+		pres = append(pres, pre)
+	}
+	slices.Reverse(pres)
+	return pres
+}
+
+// ttyPreText is preformatted attr and tag text, as rendered by a formatter
+type ttyPreText struct {
+	fmtr *ttyFormatter
 
 	// attr preformatting
-	attrText string
-	attrSep  byte
+	attrText  string
+	attrSep   byte
+	attrCount int // count of attrs in attrText
+	opened    int // count of scope groups opened in attrText
 
 	// tag preformatting
 	tagText string
@@ -44,9 +82,72 @@ type ttyDevice struct {
 	fmtr   *ttyFormatter
 	filter *ttyFilter
 
-	ref *slog.LevelVar
+	ref    levelRef
+	scopes *LevelTree
 
 	replace replaceFunc
+	skip    int
+
+	addStack   bool
+	stackLevel slog.Level
+
+	ctxAttrs func(context.Context) []Attr
+	observe  func(slog.Level, string, bool)
+	onRecord recordHooks
+	repanic  bool
+	consume  bool
+
+	// joins a new tag to an inherited one, if non-empty
+	tagJoin string
+
+	// the key of label attrs; if empty, no labels are detected
+	tagKey string
+
+	// if rekeyed, labels reach an aux handler keyed by auxTagKey, or not at all if it is empty
+	auxRekey  bool
+	auxTagKey string
+
+	// if set, records dropped by the tag filter don't reach an aux handler
+	filterAux bool
+
+	reveal   bool
+	sanitize bool
+	links    bool
+	tree     bool // renders attrs as a tree, writing to a terminal
+	dedup    bool // drops attrs followed by another with the same key
+	timePrec int
+
+	// state of the previous record, guarded by the writer mutex
+	last *ttyLast
+}
+
+// ttyLast holds state of the previous record encoded by a [TTY], kept only as needed
+type ttyLast struct {
+	// for TimeDelta
+	time time.Time
+
+	// for Config.ShowAttrsDiff
+	attrText string
+}
+
+// reports whether encoding a record reads state of the previous record, kept in dev.last
+func (dev *ttyDevice) tracksLast() bool {
+	_, isDelta := dev.fmtr.time.Encoder.(timeDelta)
+	return isDelta || dev.fmtr.attrsDiff
+}
+
+// swapTime stores the time of a record, returning the time of the previous record.
+// The writer lock is held by the caller.
+func (dev *ttyDevice) swapTime(t time.Time) (prev time.Time) {
+	prev, dev.last.time = dev.last.time, t
+	return prev
+}
+
+// swapAttrText stores the preformatted attr text of a record, returning that of the previous record.
+// The writer lock is held by the caller.
+func (dev *ttyDevice) swapAttrText(text string) (prev string) {
+	prev, dev.last.attrText = dev.last.attrText, text
+	return prev
 }
 
 // ttySyncWriter manages state relevant to writing bytes, concurrently, on-screen (or wherever)
@@ -75,17 +176,34 @@ func (w *ttySyncWriter) Write(p []byte) (n int, err error) {
 }
 
 // ttyFilter manages some state relevant to filtering log lines
+// The set of tags is immutable once stored, and swapped as a whole by [TTY.Filter].
 type ttyFilter struct {
-	tag map[string]struct{}
+	tag atomic.Pointer[map[string]struct{}]
+}
+
+// returns the current set of tags; an empty set filters nothing
+func (f *ttyFilter) tags() map[string]struct{} {
+	if tag := f.tag.Load(); tag != nil {
+		return *tag
+	}
+	return nil
+}
+
+func (f *ttyFilter) set(tags []string) {
+	tag := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		tag[t] = struct{}{}
+	}
+	f.tag.Store(&tag)
 }
 
 // Logger returns a [Logger] that uses the [TTY] as a handler.
 func (tty *TTY) Logger() Logger {
 	if tty.dev.w == nil {
-		return newLogger(tty.aux.(handler))
+		return newLogger(tty.aux.(handler)).Depth(tty.dev.skip)
 	}
 
-	return newLogger(tty)
+	return newLogger(tty).Depth(tty.dev.skip)
 }
 
 // LogValue returns a [slog.Value], of [slog.GroupKind].
@@ -112,57 +230,87 @@ func (tty *TTY) Printf(f string, args ...any) {
 		return
 	}
 
-	s := newSplicer()
+	s := tty.newSplicer()
 	defer s.free()
 
 	s.scanMessage(f)
-	s.joinStore(tty.store, tty.dev.replace)
+	s.joinStore(tty.store)
 	for _, a := range Attrs(args...) {
-		s.joinLocal(tty.store.scope, a, tty.dev.replace)
+		s.joinLocal(tty.store, a, tty.dev.replace)
 	}
 	s.ipol(f)
 
 	tty.WriteString(s.line())
 }
 
+// SetRef sets the reference level of the TTY.
+// A Leveler given by [Config.HandlerOptions] is no longer consulted.
 func (tty *TTY) SetRef(level slog.Level) {
 	tty.dev.ref.Set(level)
 }
 
-// Filter sets a filter on [TTY] output, using the given set of tags.
-func (tty *TTY) Filter(tags ...string) {
-	tty.dev.w.Lock()
-	defer tty.dev.w.Unlock()
-
-	for tag := range tty.dev.filter.tag {
-		delete(tty.dev.filter.tag, tag)
+// reports whether a tag is in the filter; a joined tag matches in full, or by any component
+func (tty *TTY) filtered(filter map[string]struct{}, tag string) bool {
+	if _, ok := filter[tag]; ok || tty.dev.tagJoin == "" {
+		return ok
 	}
 
-	for _, tag := range tags {
-		tty.dev.filter.tag[tag] = struct{}{}
+	for _, t := range strings.Split(tag, tty.dev.tagJoin) {
+		if _, ok := filter[t]; ok {
+			return true
+		}
 	}
+	return false
+}
+
+// Filter sets a filter on [TTY] output, using the given set of tags.
+// It is safe to call Filter concurrently with logging.
+func (tty *TTY) Filter(tags ...string) {
+	tty.dev.filter.set(tags)
 }
 
 // HANDLER
 
-// Enabled reports whether the [TTY] is enabled for logging at the given level.
+// Enabled reports whether the [TTY] is enabled for logging at the given level:
+// whether either the TTY or an auxilliary handler is. Each handles only records at levels it's enabled for.
+// Without a terminal to write to, an auxilliary handler decides.
 func (tty *TTY) Enabled(ctx context.Context, level slog.Level) bool {
+	if tty.dev.w == nil && tty.aux != nil {
+		return tty.aux.Enabled(ctx, level)
+	}
+	return tty.enabled(level) || (tty.aux != nil && tty.aux.Enabled(ctx, level))
+}
+
+// reports whether the TTY writes records at the level, apart from an auxilliary handler
+func (tty *TTY) enabled(level slog.Level) bool {
+	if lvl, ok := tty.dev.scopes.level(tty.store); ok {
+		return level >= lvl
+	}
 	return level >= tty.dev.ref.Level()
 }
 
+// reports whether the auxilliary handler handles records at the level; without a terminal, Enabled already asked it
+func (tty *TTY) auxEnabled(ctx context.Context, level slog.Level) bool {
+	return tty.dev.w == nil || tty.aux.Enabled(ctx, level)
+}
+
 // See [slog.WithAttrs].
 func (tty *TTY) WithAttrs(as []Attr) slog.Handler {
 	t2 := *tty
+	t2.root = tty.rootTTY()
 
 	// find & assign label
-	as, t2.label = detectLabel(as, tty.label)
+	as = joinTags(as, tty.labels, tty.dev.tagJoin, tty.dev.tagKey)
+	auxAs := tty.auxAttrs(as)
+	as, t2.labels = detectLabel(as, tty.labels, tty.dev.tagKey)
 
 	// store
 	t2.store = tty.store.WithAttrs(as)
+	t2.err = detectErr(as, tty.err)
 
 	// aux
 	if t2.aux != nil {
-		t2.aux = tty.aux.WithAttrs(as)
+		t2.aux = tty.aux.WithAttrs(auxAs)
 	}
 
 	// preformatting
@@ -170,32 +318,97 @@ func (tty *TTY) WithAttrs(as []Attr) slog.Handler {
 		return &t2
 	}
 
-	// (for consistency, using splicer methods to write attr and tag text)
-	s := newSplicer()
-	defer s.free()
+	// the store keeps attrs as given; preformatted text is replaced once, here
+	if tty.dev.replace != nil {
+		replaced := make([]Attr, len(as))
+		for i, a := range as {
+			replaced[i] = replaceAttr(tty.store.scope, a, tty.dev.replace)
+		}
+		as = replaced
+	}
 
-	b := &Buffer{s, 0}
+	t2.pre = &ttyPreformat{
+		parent: tty.pre,
+		store:  tty.store,
+		as:     expandErrs(as),
+	}
+	t2.preText()
 
-	// append attr text
-	b.sep = tty.attrSep
-	t2.encListAttrs(b, as)
+	return &t2
+}
 
-	t2.attrSep = b.sep
-	t2.attrText = tty.attrText + s.line()
+// preText returns text preformatted for the formatter in use, rendering it if the cached text was rendered by another
+func (tty *TTY) preText() *ttyPreText {
+	return tty.pre.render(tty, tty.dev.fmtr)
+}
 
-	// append tag text
-	s.text = s.text[:0]
-	b.sep = t2.tagSep
-	t2.encListTags(b, as)
-	t2.tagSep = b.sep
-	t2.tagText = tty.tagText + s.line()
+var ttyPreTextEmpty ttyPreText
 
-	return &t2
+func (pre *ttyPreformat) render(tty *TTY, fmtr *ttyFormatter) *ttyPreText {
+	if pre == nil {
+		return &ttyPreTextEmpty
+	}
+	if text := pre.text.Load(); text != nil && text.fmtr == fmtr {
+		return text
+	}
+
+	parent := pre.parent.render(tty, fmtr)
+
+	// (for consistency, using splicer methods to write attr and tag text)
+	t := *tty
+	if fmtr != tty.dev.fmtr {
+		dev := *tty.dev
+		dev.fmtr = fmtr
+		t.dev = &dev
+	}
+	t.store = pre.store
+
+	s := t.newSplicer()
+	defer s.free()
+
+	b := &Buffer{splicer: s}
+	text := &ttyPreText{fmtr: fmtr}
+
+	// append attr text, unless an attrs encoder or tree takes the attrs as given
+	if !t.attrsMerged() {
+		b.sep = parent.attrSep
+		b.prefix = t.store.scopeKey(len(t.store.scope))
+		text.opened = t.encScope(b, parent.opened, func() { t.encListAttrs(b, pre.as) })
+		b.prefix = ""
+
+		text.attrSep = b.sep
+		text.attrText = parent.attrText + s.line()
+		text.attrCount = parent.attrCount
+		for _, a := range pre.as {
+			if a.Key != "" {
+				text.attrCount++
+			}
+		}
+	}
+
+	// append tag text, unless tags are ordered as given
+	if len(fmtr.tagOrder) == 0 {
+		s.text = s.text[:0]
+		b.sep = parent.tagSep
+		t.encListTags(b, pre.as)
+		text.tagSep = b.sep
+		text.tagText = parent.tagText + s.line()
+	}
+
+	pre.text.Store(text)
+	return text
 }
 
 // See [slog.Handler.WithGroup].
+// As with other [slog.Handler]s, an empty name opens no group,
+// and a group is elided from output until it holds an attr.
 func (tty *TTY) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return tty
+	}
+
 	t2 := *tty
+	t2.root = tty.rootTTY()
 
 	// handler store
 	t2.store = tty.store.WithGroup(name)
@@ -205,73 +418,269 @@ func (tty *TTY) WithGroup(name string) slog.Handler {
 		t2.aux = tty.aux.WithGroup(name)
 	}
 
-	// preformatting
-	if t2.dev.w == nil {
-		return &t2
-	}
+	// (preformatted text opens the group with the first attr written in it)
+	return &t2
+}
 
-	s := newSplicer()
-	defer s.free()
+// Handle logs the given [slog.Record] to [TTY] output.
+// An auxilliary handler is given the same context.
+func (tty *TTY) Handle(ctx context.Context, r slog.Record) (auxErr error) {
+	if tty.dev.ctxAttrs != nil {
+		if as := tty.dev.ctxAttrs(ctx); len(as) > 0 {
+			r = r.Clone()
+			r.AddAttrs(as...)
+		}
+	}
 
-	b := &Buffer{s, 0}
-	b.sep = tty.attrSep
+	// decided once, so TTY and aux agree about the record
+	r, keep := tty.dev.onRecord.run(ctx, r)
+	pass := keep && tty.passes(r)
+	if tty.dev.observe != nil {
+		defer tty.dev.observe(r.Level, recordTag(r, tty.labels, tty.dev.tagJoin, tty.dev.tagKey), !pass)
+	}
 
-	b.writeSep()
-	b.sep = 0
+	toAux := tty.aux != nil && keep && (pass || !tty.dev.filterAux) && tty.auxEnabled(ctx, r.Level)
+	toTTY := tty.dev.w != nil && pass && tty.enabled(r.Level)
 
-	t2.dev.fmtr.key.Encode(b, name)
-	t2.encAttrGroupOpen(b)
+	// captured only for a record that is written
+	var stack []runtime.Frame
+	if (toAux || toTTY) && tty.dev.addStack && r.Level >= tty.dev.stackLevel {
+		stack = callers(1)
+	}
 
-	t2.attrSep = b.sep
+	if toAux {
+		r := tty.auxRecord(r)
 
-	t2.attrText = tty.attrText + s.line()
-	return &t2
-}
+		var chain []errCause
+		if tty.dev.fmtr.errChain {
+			chain = errChain(tty.recordErr(r), tty.dev.fmtr.errChainDepth)
+		}
 
-// Handle logs the given [slog.Record] to [TTY] output.
-func (tty *TTY) Handle(ctx context.Context, r slog.Record) (auxErr error) {
-	if tty.aux != nil {
-		auxErr = tty.aux.Handle(ctx, r)
+		if stack != nil || chain != nil {
+			r2 := r.Clone()
+			if stack != nil {
+				r2.AddAttrs(slog.Attr{Key: "stack", Value: stackValue(stack)})
+			}
+			if chain != nil {
+				r2.AddAttrs(slog.Attr{Key: "err_chain", Value: errChainValue(chain)})
+			}
+			auxErr = tty.aux.Handle(ctx, r2)
+		} else {
+			auxErr = tty.aux.Handle(ctx, r)
+		}
 	}
 
-	if tty.dev.w == nil {
+	if !toTTY {
 		return
 	}
 
-	_, enabled := tty.dev.filter.tag[tty.label.Value.String()]
-
 	// formatting
-	s := newSplicer()
+	s := tty.newSplicer()
 	defer s.free()
 
-	s.joinStore(tty.store, tty.dev.replace)
+	// values interpolated into the message are highlighted, unless the message was since rewritten
+	var spans []int
+	if len(s.ipolPen) > 0 && ctx != nil {
+		if ipol, ok := ctx.Value(spansKey{}).(*ipolSpans); ok && ipol.msg == r.Message {
+			spans = ipol.spans
+		}
+	}
+
+	s.joinStore(tty.store)
 
-	var recordErr error
+	recordErr := tty.err
 	r.Attrs(func(a Attr) bool {
-		if a.Key == "#" {
-			_, enabled = tty.dev.filter.tag[a.Value.String()]
+		if a.Key == tty.dev.tagKey && a.Key != "" {
 			return true
 		}
 		if a.Key == "err" {
 			if curr, isErr := a.Value.Any().(error); isErr {
 				recordErr = curr
 			}
+			// a stack carried by the error is rendered following the line, not as an attr
+			if ae, ok := a.Value.Any().(*attrsError); ok && ae.stack != nil {
+				a = slog.Any(a.Key, ae.sansStack())
+			}
 		}
-		s.joinLocal(tty.store.scope, a, tty.dev.replace)
+		s.joinLocal(tty.store, a, tty.dev.replace)
 		return true
 	})
 
-	if len(tty.dev.filter.tag) > 0 && !enabled {
-		return nil
+	// errors carrying attrs are exported as groups, after interpolation sees them as errors
+	for i, a := range s.export {
+		s.export[i].Value = errValue(a.Value)
+	}
+
+	// a record compared with the previous one is encoded and written under the writer lock,
+	// so that lines are written in the order they're compared
+	locked := tty.dev.tracksLast()
+	if locked {
+		tty.dev.w.Lock()
+		defer tty.dev.w.Unlock()
 	}
 
-	tty.encFields(s, r.Level, r.Message, recordErr, source(r))
+	rec := tty.newTTYRecord(r, spans, recordErr)
+	tty.encFields(s, &rec)
+	if rec.tree {
+		tty.encAttrTree(s, tty.mergeAttrs(s.export))
+	}
+	tty.encStack(s, stack)
+	if tty.dev.fmtr.errChain && recordErr != nil {
+		tty.encErrChain(s, recordErr)
+	}
+	if tty.dev.fmtr.addSource && recordErr != nil {
+		tty.encStack(s, errStack(recordErr))
+	}
 
-	tty.dev.w.Write(s.text)
+	if locked {
+		tty.dev.w.Writer.Write(s.text)
+	} else {
+		tty.dev.w.Write(s.text)
+	}
 
 	return nil
 }
 
+// reports whether a record passes the tag filter set by [TTY.Filter]
+func (tty *TTY) passes(r slog.Record) bool {
+	filter := tty.dev.filter.tags()
+	if len(filter) == 0 {
+		return true
+	}
+
+	var enabled bool
+	for _, label := range tty.labels {
+		enabled = enabled || tty.filtered(filter, label.Value.String())
+	}
+
+	r.Attrs(func(a Attr) bool {
+		if a.Key != tty.dev.tagKey || a.Key == "" {
+			return true
+		}
+
+		// a set of tags adds to the logger's tags, while a tag overrides them
+		if set, ok := a.Value.Any().(tagSet); ok {
+			for _, tag := range set {
+				enabled = enabled || tty.filtered(filter, tag)
+			}
+			return true
+		}
+
+		tag := a.Value.String()
+		if tty.dev.tagJoin != "" && len(tty.labels) > 0 {
+			tag = tty.labels[len(tty.labels)-1].Value.String() + tty.dev.tagJoin + tag
+		}
+		enabled = tty.filtered(filter, tag)
+		return true
+	})
+
+	return enabled
+}
+
+// returns the error of a record, or else the error carried by the TTY
+func (tty *TTY) recordErr(r slog.Record) (err error) {
+	err = tty.err
+	r.Attrs(func(a Attr) bool {
+		if a.Key == "err" {
+			if curr, isErr := a.Value.Any().(error); isErr {
+				err = curr
+			}
+		}
+		return true
+	})
+	return
+}
+
+// returns a splicer, configured with the TTY's treatment of secrets, quoting, control characters, hyperlinks, groups, structs, maps, times, bytes, floats, and highlighting
+func (tty *TTY) newSplicer() *splicer {
+	s := newSplicer()
+	s.reveal = tty.dev.reveal
+	s.quote = tty.dev.fmtr.quote
+	s.sanitize = tty.dev.sanitize
+	s.links = tty.dev.links
+	s.dots = tty.dev.fmtr.groupDots
+	s.structs = tty.dev.fmtr.structFields
+	s.timePrec = tty.dev.timePrec
+	s.hexBytes, s.hexMax = true, tty.dev.fmtr.hexMax
+	s.maps, s.mapSort = true, tty.dev.fmtr.mapSort
+	s.floatFmt, s.floatPrec, s.floatTrim = tty.dev.fmtr.floatFmt, tty.dev.fmtr.floatPrec, tty.dev.fmtr.floatTrim
+	s.ipolPen = tty.dev.fmtr.ipolPen
+	s.ipolRestore = tty.dev.fmtr.message.color
+	return s
+}
+
+// returns attrs for an aux handler, with labels rekeyed as configured by [Config.AuxTagKey].
+// A copy is returned if it holds labels, as detecting labels modifies as.
+func (tty *TTY) auxAttrs(as []Attr) []Attr {
+	key := tty.dev.tagKey
+	if key == "" || !slices.ContainsFunc(as, func(a Attr) bool { return a.Key == key }) {
+		return as
+	}
+
+	auxAs := make([]Attr, 0, len(as))
+	for _, a := range as {
+		if a.Key == key && tty.dev.auxRekey {
+			if tty.dev.auxTagKey == "" {
+				continue
+			}
+			a.Key = tty.dev.auxTagKey
+		}
+		auxAs = append(auxAs, a)
+	}
+	return auxAs
+}
+
+// returns a record for an aux handler, with labels rekeyed as configured by [Config.AuxTagKey]
+func (tty *TTY) auxRecord(r slog.Record) slog.Record {
+	key := tty.dev.tagKey
+	if !tty.dev.auxRekey || key == "" {
+		return r
+	}
+
+	var found bool
+	r.Attrs(func(a Attr) bool {
+		found = a.Key == key
+		return !found
+	})
+	if !found {
+		return r
+	}
+
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a Attr) bool {
+		if a.Key == key {
+			if tty.dev.auxTagKey == "" {
+				return true
+			}
+			a.Key = tty.dev.auxTagKey
+		}
+		r2.AddAttrs(a)
+		return true
+	})
+	return r2
+}
+
+func (tty *TTY) rootTTY() *TTY {
+	if tty.root == nil {
+		return tty
+	}
+	return tty.root
+}
+
+// withStore rebuilds the TTY from its root, using the given store.
+// Labels aren't held by the store, so they are replayed onto the aux handler first.
+func (tty *TTY) withStore(store Store) slog.Handler {
+	root := *tty.rootTTY()
+	if root.aux != nil && len(tty.labels) > 0 {
+		root.aux = root.aux.WithAttrs(root.auxAttrs(slices.Clone(tty.labels)))
+	}
+
+	t2 := *store.replay(&root).(*TTY)
+	t2.root = tty.rootTTY()
+	t2.labels = tty.labels
+	return &t2
+}
+
 func source(r slog.Record) *slog.Source {
 	fs := runtime.CallersFrames([]uintptr{r.PC})
 	f, _ := fs.Next()
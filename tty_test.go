@@ -2,17 +2,29 @@ package logf
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"log/slog"
 )
 
 const testTTYoutput = `   INFO    ok
-   INFO    ok	l1:{}
+   INFO    ok
    INFO    ok	l1:{a1:1}
    WARN    ok	l2:{a2:2}
-   DEBUG   ok	l1:{}
+   DEBUG   ok
    INFO    ok	l1:{a1:1}
    WARN    ok	l2:{a2:2}
    ERROR   ok	l3:{a3:3 err:<nil>}
@@ -139,66 +151,2264 @@ func TestTTYReplace(t *testing.T) {
 		}...),
 	}...))
 	want(`redacted, redacted	secret:redacted group:{secret:redacted group2:{secret:redacted secret:redacted}}`)
+
+	// each attr is replaced exactly once per record
+	var calls int
+	log = New().
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == "n" {
+				calls++
+				a.Value = slog.StringValue(a.Value.String() + "!")
+			}
+			return a
+		}).
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.With("n", 1).Info("with")
+	want(`with	n:1!`)
+
+	calls = 0
+	log.Info("local", "n", 2)
+	want(`local	n:2!`)
+	if calls != 1 {
+		t.Errorf("replace calls: want 1, got %d", calls)
+	}
+
+	log.Info("group", Group("g", KV("n", 3)))
+	want(`group	g:{n:3!}`)
+
+	log.Infof("{n}", "n", 4)
+	want(`4!	n:4!`)
 }
 
-const testTTYAuxOutput = `{"level":"INFO","msg":"buffer: auto"}
- ▏ buffer: forced TTY
-{"level":"INFO","msg":"buffer: forced auxilliary"}
-{"level":"INFO","msg":"buffer: forced TTY and auxilliary"}
- ▏ buffer: forced TTY and auxilliary
-`
+func TestTTYReplaceScope(t *testing.T) {
+	var b bytes.Buffer
 
-func TestTTYAux(t *testing.T) {
+	want := func(want string) {
+		t.Helper()
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("\n\texpected %s\n\tin %s", want, b.String())
+		}
+		b.Reset()
+	}
+
+	// redacts only outer.secret
+	replace := func(scope []string, a Attr) Attr {
+		if a.Key == "secret" && strings.Join(scope, ".") == "outer" {
+			a.Value = slog.StringValue("redacted")
+		}
+		return a
+	}
+
+	log := New().
+		ReplaceFunc(replace).
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.Info("local", "secret", 1, Group("outer", KV("secret", 2)))
+	want(`local	secret:1 outer:{secret:redacted}`)
+
+	outer := log.WithGroup("outer")
+	outer.Info("scoped", "secret", 3, Group("inner", KV("secret", 4)))
+	want(`scoped	outer:{secret:redacted inner:{secret:4}}`)
+
+	outer.With("secret", 5).Info("preformatted")
+	want(`preformatted	outer:{secret:redacted}`)
+
+	log.With("secret", 6).WithGroup("outer").With("secret", 7).Info("both")
+	want(`both	secret:6 outer:{secret:redacted}`)
+
+	// the scopes seen match those given by a slog.JSONHandler
+	seen := func(scopes *[]string) func([]string, Attr) Attr {
+		return func(scope []string, a Attr) Attr {
+			if a.Value.Kind() != slog.KindGroup && a.Key == "secret" {
+				*scopes = append(*scopes, strings.Join(append(scope[:len(scope):len(scope)], a.Key), "."))
+			}
+			return a
+		}
+	}
+
+	logAll := func(l *slog.Logger) {
+		l.Info("", "secret", 1, Group("outer", KV("secret", 2)))
+		l = l.WithGroup("outer").With("secret", 3)
+		l.Info("", "secret", 4, Group("inner", KV("secret", 5)))
+	}
+
+	var ttyScopes, jsonScopes []string
+	logAll(New().
+		ReplaceFunc(seen(&ttyScopes)).
+		Writer(&b).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().Logger)
+	logAll(slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{ReplaceAttr: seen(&jsonScopes)})))
+	b.Reset()
+
+	// (the TTY also replaces stored attrs for each record, to interpolate them)
+	for _, scopes := range []*[]string{&ttyScopes, &jsonScopes} {
+		slices.Sort(*scopes)
+		*scopes = slices.Compact(*scopes)
+	}
+
+	if !slices.Equal(ttyScopes, jsonScopes) {
+		t.Errorf("\n\tTTY scopes  %v\n\tJSON scopes %v", ttyScopes, jsonScopes)
+	}
+}
+
+func TestTTYEmptyGroups(t *testing.T) {
 	var b bytes.Buffer
 
-	auto := func(cfg *Config) Logger {
-		return cfg.
-			ForceTTY(false).
-			ForceAux(false).
-			Logger()
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want+"\n" {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
 	}
 
-	forceTTY := func(cfg *Config) Logger {
-		return cfg.
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.WithGroup("").Info("empty name", "k", 1)
+	want("empty name\tk:1")
+
+	log.With("k", 1).WithGroup("g").Info("empty scope")
+	want("empty scope\tk:1")
+
+	log.WithGroup("g").WithGroup("h").Info("nested", "k", 1)
+	want("nested\tg:{h:{k:1}}")
+
+	log.WithGroup("g").With("k", 1).WithGroup("h").Info("partial")
+	want("partial\tg:{k:1}")
+
+	log.WithGroup("g").With("k", 1).WithGroup("h").Info("partial", "j", 2)
+	want("partial\tg:{k:1 h:{j:2}}")
+
+	log.WithGroup("g").With("", 1).Info("empty key")
+	want("empty key")
+
+	log.Info("empty group", Group("e"), "k", 1)
+	want("empty group\tk:1")
+
+	log.Infof("{g}", Group("g", Group("e"), KV("k", 1)))
+	want("[k=1]\tg:{k:1}")
+
+	if store := (Store{}).WithGroup(""); len(store.scope) != 0 {
+		t.Errorf("Store.WithGroup: opened an empty group")
+	}
+}
+
+func TestTTYFilter(t *testing.T) {
+	var b bytes.Buffer
+
+	tty := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		TTY()
+
+	log := tty.Logger()
+
+	tty.Filter("a")
+	log.With("#", "a").Info("a")
+	log.With("#", "b").Info("b")
+	log.Info("c", "#", "c")
+
+	tty.Filter()
+	log.With("#", "b").Info("b")
+
+	if want, got := "a\nb\n", b.String(); want != got {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+}
+
+func TestTTYTagJoin(t *testing.T) {
+	var b bytes.Buffer
+
+	tty := New().
+		Writer(&b).
+		ShowLayout("tags", "message").
+		ShowColor(false).
+		ForceTTY(true).
+		TagJoin("/").
+		TTY()
+
+	log := tty.Logger().With("#", "a").With("#", "b")
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	log.Info("joined")
+	want("a/b joined\n")
+
+	// filters match the joined tag, or a component
+	for _, filter := range []string{"a/b", "a", "b"} {
+		tty.Filter(filter)
+		log.Info(filter)
+		want("a/b " + filter + "\n")
+	}
+
+	tty.Filter("a/b/c")
+	log.Info("record tag", "#", "c")
+	log.Info("filtered")
+	want("a/b record tag\n")
+	tty.Filter()
+
+	// the Handler wrapper joins tags, also when rebuilding
+	jlog := New().
+		Writer(&b).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == slog.TimeKey {
+				return Attr{}
+			}
+			return a
+		}).
+		TagJoin("/").
+		JSON().
+		With("#", "a", "x", 1).
+		With("#", "b")
+
+	jlog.Without("x").Info("ok")
+	want(`{"level":"INFO","msg":"ok","#":"a","#":"a/b"}` + "\n")
+}
+
+func TestTTYTags(t *testing.T) {
+	var b bytes.Buffer
+
+	tty := New().
+		Writer(&b).
+		ShowLayout("tags", "message").
+		ShowColor(false).
+		ForceTTY(true).
+		TTY()
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	log := tty.Logger().Tags("http", "slow")
+	log.Info("set")
+	want("http slow set\n")
+
+	log.Tags("slow", "db").Info("added")
+	want("http slow db added\n")
+
+	log.With("#", "a", "#", "b").Info("replaced")
+	want("a b replaced\n")
+
+	tty.Filter("slow")
+	log.Info("any")
+	log.Info("record", "#", "other")
+	log.Info("record set", "#", tagSet{"other"})
+	tty.Logger().Tags("http").Info("none")
+	want("http slow any\nhttp slow record set\n")
+	tty.Filter()
+}
+
+func TestTTYTagKey(t *testing.T) {
+	var b bytes.Buffer
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	cfg := func(key string) *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("tags", "message", "\t", "attrs").
+			ShowColor(false).
 			ForceTTY(true).
-			ForceAux(false).
-			Logger()
+			TagKey(key)
 	}
 
-	forceAux := func(cfg *Config) Logger {
-		return cfg.
-			ForceTTY(false).
+	tty := cfg("logf.tag").TTY()
+	log := tty.Logger()
+
+	log.With("logf.tag", "t", "#", "x").Info("ok")
+	want("t ok\t#:x\n")
+
+	log.Tags("a", "b").Info("ok")
+	want("a b ok\n")
+
+	tty.Filter("t")
+	log.With("#", "t").Info("filtered")
+	log.With("logf.tag", "t").Info("ok")
+	want("t ok\n")
+	tty.Filter()
+
+	// disabled
+	log = cfg("").Logger()
+	log.With("#", "x").Tags("a").Info("ok")
+	want("ok\t#:x\n")
+}
+
+func TestTTYTagKeys(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("tags", "message").
+		ShowColor(false).
+		ForceTTY(true).
+		ShowTag("method", "").
+		ShowTag("span_id", "").
+		ShowTagKeys(true).
+		Logger()
+
+	log.With("#", "label", "method", "GET").
+		Info("ok", Group("req", Group("span", "span_id", "0x5f")))
+
+	if want, got := "label method=GET req.span.span_id=0x5f ok\n", b.String(); want != got {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+}
+
+func TestTTYTagOrder(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("tags", "message").
+		ShowColor(false).
+		ForceTTY(true).
+		ShowTag("a", "").
+		ShowTag("b", "").
+		ShowTag("c", "").
+		ShowTagKeys(true).
+		ShowTagOrder("b", "a").
+		Logger()
+
+	// the same tags, given in different orders
+	for _, tc := range []struct {
+		log  Logger
+		args []any
+	}{
+		{log.With("a", 1).With("b", 2).With("#", "x"), []any{"c", 3}},
+		{log.With("#", "x").With("b", 2, "a", 1), []any{"c", 3}},
+		{log.With("c", 3).With("#", "x"), []any{"a", 1, "b", 2}},
+		{log.With("#", "x"), []any{"c", 3, Group("g", "n", 0), "a", 1, "b", 2}},
+	} {
+		tc.log.Info("ok", tc.args...)
+		if want, got := "x b=2 a=1 c=3 ok\n", b.String(); want != got {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+}
+
+func TestTTYAuxTagKey(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := func() *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("tags", "message").
+			ShowColor(false).
+			ForceTTY(true).
 			ForceAux(true).
-			Logger()
+			ReplaceFunc(func(scope []string, a Attr) Attr {
+				if a.Key == slog.TimeKey {
+					return Attr{}
+				}
+				return a
+			})
 	}
 
-	forceBoth := func(cfg *Config) Logger {
-		return cfg.
+	for _, tc := range []struct {
+		cfg  *Config
+		want string
+	}{
+		{cfg(), `{"level":"INFO","msg":"ok","#":"a","n":1}` + "\na ok\n"},
+		{cfg().AuxTagKey("tag"), `{"level":"INFO","msg":"ok","tag":"a","n":1}` + "\na ok\n"},
+		{cfg().AuxTagKey(""), `{"level":"INFO","msg":"ok","n":1}` + "\na ok\n"},
+	} {
+		tc.cfg.Logger().With("#", "a").Info("ok", "n", 1)
+		if got := b.String(); got != tc.want {
+			t.Errorf("\n\twant %q\n\tgot  %q", tc.want, got)
+		}
+		b.Reset()
+	}
+}
+
+func TestTTYFilterAux(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := func() *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("tags", "message").
+			ShowColor(false).
 			ForceTTY(true).
 			ForceAux(true).
-			Logger()
+			ReplaceFunc(func(scope []string, a Attr) Attr {
+				if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+					return Attr{}
+				}
+				return a
+			})
 	}
 
-	cfg := New().
-		ShowLayout("level", "message", "attrs").
-		ShowColor(false).
+	for _, tc := range []struct {
+		cfg  *Config
+		want string
+	}{
+		{cfg(), `{"msg":"ok","#":"a"}` + "\na ok\n" + `{"msg":"no","#":"b"}` + "\n"},
+		{cfg().FilterAux(true), `{"msg":"ok","#":"a"}` + "\na ok\n"},
+	} {
+		tty := tc.cfg.TTY()
+		tty.Filter("a")
+		log := tty.Logger()
+		log.With("#", "a").Info("ok")
+		log.With("#", "b").Info("no")
+		if got := b.String(); got != tc.want {
+			t.Errorf("\n\twant %q\n\tgot  %q", tc.want, got)
+		}
+		b.Reset()
+	}
+}
+
+func TestTTYOnRecord(t *testing.T) {
+	var b bytes.Buffer
+	var n int
+
+	log := New().
 		Writer(&b).
+		ShowLayout("message", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		ForceAux(true).
 		ReplaceFunc(func(scope []string, a Attr) Attr {
-			if a.Key == "time" {
+			if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
 				return Attr{}
 			}
 			return a
-		})
+		}).
+		OnRecord(func(ctx context.Context, r *slog.Record) bool {
+			return !strings.HasPrefix(r.Message, "drop")
+		}).
+		OnRecord(func(ctx context.Context, r *slog.Record) bool {
+			n++
+			r.Message = strings.ToUpper(r.Message)
+			r.AddAttrs(slog.Int("n", n))
+			return true
+		}).
+		Logger()
 
-	auto(cfg).Info("buffer: auto")
-	forceTTY(cfg).Info("buffer: forced TTY")
-	forceAux(cfg).Info("buffer: forced auxilliary")
-	forceBoth(cfg).Info("buffer: forced TTY and auxilliary")
+	log.Info("ok")
+	log.Info("drop me")
+	log.Info("ok")
 
-	want, got := testTTYAuxOutput, b.String()
-	if want != got {
-		t.Log(want)
-		t.Log(got)
-		t.Error("TTY aux")
+	want := `{"msg":"OK","n":1}` + "\nOK n:1\n" + `{"msg":"OK","n":2}` + "\nOK n:2\n"
+	if got := b.String(); got != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+}
+
+func TestTTYMaps(t *testing.T) {
+	var b bytes.Buffer
+	cfg := New().
+		Writer(&b).
+		ShowLayout("message", "attrs").
+		ShowQuoting("never").
+		ShowColor(false).
+		ForceTTY(true)
+	log := cfg.Logger()
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	log.Info("", "m", map[string]int{"b": 2, "a": 1, "c": 3})
+	want("m:{a:1 b:2 c:3}\n")
+
+	log.Info("", "m", map[int][]map[string]bool{10: {{"y": true}}, 9: nil})
+	want("m:{9:[] 10:[{y:true}]}\n")
+
+	// fmt sorts too, but in its own style
+	log.Infof("{m}", "m", map[string]int{"b": 2, "a": 1})
+	want("{a:1 b:2} m:{a:1 b:2}\n")
+	if got := Fmt("{}", map[string]int{"b": 2, "a": 1}); got != "map[a:1 b:2]" {
+		t.Errorf("Fmt: got %s", got)
 	}
+
+	// limits
+	big := make(map[int]int)
+	for i := 0; i < collectionMaxElems+2; i++ {
+		big[i] = i
+	}
+	log.Info("", "m", big)
+	if got := b.String(); !strings.HasSuffix(got, "63:63 …+2}\n") {
+		t.Errorf("entries: got %q", got)
+	}
+	b.Reset()
+
+	self := map[string]any{}
+	self["self"] = self
+	log.Info("", "m", map[string]map[string]any{"self": self})
+	if got := b.String(); !strings.Contains(got, "{...}") {
+		t.Errorf("depth: got %q", got)
+	}
+	b.Reset()
+
+	// unsorted
+	log = cfg.ShowMapSort(false).Logger()
+	log.Info("", "m", map[string]int{"a": 1})
+	want("m:{a:1}\n")
+}
+
+func TestTTYPreformatFormatter(t *testing.T) {
+	var plain, color bytes.Buffer
+	cfg := func(w io.Writer, colors bool) *Config {
+		return New().
+			Writer(w).
+			ShowLayout("message", "\t", "attrs", "tags").
+			ShowColor(colors).
+			ForceTTY(true)
+	}
+
+	log := cfg(&plain, false).Logger().
+		With("#", "db").
+		WithGroup("req").
+		With("path", "/", "status", 200)
+	want := cfg(&color, true).Logger().
+		With("#", "db").
+		WithGroup("req").
+		With("path", "/", "status", 200)
+
+	log.Info("ok")
+	want.Info("ok")
+
+	// preformatted text is rendered again for the formatter in use
+	tty := log.Handler().(*TTY)
+	prev := tty.dev.fmtr
+	tty.dev.fmtr = want.Handler().(*TTY).dev.fmtr
+	log.Info("ok")
+	tty.dev.fmtr = prev
+	log.Info("ok")
+
+	lines := strings.SplitAfter(plain.String(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %q", plain.String())
+	}
+	if lines[0] != "ok\treq:{path:/ status:200} db\n" || lines[2] != lines[0] {
+		t.Errorf("plain: got %q, %q", lines[0], lines[2])
+	}
+	if lines[1] != color.String() {
+		t.Errorf("\n\twant %q\n\tgot  %q", color.String(), lines[1])
+	}
+}
+
+func TestTTYShowAttrs(t *testing.T) {
+	var b bytes.Buffer
+	cfg := func(enc Encoder[[]Attr]) *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("message", "\t", "attrs", "tags").
+			ShowAttrs("", enc).
+			ReplaceFunc(func(scope []string, a Attr) Attr {
+				if a.Key == "token" {
+					a.Value = slog.StringValue("xxx")
+				}
+				return a
+			}).
+			ShowColor(false).
+			ForceTTY(true)
+	}
+
+	logWith := func(log Logger) {
+		log.
+			With("#", "db", "a", 1).
+			WithGroup("req").
+			WithGroup("empty").
+			Info("ok", "token", "abc")
+		log.
+			With("a", 1).
+			WithGroup("req").
+			With("path", "/x y", "token", "abc").
+			Info("ok", "status", 200, "pw", Redact("hunter2"))
+		log.WithGroup("req").Info("none")
+	}
+
+	logWith(cfg(AttrsJSON).Logger())
+	want := "ok\t{\"a\":1,\"req\":{\"empty\":{\"token\":\"xxx\"}}} db\n" +
+		"ok\t{\"a\":1,\"req\":{\"path\":\"/x y\",\"token\":\"xxx\",\"status\":200,\"pw\":\"[REDACTED]\"}}\n" +
+		"none\n"
+	if b.String() != want {
+		t.Errorf("AttrsJSON:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+
+	b.Reset()
+	logWith(cfg(AttrsLogfmt).Logger())
+	want = "ok\ta=1 req.empty.token=xxx db\n" +
+		"ok\ta=1 req.path=\"/x y\" req.token=xxx req.status=200 req.pw=[REDACTED]\n" +
+		"none\n"
+	if b.String() != want {
+		t.Errorf("AttrsLogfmt:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+
+	// preformatted attrs reach an attrs encoder installed later
+	b.Reset()
+	log := cfg(nil).Logger().With("a", 1).WithGroup("g").With("b", 2)
+	log.Info("ok")
+	tty := log.Handler().(*TTY)
+	tty.dev.fmtr = cfg(AttrsJSON).TTY().dev.fmtr
+	log.Info("ok", "c", 3)
+	want = "ok\ta:1 g:{b:2}\n" +
+		"ok\t{\"a\":1,\"g\":{\"b\":2,\"c\":3}}\n"
+	if b.String() != want {
+		t.Errorf("swapped:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}
+
+func TestTTYShowFieldAt(t *testing.T) {
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("level", "message", "\t", "attrs", " ", "tags", "\n", "source").
+		ShowLevel(LevelText).
+		ShowSource("", SourceShort).
+		ShowFieldAt("attrs", WARN).
+		ShowFieldAt("src", WARN).
+		ShowFieldAt("\t", ERROR).
+		AddSource(true).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		With("#", "db")
+
+	log.Info("info", "a", 1)
+	_, _, line, _ := runtime.Caller(0)
+	log.Warn("warn", "a", 1)
+
+	want := "   INFO    info db\n" +
+		fmt.Sprintf("   WARN    warn\ta:1 db\n\ttty_test.go:%d\n", line+1)
+	if b.String() != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+	}
+
+	// the newline isn't written if no field follows it
+	b.Reset()
+	log = New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs", "\n", "source").
+		ShowFieldAt("source", WARN).
+		AddSource(true).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	log.Info("info", "a", 1)
+	log.Info("info")
+	if want := "info\ta:1\ninfo\n"; b.String() != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}
+
+func TestTTYShowAttrsDiff(t *testing.T) {
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowAttrsDiff(true).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	a := log.With("svc", "api", "region", "us")
+	req := a.WithGroup("req").With("id", 7)
+	other := log.With("svc", "db")
+
+	a.Info("one", "n", 1)
+	a.Info("two", "n", 2)
+	req.Info("three", "status", 200)
+	req.Info("four", "status", 404)
+	other.Info("five")
+	a.Info("six")
+	a.Log(ERROR, "seven", "n", 7)
+	a.Info("eight")
+	log.Info("nine", "n", 9)
+	a.Info("ten")
+
+	want := []string{
+		"one\tsvc:api region:us n:1",
+		"two\t(+2 ctx) n:2",
+		"three\tsvc:api region:us req:{id:7 status:200}",
+		"four\t(+3 ctx) req:{status:404}",
+		"five\tsvc:db",
+		"six\tsvc:api region:us",
+		"seven\tsvc:api region:us n:7",
+		"eight\t(+2 ctx)",
+		"nine\tn:9",
+		"ten\tsvc:api region:us",
+	}
+	if got := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n"); !slices.Equal(want, got) {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+	}
+
+	// placeholders are dim
+	b.Reset()
+	log = New().
+		Writer(&b).
+		ShowLayout("attrs").
+		ShowAttrsDiff(true).
+		ShowColor(true).
+		ForceTTY(true).
+		Logger().
+		With("a", 1)
+	log.Info("")
+	b.Reset()
+	log.Info("")
+	if want := "\x1b[2m(+1 ctx)\x1b[0m\n"; b.String() != want {
+		t.Errorf("want %q, got %q", want, b.String())
+	}
+
+	// concurrently, a line is compared with the line written before it
+	b.Reset()
+	log = New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowAttrsDiff(true).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	var wg sync.WaitGroup
+	for _, l := range []Logger{log.With("svc", "api"), log.With("svc", "db")} {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(l Logger) {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					l.Info(l.Fmt("{svc}"))
+				}
+			}(l)
+		}
+	}
+	wg.Wait()
+
+	var prev string
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		msg, attrs, _ := strings.Cut(line, "\t")
+		want := "svc:" + msg
+		if msg == prev {
+			want = "(+1 ctx)"
+		}
+		if attrs != want {
+			t.Fatalf("after %s: want %q, got %q", prev, want, line)
+		}
+		prev = msg
+	}
+}
+
+func TestTTYGroupTree(t *testing.T) {
+	var b bytes.Buffer
+	cfg := func(colors bool) *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("message", "\t", "attrs", " ", "tags").
+			ShowGroupStyle("tree").
+			ShowColor(colors)
+	}
+
+	logWith := func(log Logger) {
+		log.
+			With("#", "db", "svc", "api").
+			WithGroup("req").
+			With("id", 7, "empty", slog.GroupValue()).
+			Info("ok", "user", slog.GroupValue(slog.String("name", "gopher"), slog.Int("age", 13)), "status", 200)
+	}
+
+	// as if writing to a terminal
+	tree := cfg(false)
+	tree.enableTTY = true
+	logWith(tree.Logger())
+
+	want := "ok db\n" +
+		"\t├─ svc:api\n" +
+		"\t└─ req:\n" +
+		"\t   ├─ id:7\n" +
+		"\t   ├─ user:\n" +
+		"\t   │  ├─ name:gopher\n" +
+		"\t   │  └─ age:13\n" +
+		"\t   └─ status:200\n"
+	if b.String() != want {
+		t.Errorf("tree:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+
+	// colors
+	b.Reset()
+	tree = cfg(true)
+	tree.enableTTY = true
+	tree.Logger().Info("ok", "a", 1)
+	if want := "ok\n\t\x1b[2m└─ \x1b[0m\x1b[36;2ma:\x1b[0m\x1b[36m1\x1b[0m\n"; b.String() != want {
+		t.Errorf("colors:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+
+	// braces, writing elsewhere
+	b.Reset()
+	logWith(cfg(false).ForceTTY(true).Logger())
+	if want := "ok\tsvc:api req:{id:7 user:{name:gopher age:13} status:200} db\n"; b.String() != want {
+		t.Errorf("braces:\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}
+
+func TestTTYAbbreviate(t *testing.T) {
+	var b, aux bytes.Buffer
+	cfg := func(style string) *Config {
+		return New().
+			Writer(&b).
+			ShowLayout("message", "\t", "attrs").
+			ShowGroupStyle(style).
+			AbbreviateKey("kubernetes.pod.name", "pod").
+			AbbreviateKey("request.status", "code").
+			AbbreviateScope("request", "req").
+			Aux(slog.NewJSONHandler(&aux, &slog.HandlerOptions{
+				ReplaceAttr: func(scope []string, a Attr) Attr {
+					if len(scope) == 0 && a.Key == slog.TimeKey {
+						return Attr{}
+					}
+					return a
+				},
+			})).
+			ShowColor(false).
+			ForceTTY(true).
+			ForceAux(true)
+	}
+
+	logWith := func(log Logger) {
+		log.
+			With("kubernetes.pod.name", "api-1").
+			WithGroup("request").
+			With("id", 7).
+			Log(INFO, "{kubernetes.pod.name} {request.id}", "req", slog.GroupValue(slog.Int("status", 200)), "status", 404)
+	}
+
+	for _, tc := range []struct {
+		style string
+		want  string
+	}{
+		{"braces", "api-1 7\tpod:api-1 req:{id:7 req:{status:200} code:404}\n"},
+		{"dots", "api-1 7\tpod:api-1 req.id:7 req.req.status:200 code:404\n"},
+	} {
+		b.Reset()
+		aux.Reset()
+		logWith(cfg(tc.style).Logger())
+		if b.String() != tc.want {
+			t.Errorf("%s:\n\twant %q\n\tgot  %q", tc.style, tc.want, b.String())
+		}
+
+		// the aux handler sees full keys
+		want := `{"level":"INFO","msg":"api-1 7","kubernetes.pod.name":"api-1","request":{"id":7,"req":{"status":200},"status":404}}` + "\n"
+		if aux.String() != want {
+			t.Errorf("%s aux:\n\twant %q\n\tgot  %q", tc.style, want, aux.String())
+		}
+	}
+}
+
+func TestTTYPrinter(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := New().
+		Writer(&b).
+		ShowColor(false).
+		ShowLevel(LevelText).
+		ForceTTY(true)
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	cfg.Printer().Info("ok", "a", 1)
+	want("ok\n")
+
+	// the Config's layout is unchanged
+	if got, want := cfg.TTY().dev.fmtr.layout, newTTYFormatter().layout; !slices.Equal(got, want) {
+		t.Errorf("layout: want %v, got %v", want, got)
+	}
+	cfg.Logger().Info("ok", "a", 1)
+	if got := b.String(); !strings.Contains(got, "INFO") || !strings.HasSuffix(got, " ok\ta:1\n") {
+		t.Errorf("logger: %q", got)
+	}
+	b.Reset()
+
+	// configured
+	cfg.PrinterLayout("level", " ", "message").Printer().With("#", "x").Warn("ok", "a", 1)
+	want("   WARN    ok\n")
+}
+
+// run with -race
+func TestTTYFilterConcurrent(t *testing.T) {
+	tty := New().
+		Writer(io.Discard).
+		ShowColor(false).
+		ForceTTY(true).
+		TTY()
+
+	log := tty.Logger().With("#", "a")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			log.Info("ok", "#", "b")
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		tty.Filter("a", strconv.Itoa(i))
+	}
+	<-done
+}
+
+const testTTYAuxOutput = `{"level":"INFO","msg":"buffer: auto"}
+ ▏ buffer: forced TTY
+{"level":"INFO","msg":"buffer: forced auxilliary"}
+{"level":"INFO","msg":"buffer: forced TTY and auxilliary"}
+ ▏ buffer: forced TTY and auxilliary
+`
+
+func TestTTYAux(t *testing.T) {
+	var b bytes.Buffer
+
+	auto := func(cfg *Config) Logger {
+		return cfg.
+			ForceTTY(false).
+			ForceAux(false).
+			Logger()
+	}
+
+	forceTTY := func(cfg *Config) Logger {
+		return cfg.
+			ForceTTY(true).
+			ForceAux(false).
+			Logger()
+	}
+
+	forceAux := func(cfg *Config) Logger {
+		return cfg.
+			ForceTTY(false).
+			ForceAux(true).
+			Logger()
+	}
+
+	forceBoth := func(cfg *Config) Logger {
+		return cfg.
+			ForceTTY(true).
+			ForceAux(true).
+			Logger()
+	}
+
+	cfg := New().
+		ShowLayout("level", "message", "attrs").
+		ShowColor(false).
+		Writer(&b).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == "time" {
+				return Attr{}
+			}
+			return a
+		})
+
+	auto(cfg).Info("buffer: auto")
+	forceTTY(cfg).Info("buffer: forced TTY")
+	forceAux(cfg).Info("buffer: forced auxilliary")
+	forceBoth(cfg).Info("buffer: forced TTY and auxilliary")
+
+	want, got := testTTYAuxOutput, b.String()
+	if want != got {
+		t.Log(want)
+		t.Log(got)
+		t.Error("TTY aux")
+	}
+}
+
+type ctxKey struct{}
+
+// records context values given to Enabled and Handle
+type ctxAux struct {
+	min     slog.Level
+	enabled []any
+	handled []any
+}
+
+func (h *ctxAux) Enabled(ctx context.Context, level slog.Level) bool {
+	h.enabled = append(h.enabled, ctx.Value(ctxKey{}))
+	return level >= h.min
+}
+
+func (h *ctxAux) Handle(ctx context.Context, r slog.Record) error {
+	h.handled = append(h.handled, ctx.Value(ctxKey{}))
+	return nil
+}
+
+func (h *ctxAux) WithAttrs([]Attr) slog.Handler { return h }
+func (h *ctxAux) WithGroup(string) slog.Handler { return h }
+
+func TestTTYAuxContext(t *testing.T) {
+	var b bytes.Buffer
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	// TTY and aux
+	aux := new(ctxAux)
+	New().
+		Writer(&b).
+		ForceTTY(true).
+		ForceAux(true).
+		Aux(aux).
+		Logger().
+		InfoContext(ctx, "ok")
+
+	if len(aux.handled) != 1 || aux.handled[0] != "value" {
+		t.Errorf("aux handled contexts: %v", aux.handled)
+	}
+
+	// aux only, the TTY defers to aux.Enabled
+	aux = &ctxAux{min: WARN}
+	log := slog.New(New().
+		Writer(&b).
+		ForceTTY(false).
+		Aux(aux).
+		TTY())
+
+	log.InfoContext(ctx, "skipped")
+	log.WarnContext(ctx, "ok")
+
+	if len(aux.enabled) != 2 || aux.enabled[0] != "value" {
+		t.Errorf("aux enabled contexts: %v", aux.enabled)
+	}
+	if len(aux.handled) != 1 || aux.handled[0] != "value" {
+		t.Errorf("aux handled contexts: %v", aux.handled)
+	}
+}
+
+func TestTTYAuxRef(t *testing.T) {
+	var b bytes.Buffer
+	var ref, auxRef slog.LevelVar
+
+	log := New().
+		Writer(&b).
+		Ref(&ref).
+		AuxRef(&auxRef).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		ForceAux(true).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == slog.TimeKey {
+				return Attr{}
+			}
+			return a
+		}).
+		Logger()
+
+	for _, tc := range []struct {
+		ref, auxRef slog.Level
+		want        string
+	}{
+		// aux only
+		{INFO, DEBUG, `{"level":"DEBUG","msg":"ok"}` + "\n"},
+		// TTY only
+		{DEBUG, INFO, "ok\n"},
+		// both
+		{DEBUG, DEBUG, `{"level":"DEBUG","msg":"ok"}` + "\nok\n"},
+		// neither
+		{INFO, INFO, ""},
+	} {
+		ref.Set(tc.ref)
+		auxRef.Set(tc.auxRef)
+
+		if want, got := tc.ref == DEBUG || tc.auxRef == DEBUG, log.Handler().Enabled(context.Background(), DEBUG); want != got {
+			t.Errorf("%s, %s: want enabled %t", tc.ref, tc.auxRef, want)
+		}
+
+		log.Debug("ok")
+		if got := b.String(); got != tc.want {
+			t.Errorf("%s, %s:\n\twant %q\n\tgot  %q", tc.ref, tc.auxRef, tc.want, got)
+		}
+		b.Reset()
+	}
+}
+
+func TestTTYWithError(t *testing.T) {
+	var b bytes.Buffer
+
+	want := func(want string) {
+		t.Helper()
+		if want != b.String() {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+		}
+		b.Reset()
+	}
+
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		With("id", 1)
+
+	errDropped := errors.New("dropped")
+
+	log = log.WithError(errDropped)
+	log.Warnf("{id}: {err}")
+	want("1: dropped: dropped\tid:1 err:dropped\n")
+
+	log = log.WithError(nil)
+	log.Info("ok")
+	want("ok\tid:1\n")
+}
+
+func TestDepth(t *testing.T) {
+	var b bytes.Buffer
+
+	want := func(want string) {
+		t.Helper()
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("\n\texpected %s\n\tin %s", want, b.String())
+		}
+		b.Reset()
+	}
+
+	cfg := New().
+		Writer(&b).
+		AddSource(true).
+		ShowLayout("message", " ", "source").
+		ShowSource("", SourceShort).
+		ShowColor(false).
+		ForceTTY(true)
+
+	log := cfg.Logger()
+
+	depth1 := func(msg string) {
+		log.Depth(1).Infof(msg)
+	}
+
+	depth2 := func(msg string) {
+		func() {
+			log.Depth(2).Info(msg)
+		}()
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	depth1("depth 1")
+	want(fmt.Sprintf("depth 1 tty_test.go:%d", line+1))
+
+	depth2("depth 2")
+	want(fmt.Sprintf("depth 2 tty_test.go:%d", line+4))
+
+	skip := cfg.SourceSkip(1).Logger()
+	func() {
+		skip.Info("skip 1")
+	}()
+	want(fmt.Sprintf("skip 1 tty_test.go:%d", line+10))
+}
+
+func TestTTYSourceEncoders(t *testing.T) {
+	var b bytes.Buffer
+
+	encode := func(e Encoder[*slog.Source], src *slog.Source) string {
+		s := newSplicer()
+		defer s.free()
+		e.Encode(&Buffer{splicer: s}, src)
+		return s.line()
+	}
+
+	// within the main (test) module
+	mod, _ := mainModulePaths()
+	if mod != "github.com/AndrewHarrisSPU/logf" {
+		t.Skipf("unexpected main module %q", mod)
+	}
+
+	src := &slog.Source{
+		Function: "github.com/AndrewHarrisSPU/logf/testlog.(*T).Run.func1",
+		File:     "/home/gopher/logf/testlog/testlog.go",
+		Line:     12,
+	}
+	if got := encode(SourceRel, src); got != "testlog/testlog.go:12" {
+		t.Errorf("rel: got %s", got)
+	}
+	if got := encode(SourceFunc, src); got != "testlog.(*T).Run.func1:12" {
+		t.Errorf("func: got %s", got)
+	}
+
+	// outside the main module
+	src = &slog.Source{
+		Function: "net/http.(*conn).serve",
+		File:     "/usr/local/go/src/net/http/server.go",
+		Line:     3,
+	}
+	if got := encode(SourceRel, src); got != "net/http/server.go:3" {
+		t.Errorf("rel: got %s", got)
+	}
+	if got := encode(SourceFunc, src); got != "http.(*conn).serve:3" {
+		t.Errorf("func: got %s", got)
+	}
+
+	// as configured, from a record
+	log := New().
+		Writer(&b).
+		AddSource(true).
+		ShowLayout("message", " ", "source").
+		ShowSource("", SourceRel).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Info("rel")
+	if want := fmt.Sprintf("rel tty_test.go:%d\n", line+1); b.String() != want {
+		t.Errorf("record: want %q, got %q", want, b.String())
+	}
+}
+
+func TestTTYSourceLink(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := New().
+		Writer(&b).
+		AddSource(true).
+		ShowLayout("message", " ", "source").
+		ShowSource("", SourceShort).
+		ShowSourceLink("vscode")
+
+	// as if writing to a terminal
+	cfg.enableTTY = true
+	log := cfg.Logger()
+
+	_, file, line, _ := runtime.Caller(0)
+	log.Info("linked")
+
+	target := "vscode://file" + (&url.URL{Path: file}).EscapedPath() + ":" + strconv.Itoa(line+1)
+	text := "tty_test.go:" + strconv.Itoa(line+1)
+	want := "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("linked: want %q\n\tin %q", want, b.String())
+	}
+	b.Reset()
+
+	// plain text without colors
+	cfg.ShowColor(false).Logger().Info("plain")
+	if got := b.String(); strings.Contains(got, "\x1b") || !strings.Contains(got, "plain tty_test.go:") {
+		t.Errorf("plain: got %q", got)
+	}
+
+	// escaping
+	src := &slog.Source{File: "/tmp/a b/#1?%.go", Line: 7}
+	if got := sourceURL("file://{file}", src); got != "file:///tmp/a%20b/%231%3F%25.go" {
+		t.Errorf("escaped: got %s", got)
+	}
+}
+
+func TestTTYLayoutAt(t *testing.T) {
+	var b bytes.Buffer
+
+	cfg := New().
+		Writer(&b).
+		Ref(new(slog.LevelVar)).
+		ShowLayout("tags", "message").
+		ShowLayoutAt(WARN, "level", "message", "\t", "attrs").
+		ShowLayoutAt(ERROR, "message", "\t", "attrs", " ", "source").
+		ShowSource("", SourcePkg).
+		ShowColor(false).
+		ForceTTY(true)
+
+	log := cfg.Logger().With("#", "tag", "a", 1)
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	log.Info("info")
+	want("tag info\n")
+
+	log.Warn("warn")
+	want("▕▎ warn\ta:1\n")
+
+	log.Error("error", errors.New("e"))
+	want("error: e\ta:1 err:e\n")
+
+	// added source is appended to each layout lacking it
+	log = cfg.AddSource(true).Logger().With("a", 1)
+
+	log.Info("info")
+	want("info\n\tlogf\n")
+
+	log.Error("error", errors.New("e"))
+	want("error: e\ta:1 err:e logf\n")
+}
+
+func TestTTYHostPid(t *testing.T) {
+	var b bytes.Buffer
+
+	pid := strconv.Itoa(os.Getpid())
+
+	cfg := New().
+		Writer(&b).
+		ShowLayout("host", "pid", "message").
+		ShowHost("", "replica-1").
+		ShowColor(false)
+
+	cfg.ForceTTY(true).Logger().Info("ok")
+	if want := "replica-1 " + pid + " ok\n"; b.String() != want {
+		t.Errorf("tty: want %q, got %q", want, b.String())
+	}
+	b.Reset()
+
+	// preset JSON aux
+	cfg.ForceTTY(false).Logger().Info("ok")
+	if want := `"msg":"ok","host":"replica-1","pid":` + pid; !strings.Contains(b.String(), want) {
+		t.Errorf("aux: expected %s in %s", want, b.String())
+	}
+	b.Reset()
+
+	// no fields, no aux attrs
+	cfg.ShowLayout("message").Logger().Info("ok")
+	if strings.Contains(b.String(), "host") {
+		t.Errorf("aux: unexpected host in %s", b.String())
+	}
+}
+
+func TestBufferPad(t *testing.T) {
+	pad := func(text string, width int, align byte) string {
+		s := newSplicer()
+		defer s.free()
+
+		s.WriteString("prefix:")
+		b := &Buffer{splicer: s, mark: len(s.text)}
+		b.WriteString(text)
+		b.Pad(width, align)
+		return s.line()[len("prefix:"):]
+	}
+
+	for _, tc := range []struct {
+		text  string
+		width int
+		align byte
+		want  string
+	}{
+		{"ok", 6, '<', "ok    "},
+		{"ok", 6, '>', "    ok"},
+		{"ok", 5, '^', " ok  "},
+		{"toolong", 3, '<', "toolong"},
+
+		// full-width
+		{"日本", 6, '<', "日本  "},
+		{"ＡＢ", 6, '>', "  ＡＢ"},
+
+		// ZWJ sequences, modifiers, and flags
+		{"👩‍🦰", 4, '<', "👩‍🦰  "},
+		{"👨‍👩‍👧", 4, '>', "  👨‍👩‍👧"},
+		{"👍🏽", 3, '<', "👍🏽 "},
+		{"🇺🇸", 3, '<', "🇺🇸 "},
+		{"❤️", 3, '<', "❤️ "},
+
+		// combining marks
+		{"e\u0301", 3, '<', "e\u0301  "},
+
+		// escapes
+		{"\x1b[31mred\x1b[0m", 5, '<', "\x1b[31mred\x1b[0m  "},
+		{"\x1b]8;;file:///x\x1b\\x\x1b]8;;\x1b\\", 2, '>', " \x1b]8;;file:///x\x1b\\x\x1b]8;;\x1b\\"},
+	} {
+		if got := pad(tc.text, tc.width, tc.align); got != tc.want {
+			t.Errorf("Pad(%q, %d, %q): want %q, got %q", tc.text, tc.width, tc.align, tc.want, got)
+		}
+	}
+
+	// as used by LevelText
+	var b bytes.Buffer
+	New().
+		Writer(&b).
+		ShowLayout("level", "message").
+		ShowLevel(LevelText).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		Info("ok")
+	if want := "   INFO    ok\n"; b.String() != want {
+		t.Errorf("LevelText: want %q, got %q", want, b.String())
+	}
+}
+
+func TestLevelBarWith(t *testing.T) {
+	var b bytes.Buffer
+	encode := func(style LevelBarStyle, levels ...slog.Level) string {
+		b.Reset()
+		var ref slog.LevelVar
+		ref.Set(DEBUG)
+		log := New().
+			Writer(&b).
+			Ref(&ref).
+			ShowLayout("level", "message").
+			ShowLevel(LevelBarWith(style)).
+			ShowColor(false).
+			ForceTTY(true).
+			Logger()
+		for _, level := range levels {
+			log.Log(level, "x")
+		}
+		return b.String()
+	}
+
+	ascii := LevelBarStyle{Debug: " .", Info: " |", Warn: "||", Error: "|||", Width: 3}
+	if got, want := encode(ascii, DEBUG, INFO, INFO+1, WARN, ERROR, ERROR+4), " . x\n | x\n | x\n|| x\n|||x\n|||x\n"; got != want {
+		t.Errorf("ascii: want %q, got %q", want, got)
+	}
+
+	for _, tc := range []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"#####", 2, "##x\n"},
+		{"日本", 3, "日 x\n"},
+		{"日本", 4, "日本x\n"},
+		{"e\u0301e\u0301", 1, "e\u0301x\n"},
+		{"as is ", 0, "as is x\n"},
+	} {
+		if got := encode(LevelBarStyle{Info: tc.text, Width: tc.width}, INFO); got != tc.want {
+			t.Errorf("%q, %d: want %q, got %q", tc.text, tc.width, tc.want, got)
+		}
+	}
+}
+
+func TestTTYTrace(t *testing.T) {
+	var b bytes.Buffer
+	var ref slog.LevelVar
+	ref.Set(TRACE)
+
+	cfg := func(enc Encoder[slog.Level]) *Config {
+		return New().
+			Writer(&b).
+			Ref(&ref).
+			ShowLayout("level", "message").
+			ShowLevel(enc).
+			ShowColor(false).
+			ForceTTY(true)
+	}
+
+	for _, tc := range []struct {
+		enc  Encoder[slog.Level]
+		want string
+	}{
+		{LevelBar, " ╎ trace\n ╎ trace+1\n ▏ debug\n"},
+		{LevelBullet, " · trace\n · trace+1\n ╴ debug\n"},
+		{LevelText, "   TRACE   trace\n  TRACE+1  trace+1\n   DEBUG   debug\n"},
+		{LevelBarWith(LevelBarStyle{Debug: " .", Width: 3}), " . trace\n . trace+1\n . debug\n"},
+		{LevelBarWith(LevelBarStyle{Trace: " ,", Debug: " .", Width: 3}), " , trace\n , trace+1\n . debug\n"},
+	} {
+		log := cfg(tc.enc).Logger()
+		log.Trace("trace")
+		log.Log(TRACE+1, "trace+1")
+		log.Debug("debug")
+
+		if got := b.String(); got != tc.want {
+			t.Errorf("\n\twant %q\n\tgot  %q", tc.want, got)
+		}
+		b.Reset()
+	}
+
+	// aux and JSON output name levels as the TTY does
+	ref.Set(TRACE - 1)
+	for name, log := range map[string]Logger{
+		"aux":         New().Writer(&b).Ref(&ref).AuxRef(&ref).ForceAux(true).Logger(),
+		"JSON":        New().Writer(&b).Ref(&ref).JSON(),
+		"native JSON": New().Writer(&b).Ref(&ref).NativeJSON(true).JSON(),
+	} {
+		log.Trace("trace")
+		log.Log(TRACE-1, "trace-1")
+		log.Log(TRACE+1, "trace+1")
+		log.Debug("debug")
+
+		var levels []string
+		for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+			var rec struct{ Level string }
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatalf("%s: %v: %s", name, err, line)
+			}
+			levels = append(levels, rec.Level)
+		}
+		if want := []string{"TRACE", "TRACE-1", "TRACE+1", "DEBUG"}; !slices.Equal(want, levels) {
+			t.Errorf("%s:\n\twant %q\n\tgot  %q", name, want, levels)
+		}
+		b.Reset()
+	}
+	ref.Set(TRACE)
+
+	// a distinct color
+	log := cfg(LevelBar).
+		ShowColor(true).
+		ShowTraceColor("magenta").
+		ShowMessage("").
+		Logger().
+		With("n", 1)
+
+	log.Tracef("{n}")
+	if got := b.String(); !strings.HasPrefix(got, string(newPen("magenta"))+" ╎ ") {
+		t.Errorf("color: %q", got)
+	}
+	b.Reset()
+
+	// filtered at DEBUG
+	ref.Set(DEBUG)
+	log.Trace("trace")
+	if got := b.String(); got != "" {
+		t.Errorf("filtered: %q", got)
+	}
+}
+
+func TestBufferAppend(t *testing.T) {
+	enc := EncodeFunc(func(b *Buffer, v Value) {
+		switch v.Kind() {
+		case slog.KindInt64:
+			b.AppendInt(v.Int64())
+		case slog.KindUint64:
+			b.AppendUint(v.Uint64())
+		case slog.KindFloat64:
+			b.AppendFloat(v.Float64())
+		case slog.KindDuration:
+			b.AppendDuration(v.Duration())
+		case slog.KindTime:
+			b.AppendTimeRFC3339(v.Time())
+		default:
+			b.AppendQuote(v.String())
+		}
+	})
+
+	var b bytes.Buffer
+	New().
+		Writer(&b).
+		ShowLayout("attrs").
+		ShowAttrValue("", enc).
+		FloatFormat('f', 2).
+		TimePrecision(time.Second).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		Info("",
+			"i", -1,
+			"u", uint64(2),
+			"f", 3.14159,
+			"d", 1500*time.Millisecond,
+			"t", time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC),
+			"s", "a\tb",
+		)
+
+	want := `i:-1 u:2 f:3.14 d:1.5s t:2023-01-02T03:04:05Z s:"a\tb"` + "\n"
+	if b.String() != want {
+		t.Errorf("want %q, got %q", want, b.String())
+	}
+}
+
+func TestTTYGroupDots(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowGroupStyle("dots").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	log.Info("ok", slog.Group("outer", slog.Group("inner", "x", 1), "y", 2))
+	want("ok\touter.inner.x:1 outer.y:2\n")
+
+	log = log.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h")
+	log.Info("ok", "c", 3)
+	want("ok\ta:1 g.b:2 g.h.c:3\n")
+
+	log.Infof("{i} {*}", slog.Group("i", slog.Group("j", "d", 4)))
+	want("[j.d=4] a=1 g.b=2 g.h.i.j.d=4\ta:1 g.b:2 g.h.i.j.d:4\n")
+}
+
+func TestTTYKeyColor(t *testing.T) {
+	var b bytes.Buffer
+
+	const (
+		dimCyan = "\x1b[36;2m"
+		cyan    = "\x1b[36m"
+		dim     = "\x1b[2m"
+		yellow  = "\x1b[33m"
+		red     = "\x1b[31m"
+		reset   = "\x1b[0m"
+	)
+
+	cfg := New().
+		Writer(&b).
+		ShowLayout("attrs").
+		ShowKeyColor("uuid", "", "dim").
+		ShowKeyColor("status", "yellow", "yellow").
+		ShowKeyColor("req.status", "", "red").
+		ForceTTY(true)
+
+	log := cfg.Logger()
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	// call-time
+	log.Info("", "uuid", "u", "n", 1)
+	want(dimCyan + "uuid:" + reset + dim + "u" + reset + " " + dimCyan + "n:" + reset + cyan + "1" + reset + "\n")
+
+	// preformatted, and under groups
+	log.With("status", 200).WithGroup("req").With("status", 404).Info("")
+	want(yellow + "status:" + reset + yellow + "200" + reset + " " +
+		dimCyan + "req:" + reset + dim + "{" + reset +
+		dimCyan + "status:" + reset + red + "404" + reset +
+		dim + "}" + reset + "\n")
+
+	// no colors
+	cfg.ShowColor(false).Logger().Info("", "status", 200)
+	want("status:200\n")
+}
+
+func TestTTYInterpolated(t *testing.T) {
+	var b, aux bytes.Buffer
+
+	const (
+		yellow = "\x1b[33m"
+		reset  = "\x1b[0m"
+	)
+
+	cfg := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowInterpolated("yellow").
+		ForceTTY(true)
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	log := cfg.Logger().With("user", "gopher")
+
+	log.Infof("hello {user}, {} {0}", "n", 1)
+	want("hello " + yellow + "gopher" + reset + ", " + yellow + "1" + reset + " " + yellow + "1" + reset + "\n")
+
+	log.Infof("no values")
+	want("no values\n")
+
+	cfg.TTY().Printf("printed {}", "k", "x")
+	want("printed " + yellow + "x" + reset + "\n")
+
+	// only the TTY highlights
+	if got := log.Fmt("hello {user}"); got != "hello gopher" {
+		t.Errorf("Fmt: got %q", got)
+	}
+
+	log = cfg.Aux(slog.NewTextHandler(&aux, nil)).ForceAux(true).Logger()
+	log.Infof("hello {}", "k", "aux")
+	want("hello " + yellow + "aux" + reset + "\n")
+	if !strings.Contains(aux.String(), `msg="hello aux"`) {
+		t.Errorf("aux: got %q", aux.String())
+	}
+
+	// spans of interpolated values stay aligned with escaped text
+	log = cfg.Logger()
+	log.Infof("a\tb {} {}", "k", "x\ny", "j", "z")
+	want("a\tb " + yellow + `x\ny` + reset + " " + yellow + "z" + reset + "\n")
+
+	// the message of a record, rather than a template, is written
+	log = New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowInterpolated("yellow").
+		ForceTTY(true).
+		ReplaceFunc(func(scope []string, a Attr) Attr {
+			if a.Key == slog.MessageKey {
+				return slog.String(a.Key, "replaced")
+			}
+			return a
+		}).
+		Logger()
+	log.Infof("hello {}", "k", "x")
+	want("replaced\n")
+
+	// a message rewritten by a hook is written
+	log = New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowInterpolated("yellow").
+		ForceTTY(true).
+		OnRecord(func(ctx context.Context, r *slog.Record) bool {
+			r.Message = strings.ToUpper(r.Message)
+			return true
+		}).
+		Logger()
+	log.Infof("hello {}", "k", "x")
+	want("HELLO X\n")
+
+	// consumed values are highlighted, and not written as attrs
+	log = New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowInterpolated("yellow").
+		ShowColor(false).
+		ForceTTY(true).
+		ConsumeInterpolated(true).
+		Logger()
+	log.Infof("hello {user} {n}", "user", "gopher", "n", 1, "m", 2)
+	want("hello gopher 1\tm:2\n")
+
+	log = New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowInterpolated("yellow").
+		ForceTTY(true).
+		ConsumeInterpolated(true).
+		Logger()
+	log.Infof("hello {user}", "user", "gopher")
+	want("hello " + yellow + "gopher" + reset + "\n")
+
+	// no colors
+	cfg.ShowColor(false).Logger().Infof("hello {}", "k", "plain")
+	want("hello plain\n")
+}
+
+func TestTTYErrorChain(t *testing.T) {
+	var b, aux bytes.Buffer
+
+	cfg := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowErrorChain(true).
+		ShowColor(false).
+		ForceTTY(true)
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	base := errors.New("base")
+	mid := fmt.Errorf("mid: %w", base)
+	top := fmt.Errorf("top: %w", mid)
+
+	log := cfg.Logger()
+
+	log.Error("failed", top)
+	want("failed: top: mid: base\n\tcaused by: mid: base\n\tcaused by: base\n")
+
+	// siblings
+	multi := fmt.Errorf("multi: %w", errors.Join(mid, errors.New("other")))
+	log.Error("failed", multi)
+	want("failed: multi:\n\tmid: base\n\tother\n\t\tcaused by: base\n")
+
+	// siblings not written in the message
+	multi = &wrapErrors{"multi", []error{mid, errors.New("other")}}
+	log.Error("failed", multi)
+	want("failed: multi\n\tcaused by: mid: base\n\t\tcaused by: base\n\tcaused by: other\n")
+
+	// limited depth
+	cfg.ShowErrorChainDepth(1).Logger().Error("failed", top)
+	want("failed: top: mid: base\n\tcaused by: mid: base\n")
+
+	// aux
+	log = cfg.ShowErrorChainDepth(8).Aux(slog.NewJSONHandler(&aux, nil)).ForceAux(true).Logger()
+	log.Error("failed", top)
+	if want := `"err_chain":{"0":{"msg":"mid: base"},"1":{"msg":"base"}}`; !strings.Contains(aux.String(), want) {
+		t.Errorf("aux: expected %s in %s", want, aux.String())
+	}
+	b.Reset()
+}
+
+func TestTTYRecordTime(t *testing.T) {
+	var b bytes.Buffer
+
+	tty := New().
+		Writer(&b).
+		ShowLayout("time", "message").
+		ShowTime("", TimeRFC3339Nano).
+		ShowColor(false).
+		ForceTTY(true).
+		TTY()
+
+	// a replayed record keeps its time
+	then := time.Date(2001, 2, 3, 4, 5, 6, 7, time.UTC)
+	tty.Handle(context.Background(), slog.NewRecord(then, INFO, "replayed", 0))
+	if want := "2001-02-03T04:05:06.000000007Z replayed\n"; b.String() != want {
+		t.Errorf("want %q, got %q", want, b.String())
+	}
+	b.Reset()
+
+	// a zero time falls back to now
+	before := time.Now()
+	tty.Handle(context.Background(), slog.NewRecord(time.Time{}, INFO, "zero", 0))
+	stamp, _, _ := strings.Cut(b.String(), " ")
+	if got, err := time.Parse(time.RFC3339Nano, stamp); err != nil || got.Before(before) {
+		t.Errorf("zero: got %q", b.String())
+	}
+}
+
+func TestTTYTimeEncoders(t *testing.T) {
+	var b bytes.Buffer
+	handle := func(enc Encoder[time.Time], times ...time.Time) string {
+		b.Reset()
+		tty := New().
+			Writer(&b).
+			ShowLayout("time", "message").
+			ShowTime("", enc).
+			ShowColor(false).
+			ForceTTY(true).
+			TTY()
+
+		for _, t := range times {
+			tty.WithAttrs([]Attr{slog.Int("a", 1)}).Handle(context.Background(), slog.NewRecord(t, INFO, "ok", 0))
+		}
+		return b.String()
+	}
+
+	then := time.Date(2001, 2, 3, 4, 5, 6, 7008009, time.UTC)
+	for _, tc := range []struct {
+		enc  Encoder[time.Time]
+		want string
+	}{
+		{TimeShort, "04:05:06 ok\n"},
+		{TimeShortMillis, "04:05:06.007 ok\n"},
+		{TimeShortMicro, "04:05:06.007008 ok\n"},
+	} {
+		if got := handle(tc.enc, then); got != tc.want {
+			t.Errorf("want %q, got %q", tc.want, got)
+		}
+	}
+
+	// deltas are kept by the TTY, across derived handlers
+	got := handle(TimeDelta,
+		then,
+		then.Add(250*time.Millisecond),
+		then.Add(12345*time.Millisecond),
+		then.Add(12*time.Second),
+	)
+	if want := "+0.000s ok\n+0.250s ok\n+12.095s ok\n-0.345s ok\n"; got != want {
+		t.Errorf("TimeDelta: want %q, got %q", want, got)
+	}
+}
+
+func TestTTYReplaceBuiltins(t *testing.T) {
+	var b bytes.Buffer
+
+	then := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	cfg := New().
+		Writer(&b).
+		AddSource(true).
+		ShowLayout("time", "level", " ", "message", "\t", "attrs", " ", "source").
+		ShowTime("", TimeRFC3339Nano).
+		ShowLevel(LevelText).
+		ShowColor(false).
+		ForceTTY(true)
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	// dropped
+	cfg.ReplaceFunc(func(scope []string, a Attr) Attr {
+		switch a.Key {
+		case slog.TimeKey, slog.LevelKey, slog.SourceKey:
+			return Attr{}
+		}
+		return a
+	}).Logger().Info("ok", "a", 1)
+	want("ok\ta:1\n")
+
+	// rewritten, with values of the expected kinds
+	cfg.ReplaceFunc(func(scope []string, a Attr) Attr {
+		switch a.Key {
+		case slog.TimeKey:
+			a.Value = slog.TimeValue(then)
+		case slog.LevelKey:
+			a.Value = slog.AnyValue(WARN)
+		case slog.MessageKey:
+			a.Value = slog.StringValue("rewritten " + a.Value.String())
+		case slog.SourceKey:
+			a.Value = slog.AnyValue(&slog.Source{File: "/x/file.go", Line: 1})
+		}
+		return a
+	}).Logger().Infof("{a}", "a", 1)
+	want("2001-02-03T04:05:06Z    WARN    rewritten 1\ta:1 /x/file.go:1\n")
+
+	// rewritten, with values of other kinds
+	cfg.ReplaceFunc(func(scope []string, a Attr) Attr {
+		switch a.Key {
+		case slog.TimeKey:
+			a.Value = slog.StringValue("then")
+		case slog.LevelKey:
+			a.Value = slog.StringValue("NOTICE")
+		case slog.SourceKey:
+			a.Value = slog.StringValue("here")
+		}
+		return a
+	}).Logger().Info("ok")
+	want("then NOTICE ok here\n")
+
+	// the message is dropped, the error is kept
+	cfg.AddSource(false).ShowLayout("message").ReplaceFunc(func(scope []string, a Attr) Attr {
+		if a.Key == slog.MessageKey {
+			return Attr{}
+		}
+		return a
+	}).Logger().Error("dropped", errors.New("kept"))
+	want("kept\n")
+}
+
+func TestTTYStack(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		AddStack(ERROR).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		ForceAux(true).
+		Logger()
+
+	log.Info("below threshold")
+	if strings.Contains(b.String(), `"stack"`) {
+		t.Errorf("unexpected stack below threshold:\n%s", b.String())
+	}
+	b.Reset()
+
+	log.Error("stack", nil)
+	got := b.String()
+
+	if !strings.Contains(got, `"stack":{"0":{"file":`) {
+		t.Errorf("expected structured stack in aux output:\n%s", got)
+	}
+	if !strings.Contains(got, "\n\ttesting.tRunner\n\t\t") {
+		t.Errorf("expected indented frames in TTY output:\n%s", got)
+	}
+	if strings.Contains(got, "logf.(*TTY)") || strings.Contains(got, "log/slog") {
+		t.Errorf("expected logf and slog frames to be elided:\n%s", got)
+	}
+
+	// the threshold applies to the level an OnRecord hook leaves
+	b.Reset()
+	log = New().
+		Writer(&b).
+		AddStack(ERROR).
+		OnRecord(func(ctx context.Context, r *slog.Record) bool {
+			if r.Message == "promoted" {
+				r.Level = ERROR
+			}
+			return r.Message != "dropped"
+		}).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		ForceAux(true).
+		Logger()
+
+	log.Warn("promoted")
+	if got := b.String(); !strings.Contains(got, `"stack"`) || !strings.Contains(got, "\n\ttesting.tRunner") {
+		t.Errorf("expected stack on a promoted record:\n%s", got)
+	}
+	b.Reset()
+
+	log.Error("dropped", nil)
+	if got := b.String(); got != "" {
+		t.Errorf("unexpected output of a dropped record:\n%s", got)
+	}
+}
+
+func TestTTYWrapErrStack(t *testing.T) {
+	base := errors.New("base")
+	err := WrapErrStack("{op} failed", base, "op", "read")
+
+	if err.Error() != "read failed: base" || !errors.Is(err, base) {
+		t.Errorf("wrap: got %v", err)
+	}
+
+	st, ok := err.(interface{ StackTrace() []runtime.Frame })
+	if !ok {
+		t.Fatalf("%T has no StackTrace method", err)
+	}
+	frames := st.StackTrace()
+	if len(frames) == 0 || len(frames) > maxStackDepth {
+		t.Fatalf("unexpected stack depth %d", len(frames))
+	}
+	for _, f := range frames {
+		if framePkg(f.Function) == "github.com/AndrewHarrisSPU/logf" {
+			t.Errorf("expected logf frames to be trimmed, got %s", f.Function)
+		}
+	}
+
+	var b bytes.Buffer
+	cfg := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true)
+
+	// without AddSource, no stack is rendered
+	cfg.Logger().Error("", err)
+	if got := b.String(); got != "read failed: base\terr:{msg:\"read failed: base\" op:read}\n" {
+		t.Errorf("without source: got %q", got)
+	}
+	b.Reset()
+
+	// with AddSource, frames follow the line
+	cfg.AddSource(true).Logger().Error("", err)
+	if got := b.String(); !strings.Contains(got, "\n\ttesting.tRunner\n\t\t") {
+		t.Errorf("with source: expected indented frames, got:\n%s", got)
+	}
+}
+
+func TestTTYJoinedErrors(t *testing.T) {
+	var b bytes.Buffer
+	log := New().
+		Writer(&b).
+		ShowLayout("message").
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	want := func(ok string) {
+		t.Helper()
+		if got := b.String(); got != ok {
+			t.Errorf("want:\n%q\ngot:\n%q", ok, got)
+		}
+		b.Reset()
+	}
+
+	e1, e2, e3 := errors.New("e1"), errors.New("e2"), errors.New("e3")
+
+	log.Error("oops", errors.Join(e1, e2))
+	want("oops:\n\te1\n\te2\n")
+
+	// nesting
+	log.Error("oops", errors.Join(e1, errors.Join(e2, e3)))
+	want("oops:\n\te1\n\t\te2\n\t\te3\n")
+
+	// wrapped
+	log.Error("", fmt.Errorf("ctx: %w", errors.Join(e1, e2)))
+	want("ctx:\n\te1\n\te2\n")
+
+	// as the err attr
+	log.Info("oops", "err", errors.Join(e1, e2))
+	want("oops:\n\te1\n\te2\n")
+
+	// not joined
+	log.Error("oops", fmt.Errorf("%w and %w", e1, e2))
+	want("oops: e1 and e2\n")
+}
+
+func TestWrapErrMulti(t *testing.T) {
+	e1, e2, e3 := errors.New("e1"), errors.New("e2"), errors.New("e3")
+
+	err := WrapErr("{a} then {b}", e3, "a", e1, "b", e2)
+	if err.Error() != "e1 then e2: e3" {
+		t.Errorf("message: got %q", err.Error())
+	}
+	for _, target := range []error{e1, e2, e3} {
+		if !errors.Is(err, target) {
+			t.Errorf("%v does not match %v", err, target)
+		}
+	}
+
+	joined := errors.Join(e1, errors.Join(e2, e3))
+	err = WrapErr("{op}", joined, "op", "read")
+	for _, target := range []error{joined, e1, e2, e3} {
+		if !errors.Is(err, target) {
+			t.Errorf("%v does not match %v", err, target)
+		}
+	}
+}
+
+func TestTTYSecrets(t *testing.T) {
+	var b bytes.Buffer
+
+	want := func(ok string) {
+		t.Helper()
+		if got := b.String(); got != ok {
+			t.Errorf("want %q, got %q", ok, got)
+		}
+		b.Reset()
+	}
+
+	cfg := New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ForceTTY(true)
+
+	pw := Redact("hunter2")
+
+	// redacted by default
+	log := cfg.Logger()
+	log.Infof("{password}", "password", pw)
+	want("[REDACTED]\tpassword:[REDACTED]\n")
+
+	log.With("password", pw).Info("with")
+	want("with\tpassword:[REDACTED]\n")
+
+	if got := Fmt("{password:json}", "password", pw); got != `"[REDACTED]"` {
+		t.Errorf("Fmt: got %s", got)
+	}
+
+	// revealed by a TTY
+	tty := cfg.RevealSecrets(true).TTY()
+	log = tty.Logger()
+	log.Info("", "password", pw)
+	want("password:hunter2\n")
+
+	log.With("password", pw).Info("with")
+	want("with\tpassword:hunter2\n")
+
+	tty.Printf("{password} {password:json}", "password", pw)
+	want("hunter2 \"hunter2\"\n")
+
+	// messages interpolated before reaching the TTY stay redacted
+	log.Infof("{password}", "password", pw)
+	want("[REDACTED]\tpassword:hunter2\n")
+}
+
+func TestTTYQuoting(t *testing.T) {
+	var b bytes.Buffer
+
+	logger := func(mode string) Logger {
+		return New().
+			Writer(&b).
+			ShowLayout("message", "\t", "attrs").
+			ShowColor(false).
+			ShowQuoting(mode).
+			SanitizeControl(false).
+			ForceTTY(true).
+			Logger()
+	}
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	args := []any{"plain", "value", "spaced key", "a b", "colon", "a:b", "brace", "{x}", "ctrl", "a\nb"}
+
+	logger("auto").Info("msg", args...)
+	want("msg\tplain:value \"spaced key\":\"a b\" colon:\"a:b\" brace:\"{x}\" ctrl:\"a\\nb\"\n")
+
+	logger("always").Info("msg", "plain", "value", "n", 1)
+	want("msg\t\"plain\":\"value\" \"n\":\"1\"\n")
+
+	logger("never").Info("msg", "spaced key", "a b")
+	want("msg\tspaced key:a b\n")
+}
+
+func TestTTYSanitizeControl(t *testing.T) {
+	var b bytes.Buffer
+
+	logger := func(sanitize bool) Logger {
+		return New().
+			Writer(&b).
+			ShowLayout("message", "\t", "attrs").
+			ShowColor(false).
+			SanitizeControl(sanitize).
+			ForceTTY(true).
+			Logger()
+	}
+
+	want := func(want string) {
+		t.Helper()
+		if got := b.String(); got != want {
+			t.Errorf("\n\twant %q\n\tgot  %q", want, got)
+		}
+		b.Reset()
+	}
+
+	// cursor movement, and setting the terminal title
+	cursor := "\x1b[2J\x1b[H"
+	title := "\x1b]0;pwned\x07"
+
+	log := logger(true)
+	log.Info("clear"+cursor, "title", title)
+	want(`clear\x1b[2J\x1b[H	title:\x1b]0;pwned\a` + "\n")
+
+	log.Infof("agent {ua}", "ua", "curl\x1b[31m")
+	want(`agent curl\x1b[31m	ua:curl\x1b[31m` + "\n")
+
+	log.Info("c1\u009b", "tab", "a\tb")
+	want(`c1\u009b	tab:"a\tb"` + "\n")
+
+	log.Error("failed", errors.New("read\x1b[1A"))
+	want(`failed: read\x1b[1A	err:read\x1b[1A` + "\n")
+
+	// disabled, sequences pass through (although values are still quoted)
+	logger(false).Info("raw"+cursor, "cursor", cursor)
+	want("raw" + cursor + "\tcursor:\"\\x1b[2J\\x1b[H\"\n")
+	New().
+		Writer(&b).
+		ShowLayout("message", "\t", "attrs").
+		ShowColor(false).
+		ShowQuoting("never").
+		SanitizeControl(false).
+		ForceTTY(true).
+		Logger().
+		Info("raw", "title", title)
+	want("raw\ttitle:" + title + "\n")
 }
@@ -0,0 +1,220 @@
+package logf
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DISPLAY WIDTH
+
+// cellWidth returns the number of terminal cells occupied by text.
+// ANSI escape sequences occupy no cells.
+// Combining marks, zero-width joiners and the characters they join, variation selectors, and emoji modifiers
+// occupy no cells, so that most grapheme clusters measure as their leading character.
+func cellWidth(text []byte) (n int) {
+	var prev int
+	var joined bool
+	for len(text) > 0 {
+		if text[0] == '\x1b' {
+			text = text[escapeLen(text):]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+
+		switch {
+		case r == '\u200d':
+			joined = true
+			continue
+		case joined:
+			joined = false
+			continue
+		case r == '\ufe0f':
+			// emoji presentation widens a preceding narrow character
+			if prev == 1 {
+				n++
+				prev = 2
+			}
+			continue
+		}
+
+		prev = runeWidth(r)
+		n += prev
+	}
+	return n
+}
+
+// cellPrefix returns the length of the longest prefix of text occupying no more than width cells, as measured by cellWidth.
+func cellPrefix(text []byte, width int) int {
+	var n int
+	for n < len(text) {
+		next := n + 1
+		if text[n] == '\x1b' {
+			next = n + escapeLen(text[n:])
+		} else {
+			_, size := utf8.DecodeRune(text[n:])
+			next = n + size
+		}
+
+		if cellWidth(text[:next]) > width {
+			break
+		}
+		n = next
+	}
+	return n
+}
+
+// returns the length of an escape sequence at the head of text
+func escapeLen(text []byte) int {
+	if len(text) < 2 {
+		return len(text)
+	}
+
+	switch text[1] {
+	case '[':
+		// CSI: parameters and intermediates, then a final byte
+		for i := 2; i < len(text); i++ {
+			if 0x40 <= text[i] && text[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		// OSC: terminated by BEL or ST
+		for i := 2; i < len(text); i++ {
+			if text[i] == '\a' {
+				return i + 1
+			}
+			if text[i] == '\x1b' && i+1 < len(text) && text[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	default:
+		return 2
+	}
+	return len(text)
+}
+
+// returns the number of cells occupied by a rune, not in a sequence
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20, 0x7f <= r && r < 0xa0:
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case 0xfe00 <= r && r <= 0xfe0f, 0xe0100 <= r && r <= 0xe01ef:
+		return 0
+	case 0x1f3fb <= r && r <= 0x1f3ff:
+		// emoji skin tone modifiers
+		return 0
+	case inTable(r, wideTable):
+		return 2
+	}
+	return 1
+}
+
+func inTable(r rune, table [][2]rune) bool {
+	i := sort.Search(len(table), func(i int) bool {
+		return table[i][1] >= r
+	})
+	return i < len(table) && table[i][0] <= r
+}
+
+// East Asian Wide (W) and Fullwidth (F) ranges, including emoji presentation
+var wideTable = [][2]rune{
+	{0x1100, 0x115f},
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x23f0, 0x23f0},
+	{0x23f3, 0x23f3},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267f, 0x267f},
+	{0x2693, 0x2693},
+	{0x26a1, 0x26a1},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x26ce, 0x26ce},
+	{0x26d4, 0x26d4},
+	{0x26ea, 0x26ea},
+	{0x26f2, 0x26f3},
+	{0x26f5, 0x26f5},
+	{0x26fa, 0x26fa},
+	{0x26fd, 0x26fd},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x274e, 0x274e},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27b0, 0x27b0},
+	{0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xa960, 0xa97f},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x16fe0, 0x16fe4},
+	{0x17000, 0x18aff},
+	{0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f202},
+	{0x1f210, 0x1f23b},
+	{0x1f240, 0x1f248},
+	{0x1f250, 0x1f251},
+	{0x1f260, 0x1f265},
+	{0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335},
+	{0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393},
+	{0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3},
+	{0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440},
+	{0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d},
+	{0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567},
+	{0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f},
+	{0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc},
+	{0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7},
+	{0x1f6dc, 0x1f6df},
+	{0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc},
+	{0x1f7e0, 0x1f7eb},
+	{0x1f7f0, 0x1f7f0},
+	{0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff},
+	{0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
@@ -0,0 +1,137 @@
+package logf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"runtime"
+	"sync"
+	"time"
+
+	"log/slog"
+)
+
+// NewStdLogger returns a [log.Logger] that writes to the given [Logger].
+// Each line written by the [log.Logger] becomes a record at the given level.
+// Source information is attributed to the caller of the [log.Logger].
+func NewStdLogger(l Logger, level slog.Level) *stdlog.Logger {
+	w := &stdWriter{
+		h:     l.Handler(),
+		level: level,
+	}
+	return stdlog.New(w, "", 0)
+}
+
+type stdWriter struct {
+	h     slog.Handler
+	level slog.Level
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, w.level) {
+		return len(p), nil
+	}
+
+	pc := stdCaller()
+
+	lines := bytes.TrimSuffix(p, []byte{'\n'})
+	for _, line := range bytes.Split(lines, []byte{'\n'}) {
+		r := slog.NewRecord(time.Now(), w.level, string(line), pc)
+		if err := w.h.Handle(ctx, r); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// finds the pc of the first caller outside of the log package
+func stdCaller() uintptr {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+
+	var inLog bool
+	for _, pc := range pcs[:n] {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		switch {
+		case framePkg(f.Function) == "log":
+			inLog = true
+		case inLog:
+			return pc
+		}
+	}
+	return 0
+}
+
+// Writer returns an [io.WriteCloser] that logs each line written to it as a record at the given level.
+// Bytes are buffered until a newline is written; Close logs any remaining partial line.
+// If logging a line fails, Write returns the error, counting the bytes of p through that line as written.
+// It is safe to call Write concurrently.
+func (l Logger) Writer(level slog.Level) io.WriteCloser {
+	return &lineWriter{
+		h:     l.Handler(),
+		level: level,
+	}
+}
+
+type lineWriter struct {
+	h     slog.Handler
+	level slog.Level
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	held := len(w.buf)
+	w.buf = append(w.buf, p...)
+
+	var off int
+	for {
+		n := bytes.IndexByte(w.buf[off:], '\n')
+		if n < 0 {
+			break
+		}
+		off += n + 1
+		if err := w.emit(w.buf[off-n-1 : off-1]); err != nil {
+			// the failed line is handled, while the rest of p is left to the caller
+			w.buf = w.buf[:0]
+			return off - held, err
+		}
+	}
+
+	// retain only the partial line
+	w.buf = append(w.buf[:0], w.buf[off:]...)
+
+	return len(p), nil
+}
+
+// Close logs any buffered partial line.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	err := w.emit(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *lineWriter) emit(line []byte) error {
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, w.level) {
+		return nil
+	}
+
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	r := slog.NewRecord(time.Now(), w.level, string(line), 0)
+	return w.h.Handle(ctx, r)
+}
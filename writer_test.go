@@ -0,0 +1,114 @@
+package logf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"log/slog"
+)
+
+func TestStdLogger(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		AddSource(true).
+		ShowLayout("level", "message", " ", "source").
+		ShowLevel(LevelText).
+		ShowSource("", SourceShort).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger()
+
+	std := NewStdLogger(log, WARN)
+
+	_, _, line, _ := runtime.Caller(0)
+	std.Print("first\nsecond\n")
+	std.Printf("third")
+
+	want := fmt.Sprintf(`   WARN    first writer_test.go:%[1]d
+   WARN    second writer_test.go:%[1]d
+   WARN    third writer_test.go:%[2]d
+`, line+1, line+2)
+
+	if want != b.String() {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}
+
+func TestLoggerWriter(t *testing.T) {
+	var b bytes.Buffer
+
+	log := New().
+		Writer(&b).
+		ShowLayout("level", "tags", "message").
+		ShowLevel(LevelText).
+		ShowColor(false).
+		ForceTTY(true).
+		Logger().
+		With("#", "proc")
+
+	w := log.Writer(INFO)
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\ncaf\xc3"))
+	w.Write([]byte("\xa9\npartial"))
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `   INFO    proc first
+   INFO    proc second
+   INFO    proc café
+   INFO    proc partial
+`
+
+	if want != b.String() {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}
+
+// fails to handle records with the message "bad"
+type failingHandler struct {
+	slog.Handler
+}
+
+func (h failingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Message == "bad" {
+		return errors.New("failed")
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestLoggerWriterError(t *testing.T) {
+	var b bytes.Buffer
+	text := slog.NewTextHandler(&b, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a Attr) Attr {
+			if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+				return Attr{}
+			}
+			return a
+		},
+	})
+	w := UsingHandler(failingHandler{text}).Writer(INFO)
+
+	w.Write([]byte("par"))
+	p := []byte("tial\nbad\nafter\n")
+	n, err := w.Write(p)
+	if err == nil || n != len("tial\nbad\n") {
+		t.Errorf("write: got %d, %v", n, err)
+	}
+
+	// the caller retries the rest
+	if _, err := w.Write(p[n:]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "msg=partial\nmsg=after\n"; b.String() != want {
+		t.Errorf("\n\twant %q\n\tgot  %q", want, b.String())
+	}
+}